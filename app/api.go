@@ -38,6 +38,7 @@ func NewAppApi(router *chi.Mux, app *app) *appApi {
 func (appApi *appApi) Mount() {
 	appApi.bindDefaultEndpoints()
 	appApi.RegisterBotsEndpoints(appApi.app.Bots...)
+	appApi.RegisterSharedWebhooks(bot.SharedWebhooks())
 }
 
 // bindDefaultEndpoints binds the default API endpoints to the appApi object
@@ -53,6 +54,61 @@ func (appApi *appApi) bindDefaultEndpoints() {
 			"/bots",
 			appApi.handleCreateBot,
 		),
+		bot.NewApiEndpoint(
+			"GET",
+			"/bots/{slug}/data-types",
+			appApi.handleGetDataTypes,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/data-types",
+			appApi.handleSaveDataTypes,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/data-types/discover",
+			appApi.handleDiscoverDataTypes,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/training/intents",
+			appApi.handleCreateIntent,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/training/entities",
+			appApi.handleCreateEntity,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/training/entities/{entity}/values",
+			appApi.handleAddEntityValue,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/training/utterances",
+			appApi.handleAddUtterances,
+		),
+		bot.NewApiEndpoint(
+			"GET",
+			"/bots/{slug}/review",
+			appApi.handleListReviewItems,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/review/sync",
+			appApi.handleSyncReviewItems,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/review/{id}/label",
+			appApi.handleLabelReviewItem,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/review/{id}/dismiss",
+			appApi.handleDismissReviewItem,
+		),
 		bot.NewApiEndpoint(
 			"GET",
 			"/intents",
@@ -63,6 +119,36 @@ func (appApi *appApi) bindDefaultEndpoints() {
 			"/entities",
 			appApi.handleListEntities,
 		),
+		bot.NewApiEndpoint(
+			"GET",
+			"/nlp/cache",
+			appApi.handleCacheStats,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/nlp/cache/invalidate",
+			appApi.handleInvalidateCaches,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/bots/{slug}/nlp/cache/invalidate",
+			appApi.handleInvalidateBotCache,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/users/link-codes",
+			appApi.handleCreateLinkCode,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/users/merge",
+			appApi.handleMergeUsers,
+		),
+		bot.NewApiEndpoint(
+			"POST",
+			"/users/unmerge",
+			appApi.handleUnmergeUser,
+		),
 	)
 }
 
@@ -102,6 +188,21 @@ func (appApi *appApi) RegisterBotsEndpoints(bots ...bot.Bot) {
 	}
 }
 
+// RegisterSharedWebhooks registers the webhooks shared by the bots of each platform
+// and binds them to the router.
+func (appApi *appApi) RegisterSharedWebhooks(webhooks map[bot.Platform][]*bot.Webhook) {
+	for platform, platformWebhooks := range webhooks {
+		for _, endpoint := range platformWebhooks {
+			path := "/api/webhooks/" + string(platform) + endpoint.BasePath
+			path = strings.TrimRight(path, "/")
+			endpoint.MountedPath = path
+			appApi.bind(endpoint.Method, path, endpoint.Handler)
+
+			appApi.Webhooks = append(appApi.Webhooks, endpoint)
+		}
+	}
+}
+
 // bindRoute binds a new route to the router given its method, path and handler func
 func (appApi *appApi) bind(method, path string, handler http.HandlerFunc) {
 	log.Debugf("%s %s", string(method), path)
@@ -119,7 +220,7 @@ func (appApi *appApi) handleListBots(w http.ResponseWriter, r *http.Request) {
 	var definitions []*bot.Definition
 
 	for _, b := range appApi.app.Bots {
-		definitions = append(definitions, b.Definition())
+		definitions = append(definitions, b.Definition().Public())
 	}
 
 	j, _ := json.Marshal(definitions)
@@ -149,7 +250,7 @@ func (appApi *appApi) handleCreateBot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	j, _ := json.Marshal(definition)
+	j, _ := json.Marshal(definition.Public())
 
 	// @todo: return a proper response
 	w.Write(j)
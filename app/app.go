@@ -6,10 +6,9 @@ import (
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/aziule/conversation-management/app/facebook"
-	"github.com/aziule/conversation-management/core/api"
 	"github.com/aziule/conversation-management/core/bot"
 	"github.com/aziule/conversation-management/core/conversation"
 	"github.com/aziule/conversation-management/core/nlp"
@@ -19,17 +18,52 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	// Required for initialisation
+	_ "github.com/aziule/conversation-management/app/facebook"
+	_ "github.com/aziule/conversation-management/app/slack"
+	_ "github.com/aziule/conversation-management/app/sms"
+	_ "github.com/aziule/conversation-management/app/telegram"
+	_ "github.com/aziule/conversation-management/app/web"
+	_ "github.com/aziule/conversation-management/app/whatsapp"
+	_ "github.com/aziule/conversation-management/infrastructure/dialogflow"
 	_ "github.com/aziule/conversation-management/infrastructure/facebook"
+	_ "github.com/aziule/conversation-management/infrastructure/file"
+	_ "github.com/aziule/conversation-management/infrastructure/keyword"
 	_ "github.com/aziule/conversation-management/infrastructure/memory"
+	_ "github.com/aziule/conversation-management/infrastructure/rasa"
+	_ "github.com/aziule/conversation-management/infrastructure/slack"
+	_ "github.com/aziule/conversation-management/infrastructure/telegram"
+	_ "github.com/aziule/conversation-management/infrastructure/twilio"
+	_ "github.com/aziule/conversation-management/infrastructure/whatsapp"
 	_ "github.com/aziule/conversation-management/infrastructure/wit"
 )
 
+const (
+	// httpClientTimeout is the timeout used by the HTTP clients calling third-party APIs
+	httpClientTimeout = 10 * time.Second
+	// appCacheNamespace is the namespace of the app-wide NLP cache, and botCacheNamespacePrefix
+	// prefixes the bots' slugs within the namespaces of their NLP caches
+	appCacheNamespace       = "app"
+	botCacheNamespacePrefix = "bot/"
+)
+
 // app defines the main structure, holding information about
 // what bot is running, public-facing API endpoints, etc.
 type app struct {
-	Bots          []bot.Bot
-	botRepository bot.Repository
-	nlpRepository nlp.Repository
+	Bots               []bot.Bot
+	botRepository      bot.Repository
+	nlpRepository      nlp.Repository
+	dataTypeRepository nlp.DataTypeRepository
+	// dataTypes and nlpRepositories are the bots' data types and NLP repositories, by bot slug
+	dataTypes       map[string]*nlp.DataTypes
+	nlpRepositories map[string]nlp.Repository
+	// trainers are the bots' NLP services that can be trained, and reviewQueues
+	// the bots' review queues, by bot slug
+	trainers     map[string]nlp.Trainer
+	reviewQueues map[string]*nlp.ReviewQueue
+	// nlpCache is the app-wide NLP cache, and caches the bots' NLP caches, by bot slug
+	nlpCache   *nlp.Cache
+	caches     map[string]*nlp.Cache
+	identities *conversation.IdentityManager
 }
 
 // Run starts the server and waits for interactions
@@ -81,71 +115,190 @@ func Run(configFilePath string) {
 		log.Fatalf("An error occurred when creating the story repository: %s", err)
 	}
 
-	fbApi, err := api.NewFacebookApi("facebook", map[string]interface{}{
-		"page_access_token": config.FbPageAccessToken,
-		"version":           config.FbApiVersion,
-		"client":            http.DefaultClient,
-	})
+	definitions, err := botRepository.FindAll()
 
 	if err != nil {
-		log.Fatalf("An error occurred when creating the Facebook API: %s", err)
+		log.Fatalf("An error occurred when finding the bots list: %s", err)
 	}
 
-	definitions, err := botRepository.FindAll()
+	nlpParams := map[string]interface{}{
+		"bearer_token": config.WitBearerToken,
+	}
+
+	for name, value := range config.NlpParams {
+		nlpParams[name] = value
+	}
+
+	nlpApi, err := newNlpApi(config.Nlp, nlpParams, nil)
 
 	if err != nil {
-		log.Fatalf("An error occurred when finding the bots list: %s", err)
+		log.Fatalf("An error occurred when creating the NLP API: %s", err)
 	}
 
-	nlpParser, err := nlp.NewParser("wit", nil)
+	nlpRepository, err := nlp.NewRepository(config.Nlp, map[string]interface{}{
+		"api": nlpApi,
+	})
 
 	if err != nil {
-		log.Fatalf("An error occurred when creating the parser: %s", err)
+		log.Fatalf("An error occurred when creating the repository: %s", err)
 	}
 
-	nlpApi, err := nlp.NewApi("wit", map[string]interface{}{
-		"client":       http.DefaultClient,
-		"bearer_token": config.WitBearerToken,
+	cacheStoreName, _ := config.NlpCache["store"].(string)
+
+	if cacheStoreName == "" {
+		cacheStoreName = "memory"
+	}
+
+	cacheStore, err := nlp.NewCacheStore(cacheStoreName, map[string]interface{}{
+		"db":          db,
+		"max_entries": config.NlpCache["max_entries"],
 	})
 
 	if err != nil {
-		log.Fatalf("An error occurred when creating the NLP API: %s", err)
+		log.Fatalf("An error occurred when creating the NLP cache store: %s", err)
 	}
 
-	nlpRepository, err := nlp.NewRepository("wit", map[string]interface{}{
-		"api": nlpApi,
+	appCache := nlp.NewCache(cacheStore, appCacheNamespace, nlp.NewCacheConf(config.NlpCache))
+
+	dataTypeRepository, err := nlp.NewDataTypeRepository(config.DataTypes, map[string]interface{}{
+		"db":  db,
+		"dir": config.DataTypesDir,
 	})
 
 	if err != nil {
-		log.Fatalf("An error occurred when creating the repository: %s", err)
+		log.Fatalf("An error occurred when creating the data type repository: %s", err)
+	}
+
+	reviewRepository, err := nlp.NewReviewRepository("mongo", map[string]interface{}{
+		"db": db,
+	})
+
+	if err != nil {
+		log.Fatalf("An error occurred when creating the review repository: %s", err)
 	}
 
 	app := &app{
-		botRepository: botRepository,
-		nlpRepository: nlpRepository,
+		botRepository:      botRepository,
+		nlpRepository:      nlp.NewCachedRepository(nlpRepository, appCache),
+		dataTypeRepository: dataTypeRepository,
+		dataTypes:          make(map[string]*nlp.DataTypes),
+		nlpRepositories:    make(map[string]nlp.Repository),
+		trainers:           make(map[string]nlp.Trainer),
+		reviewQueues:       make(map[string]*nlp.ReviewQueue),
+		nlpCache:           appCache,
+		caches:             make(map[string]*nlp.Cache),
+		identities:         conversation.NewIdentityManager(conversationRepository),
 	}
 
 	router := chi.NewRouter()
 	router.Use(render.SetContentType(render.ContentTypeJSON))
 
 	for _, definition := range definitions {
-		var b bot.Bot
-
-		switch definition.Platform {
-		case bot.PlatformFacebook:
-			// @todo: register all available implementations using a factory
-			// pattern, and fetch them directly from the config passed
-			b = facebook.NewBot(
-				&facebook.Config{
-					Definition:             definition,
-					FbApi:                  fbApi,
-					NlpParser:              nlpParser,
-					ConversationRepository: conversationRepository,
-					StoryRepository:        storyRepository,
-				},
-			)
-		default:
-			log.Errorf("Unhandled platform: %s", definition.Platform)
+		dataTypes, stored := app.loadDataTypes(definition.Slug)
+
+		// The bot's TTLs override the app-wide ones
+		cacheParams := map[string]interface{}{}
+
+		for _, params := range []map[string]interface{}{config.NlpCache, definition.MapParam(bot.CacheParam)} {
+			for name, value := range params {
+				cacheParams[name] = value
+			}
+		}
+
+		cache := nlp.NewCache(cacheStore, botCacheNamespacePrefix+definition.Slug, nlp.NewCacheConf(cacheParams))
+		app.caches[definition.Slug] = cache
+
+		// The NLP data provided by the platforms, such as Messenger's built-in NLP, uses Wit's format
+		nlpParser, err := nlp.NewParser("wit", map[string]interface{}{
+			"data_types": dataTypes,
+		})
+
+		if err != nil {
+			log.Fatalf("An error occurred when creating the parser: %s", err)
+		}
+
+		engine := conversation.NewEngine(
+			defaultStepsProcessMap(),
+			conversationRepository,
+			storyRepository,
+			nlp.NewCachedParser(nlpParser, cache),
+		)
+
+		engine.SetIdentities(app.identities)
+
+		if err := configureEngine(engine, definition); err != nil {
+			log.WithField("bot", definition.Slug).Errorf("An error occurred when configuring the bot: %s", err)
+			continue
+		}
+
+		provider, botNlpParams := config.Nlp, nlpParams
+
+		if params := definition.MapParam(bot.NlpParam); params != nil {
+			provider, _ = params["provider"].(string)
+			botNlpParams = params
+		}
+
+		botNlpApi, err := newNlpApi(provider, botNlpParams, dataTypes)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"bot":      definition.Slug,
+				"provider": provider,
+			}).Errorf("An error occurred when creating the bot's NLP API: %s", err)
+			continue
+		}
+
+		// The trainer is the API itself, as the training is never cached
+		if trainer, ok := botNlpApi.(nlp.Trainer); ok {
+			app.trainers[definition.Slug] = trainer
+		}
+
+		botNlpApi = nlp.NewCachedApi(botNlpApi, cache)
+
+		// Messages coming without built-in NLP are sent to the NLP service
+		engine.SetTextParser(botNlpApi)
+
+		botNlpRepository, err := nlp.NewRepository(provider, map[string]interface{}{
+			"api": botNlpApi,
+		})
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"bot":      definition.Slug,
+				"provider": provider,
+			}).Errorf("An error occurred when creating the bot's NLP repository: %s", err)
+			continue
+		}
+
+		app.nlpRepositories[definition.Slug] = botNlpRepository
+
+		// Messages the NLP service did not understand well enough are put in the bot's review queue
+		reviewQueue := nlp.NewReviewQueue(reviewRepository, definition.Slug)
+		app.reviewQueues[definition.Slug] = reviewQueue
+		engine.SetReviewQueue(reviewQueue)
+
+		// The types of the bots without any data type map are discovered from the NLP provider
+		if !stored {
+			if _, err := app.discoverDataTypes(definition.Slug); err != nil {
+				log.WithField("bot", definition.Slug).Errorf("An error occurred when discovering the bot's data types: %s", err)
+			}
+		}
+
+		b, err := bot.NewBot(definition, map[string]interface{}{
+			"engine":          engine,
+			"bot_repository":  botRepository,
+			"client":          &http.Client{Timeout: httpClientTimeout},
+			"webhook_url":     webhookUrl(config, definition),
+			"fb_api_version":  config.FbApiVersion,
+			"fb_app_secret":   config.FbAppSecret,
+			"fb_verify_token": config.FbVerifyToken,
+		})
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"bot":      definition.Slug,
+				"platform": definition.Platform,
+			}).Errorf("An error occurred when creating the bot: %s", err)
 			continue
 		}
 
@@ -159,3 +312,59 @@ func Run(configFilePath string) {
 	log.Debugf("Listening on port %d", config.ListeningPort)
 	http.ListenAndServe(":"+strconv.Itoa(config.ListeningPort), router)
 }
+
+// configureEngine sets the thresholds, the timezone and the validators of the bot's definition to the engine.
+// The default validators are used unless the bot has its own.
+func configureEngine(engine *conversation.Engine, definition *bot.Definition) error {
+	engine.SetValidators(defaultValidators())
+
+	if thresholds := definition.MapParam(bot.ThresholdsParam); thresholds != nil {
+		engine.SetThresholds(conversation.NewThresholds(thresholds))
+	}
+
+	if timezone := definition.StringParam(bot.TimezoneParam); timezone != "" {
+		if err := engine.SetTimezone(timezone); err != nil {
+			return err
+		}
+	}
+
+	if rules := definition.MapParam(bot.ValidatorsParam); rules != nil {
+		validators, err := nlp.NewValidators(rules)
+
+		if err != nil {
+			return err
+		}
+
+		engine.SetValidators(validators)
+	}
+
+	return nil
+}
+
+// newNlpApi creates the NLP API of the given provider, using its params.
+// The bot's data types are optional.
+func newNlpApi(provider string, params map[string]interface{}, dataTypes *nlp.DataTypes) (nlp.Api, error) {
+	conf := map[string]interface{}{
+		"client": &http.Client{Timeout: httpClientTimeout},
+	}
+
+	if dataTypes != nil {
+		conf["data_types"] = dataTypes
+	}
+
+	for name, value := range params {
+		conf[name] = value
+	}
+
+	return nlp.NewApi(provider, conf)
+}
+
+// webhookUrl returns the public url of the bot's webhooks, as mounted by the appApi.
+// Returns an empty string if the public url is not configured.
+func webhookUrl(config *Config, definition *bot.Definition) string {
+	if config.PublicUrl == "" {
+		return ""
+	}
+
+	return strings.TrimRight(config.PublicUrl, "/") + "/api/bots/" + definition.Slug + "/webhooks"
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheStatsResponse is the response body giving the counters of the NLP caches
+type cacheStatsResponse struct {
+	App  *nlp.CacheStats            `json:"app"`
+	Bots map[string]*nlp.CacheStats `json:"bots"`
+}
+
+// invalidateCaches invalidates the NLP caches of the bot and of the app, such as when the bot's
+// NLP service was trained. Errors are logged, as the values expire anyway.
+func (app *app) invalidateCaches(slug string) {
+	caches := []*nlp.Cache{app.nlpCache}
+
+	if cache, ok := app.caches[slug]; ok {
+		caches = append(caches, cache)
+	}
+
+	for _, cache := range caches {
+		if err := cache.Invalidate(); err != nil {
+			log.WithField("bot", slug).Errorf("Could not invalidate the NLP cache: %s", err)
+		}
+	}
+}
+
+// handleCacheStats is the handler func that returns the counters of the app-wide NLP cache,
+// and of the bots' NLP caches
+func (appApi *appApi) handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	response := &cacheStatsResponse{
+		App:  appApi.app.nlpCache.Stats(),
+		Bots: make(map[string]*nlp.CacheStats),
+	}
+
+	for slug, cache := range appApi.app.caches {
+		response.Bots[slug] = cache.Stats()
+	}
+
+	j, _ := json.Marshal(response)
+
+	w.Write(j)
+}
+
+// handleInvalidateCaches invalidates all of the NLP caches
+func (appApi *appApi) handleInvalidateCaches(w http.ResponseWriter, r *http.Request) {
+	caches := []*nlp.Cache{appApi.app.nlpCache}
+
+	for _, cache := range appApi.app.caches {
+		caches = append(caches, cache)
+	}
+
+	for _, cache := range caches {
+		if err := cache.Invalidate(); err != nil {
+			log.Errorf("Could not invalidate the NLP cache: %s", err)
+			http.Error(w, "Could not invalidate the NLP caches", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleInvalidateBotCache invalidates the NLP cache of the bot
+func (appApi *appApi) handleInvalidateBotCache(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	cache, ok := appApi.app.caches[slug]
+
+	if !ok {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+		return
+	}
+
+	if err := cache.Invalidate(); err != nil {
+		log.WithField("bot", slug).Errorf("Could not invalidate the NLP cache: %s", err)
+		http.Error(w, "Could not invalidate the NLP cache", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
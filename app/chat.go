@@ -0,0 +1,184 @@
+package app
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aziule/conversation-management/app/console"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/infrastructure/mongo"
+	log "github.com/sirupsen/logrus"
+
+	// Required for initialisation
+	_ "github.com/aziule/conversation-management/infrastructure/keyword"
+)
+
+// ChatOptions are the options used to chat with a bot on the console
+type ChatOptions struct {
+	// Nlp is the name of the text parser, such as "keyword" to parse the messages offline
+	Nlp string
+	// TrainingFile is the optional training data file of the keyword parser
+	TrainingFile string
+	// WitBearerToken is the bearer token used by the "wit" parser
+	WitBearerToken string
+	// Debug tells whether the conversation's state is printed after each answer
+	Debug bool
+	// Verbose tells whether the logs are printed
+	Verbose bool
+	// Bot is the optional slug of the bot to chat with: its definition, data types and validators
+	// are loaded using the ConfigFile's database
+	Bot        string
+	ConfigFile string
+}
+
+// consoleRunner is the interface of the bots chatting on the console
+type consoleRunner interface {
+	Run() error
+}
+
+// Chat runs the stories in-process and chats with them on the console.
+// Everything is kept in memory: no database is needed, unless a bot is given.
+func Chat(options *ChatOptions) error {
+	// The errors are printed within the chat: only show the logs when asked to
+	log.SetLevel(log.FatalLevel)
+
+	if options.Verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	rand.Seed(time.Now().UnixNano())
+
+	conversationRepository, err := conversation.NewRepository("memory", nil)
+
+	if err != nil {
+		return err
+	}
+
+	storyRepository, err := conversation.NewStoryRepository("memory", nil)
+
+	if err != nil {
+		return err
+	}
+
+	definition := &bot.Definition{
+		Slug:     "console",
+		Platform: bot.PlatformConsole,
+	}
+	dataTypes := nlp.NewDataTypes(defaultDataTypes())
+
+	if options.Bot != "" {
+		definition, dataTypes, err = loadBot(options.Bot, options.ConfigFile)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	textParser, err := nlp.NewTextParser(options.Nlp, map[string]interface{}{
+		"training_file": options.TrainingFile,
+		"client":        &http.Client{Timeout: httpClientTimeout},
+		"bearer_token":  options.WitBearerToken,
+		"data_types":    dataTypes,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	engine := conversation.NewEngine(
+		defaultStepsProcessMap(),
+		conversationRepository,
+		storyRepository,
+		nil,
+	)
+	engine.SetTextParser(textParser)
+
+	if err = configureEngine(engine, definition); err != nil {
+		return err
+	}
+
+	// The bot chats on the console, whatever its platform
+	consoleDefinition := &bot.Definition{
+		Slug:     definition.Slug,
+		Platform: bot.PlatformConsole,
+		Parameters: map[bot.ParamName]interface{}{
+			console.Debug: options.Debug,
+		},
+	}
+
+	b, err := bot.NewBot(consoleDefinition, map[string]interface{}{
+		"engine": engine,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return b.(consoleRunner).Run()
+}
+
+// loadBot loads the definition and the data types of the bot, using the config's database.
+// Returns ErrBotNotFound if there is no bot with this slug.
+func loadBot(slug, configFilePath string) (*bot.Definition, *nlp.DataTypes, error) {
+	config, err := LoadConfig(configFilePath)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := mongo.CreateSession(mongo.DbParams{
+		DbHost: config.DbHost,
+		DbName: config.DbName,
+		DbUser: config.DbUser,
+		DbPass: config.DbPass,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer db.Close()
+
+	botRepository, err := bot.NewRepository("mongo", map[string]interface{}{
+		"db": db,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	definitions, err := botRepository.FindAll()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var definition *bot.Definition
+
+	for _, d := range definitions {
+		if d.Slug == slug {
+			definition = d
+			break
+		}
+	}
+
+	if definition == nil {
+		return nil, nil, ErrBotNotFound
+	}
+
+	dataTypeRepository, err := nlp.NewDataTypeRepository(config.DataTypes, map[string]interface{}{
+		"db":  db,
+		"dir": config.DataTypesDir,
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataTypes, _ := findDataTypes(dataTypeRepository, slug)
+
+	return definition, dataTypes, nil
+}
@@ -7,16 +7,33 @@ import (
 
 // Config is the struct that will hold the runtime configuration
 type Config struct {
-	Debug             bool   `json:"debug"`
-	ListeningPort     int    `json:"listening_port"`
-	FbVerifyToken     string `json:"fb_verify_token"`
-	FbApiVersion      string `json:"fb_api_version"`
-	FbPageAccessToken string `json:"fb_page_access_token"`
-	DbHost            string `json:"db_host"`
-	DbName            string `json:"db_name"`
-	DbUser            string `json:"db_user"`
-	DbPass            string `json:"db_pass"`
-	WitBearerToken    string `json:"wit_bearer_token"` // @todo: move it to the DB (bot's config)
+	Debug         bool   `json:"debug"`
+	ListeningPort int    `json:"listening_port"`
+	PublicUrl     string `json:"public_url"`
+	FbApiVersion  string `json:"fb_api_version"`
+	// FbAppSecret is the Facebook app's secret, used to check the signature of the events
+	// received by the Facebook bots without their own secret (see facebook.AppSecret)
+	FbAppSecret string `json:"fb_app_secret"`
+	// FbVerifyToken is the Facebook app's verify token, used to validate the webhook shared by
+	// the Facebook bots (see facebook.VerifyToken for the webhook of each bot)
+	FbVerifyToken  string `json:"fb_verify_token"`
+	DbHost         string `json:"db_host"`
+	DbName         string `json:"db_name"`
+	DbUser         string `json:"db_user"`
+	DbPass         string `json:"db_pass"`
+	WitBearerToken string `json:"wit_bearer_token"` // @todo: move it to the DB (bot's config)
+	// Nlp is the NLP provider used to parse the messages' text and list the intents and entities:
+	// "wit" (default), "dialogflow" or "rasa". Bots can use their own provider (see bot.NlpParam).
+	Nlp string `json:"nlp"`
+	// NlpParams are the provider's params, such as Dialogflow's "project_id" and "credentials_file", or Rasa's "base_url"
+	NlpParams map[string]interface{} `json:"nlp_params"`
+	// DataTypes is where the bots' data type maps are stored: "mongo" (default) or "file",
+	// in which case they are stored within the DataTypesDir directory
+	DataTypes    string `json:"data_types"`
+	DataTypesDir string `json:"data_types_dir"`
+	// NlpCache is the NLP cache's config: its "store", "memory" (default) or "mongo", the "max_entries"
+	// kept by the store, and the "parse_ttl" and "list_ttl" (see nlp.NewCacheConf)
+	NlpCache map[string]interface{} `json:"nlp_cache"`
 }
 
 // LoadConfig loads the configuration located at the given path
@@ -27,7 +44,11 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
+	config := Config{
+		Nlp:          "wit",
+		DataTypes:    "mongo",
+		DataTypesDir: "data/data_types",
+	}
 
 	if err = json.Unmarshal(data, &config); err != nil {
 		return nil, err
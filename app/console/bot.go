@@ -0,0 +1,109 @@
+// Package console defines a bot chatting on the console, to try out the stories locally.
+package console
+
+import (
+	"io"
+	"os"
+
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const (
+	// Debug tells whether the debug pane, showing the conversation's state, is printed after each answer
+	Debug bot.ParamName = "debug"
+)
+
+// Config is the config required in order to instantiate a new ConsoleBot
+type Config struct {
+	Definition *bot.Definition
+	Engine     *conversation.Engine
+	Input      io.Reader
+	Output     io.Writer
+}
+
+// consoleBot is the main structure. It has neither webhooks nor API endpoints:
+// it reads the user's messages from its input and writes the answers to its output.
+type consoleBot struct {
+	definition          *bot.Definition
+	conversationHandler *conversationHandler
+}
+
+// NewBot is the constructor method that creates a console bot, using
+// the Config struct as method parameters.
+func NewBot(config *Config) *consoleBot {
+	return &consoleBot{
+		definition: config.Definition,
+		conversationHandler: newConversationHandler(
+			config.Engine,
+			config.Input,
+			config.Output,
+			config.Definition.BoolParam(Debug),
+		),
+	}
+}
+
+// buildBot is the builder registered for the console platform.
+// The standard input and output are used unless an "input" and an "output" are given.
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	input, ok := utils.GetParam(conf, "input").(io.Reader)
+
+	if !ok {
+		input = os.Stdin
+	}
+
+	output, ok := utils.GetParam(conf, "output").(io.Writer)
+
+	if !ok {
+		output = os.Stdout
+	}
+
+	return NewBot(
+		&Config{
+			Definition: definition,
+			Engine:     engine,
+			Input:      input,
+			Output:     output,
+		},
+	), nil
+}
+
+// Run chats with the user until the input is closed
+func (b *consoleBot) Run() error {
+	return b.conversationHandler.Chat()
+}
+
+// Webhooks returns the bot's webhooks.
+// This method is required in order to implement the Bot interface.
+func (b *consoleBot) Webhooks() []*bot.Webhook {
+	return nil
+}
+
+// ApiEndpoints returns the bot's available API endpoints.
+// This method is required in order to implement the Bot interface.
+func (b *consoleBot) ApiEndpoints() []*bot.ApiEndpoint {
+	return nil
+}
+
+// Definition returns the bot's definition.
+// This method is required in order to implement the Bot interface.
+func (b *consoleBot) Definition() *bot.Definition {
+	return b.definition
+}
+
+func init() {
+	bot.RegisterBuilder(bot.PlatformConsole, buildBot)
+}
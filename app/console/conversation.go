@@ -0,0 +1,203 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+)
+
+const (
+	// senderId is the id of the console's single user
+	senderId = "console"
+	// quitCommand ends the chat
+	quitCommand = "/quit"
+	prompt      = "> "
+)
+
+// platform is the platform name given to the conversation engine
+var platform = string(bot.PlatformConsole)
+
+// conversationHandler is the struct responsible for handling the console conversation.
+// It reads the user's messages line by line, and prints the engine's answers.
+//
+// Quick replies are printed as numbered options: the user can answer using the
+// option's number or its title.
+type conversationHandler struct {
+	engine  *conversation.Engine
+	input   io.Reader
+	output  io.Writer
+	debug   bool
+	options []*conversation.QuickReply
+}
+
+// newConversationHandler is the constructor method for conversationHandler
+func newConversationHandler(e *conversation.Engine, input io.Reader, output io.Writer, debug bool) *conversationHandler {
+	return &conversationHandler{
+		engine: e,
+		input:  input,
+		output: output,
+		debug:  debug,
+	}
+}
+
+// Chat reads the user's messages until the input is closed or the user quits,
+// and answers each of them.
+func (h *conversationHandler) Chat() error {
+	scanner := bufio.NewScanner(h.input)
+
+	fmt.Fprintf(h.output, "Chatting with the bot. Type %s to exit.\n", quitCommand)
+	fmt.Fprint(h.output, prompt)
+
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == quitCommand {
+			return nil
+		}
+
+		if text != "" {
+			h.MessageReceived(text)
+		}
+
+		fmt.Fprint(h.output, prompt)
+	}
+
+	return scanner.Err()
+}
+
+// MessageReceived hands the message over to the conversation engine and prints its answers
+func (h *conversationHandler) MessageReceived(text string) {
+	in := &conversation.InboundMessage{
+		Platform: platform,
+		SenderId: senderId,
+		Text:     text,
+		SentAt:   time.Now(),
+	}
+
+	if quickReply := h.pickOption(text); quickReply != nil {
+		in.Text = quickReply.Title
+		in.QuickReplyPayload = quickReply.Payload
+	}
+
+	messages, err := h.engine.Handle(in)
+
+	if err != nil {
+		fmt.Fprintf(h.output, "(no answer: %s)\n", err)
+	}
+
+	h.answer(messages)
+
+	if h.debug {
+		h.printDebug()
+	}
+}
+
+// answer prints the messages, along with their quick replies as numbered options
+func (h *conversationHandler) answer(messages []*conversation.OutboundMessage) {
+	for _, message := range messages {
+		fmt.Fprintf(h.output, "bot: %s\n", message.Text)
+
+		if len(message.QuickReplies) == 0 {
+			continue
+		}
+
+		h.options = message.QuickReplies
+
+		for i, quickReply := range message.QuickReplies {
+			fmt.Fprintf(h.output, "  %d. %s\n", i+1, quickReply.Title)
+		}
+	}
+}
+
+// pickOption returns the option chosen by the user, either by its number or by its title.
+// The options are forgotten once the user replied. Returns nil if the text does not match any option.
+func (h *conversationHandler) pickOption(text string) *conversation.QuickReply {
+	options := h.options
+	h.options = nil
+
+	if number, err := strconv.Atoi(strings.TrimRight(text, ".)")); err == nil {
+		if number >= 1 && number <= len(options) {
+			return options[number-1]
+		}
+
+		return nil
+	}
+
+	for _, option := range options {
+		if strings.EqualFold(option.Title, text) {
+			return option
+		}
+	}
+
+	return nil
+}
+
+// printDebug prints the debug pane: the conversation's state, the data parsed
+// from the latest message and the slots filled so far.
+func (h *conversationHandler) printDebug() {
+	c, err := h.engine.LatestConversation(platform, senderId)
+
+	if err != nil {
+		fmt.Fprintf(h.output, "  [debug] no conversation: %s\n", err)
+		return
+	}
+
+	step := c.CurrentStep
+
+	if step == "" {
+		step = "-"
+	}
+
+	fmt.Fprintf(h.output, "  [debug] step: %s | status: %s\n", step, c.Status)
+	fmt.Fprintf(h.output, "  [debug] intent: %s\n", latestIntent(c))
+	fmt.Fprintf(h.output, "  [debug] slots: %s\n", formatSlots(c))
+}
+
+// latestIntent returns the intent parsed from the user's latest message, along with its confidence
+func latestIntent(c *conversation.Conversation) string {
+	if len(c.Messages) == 0 {
+		return "-"
+	}
+
+	userMessage, ok := c.Messages[len(c.Messages)-1].Message.(*conversation.UserMessage)
+
+	if !ok || userMessage.ParsedData == nil || userMessage.ParsedData.Intent == nil {
+		return "-"
+	}
+
+	intent := userMessage.ParsedData.Intent
+
+	return fmt.Sprintf("%s (%.2f)", intent.Intent.Name, intent.Confidence)
+}
+
+// formatSlots returns the conversation's slots, sorted by name
+func formatSlots(c *conversation.Conversation) string {
+	slots := c.Slots()
+
+	if len(slots) == 0 {
+		return "-"
+	}
+
+	var names []string
+
+	for name := range slots {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var formatted []string
+
+	for _, name := range names {
+		formatted = append(formatted, fmt.Sprintf("%s=%+v", name, slots[name].Data))
+	}
+
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrBotNotFound = errors.New("Bot not found")
+
+// dataTypesResponse is the response body describing a bot's data types
+type dataTypesResponse struct {
+	// DataTypes are the types used by the bot, including the default ones
+	DataTypes nlp.DataTypeMap `json:"data_types"`
+	// Custom are the types set for the bot
+	Custom nlp.DataTypeMap `json:"custom"`
+	// Unknown are the entities received without any type, along with the number of times
+	// they were received
+	Unknown   map[string]int `json:"unknown"`
+	Discovery *nlp.Discovery `json:"discovery,omitempty"`
+}
+
+// loadDataTypes creates the bot's data types, using its stored data type map if any.
+// Returns whether the bot has a stored data type map.
+func (app *app) loadDataTypes(slug string) (*nlp.DataTypes, bool) {
+	dataTypes, stored := findDataTypes(app.dataTypeRepository, slug)
+	app.dataTypes[slug] = dataTypes
+
+	return dataTypes, stored
+}
+
+// findDataTypes creates the bot's data types, using the data type map stored within the repository if any.
+// Returns whether the bot has a stored data type map.
+func findDataTypes(repository nlp.DataTypeRepository, slug string) (*nlp.DataTypes, bool) {
+	dataTypes := nlp.NewDataTypes(defaultDataTypes())
+
+	dataTypeMap, err := repository.FindByBot(slug)
+
+	if err != nil {
+		log.WithField("bot", slug).Errorf("An error occurred when finding the bot's data type map: %s", err)
+		return dataTypes, false
+	}
+
+	if dataTypeMap == nil {
+		return dataTypes, false
+	}
+
+	// The invalid types are reported and ignored, so that the bot can still use the valid ones
+	if invalid := dataTypeMap.InvalidKeys(); len(invalid) > 0 {
+		log.WithFields(log.Fields{
+			"bot":      slug,
+			"entities": invalid,
+		}).Warn("The bot's data type map has invalid types")
+
+		for _, name := range invalid {
+			delete(dataTypeMap, name)
+		}
+	}
+
+	dataTypes.Set(dataTypeMap)
+
+	return dataTypes, true
+}
+
+// discoverDataTypes discovers the types of the entities listed by the bot's NLP provider, and saves them
+func (app *app) discoverDataTypes(slug string) (*nlp.Discovery, error) {
+	dataTypes, ok := app.dataTypes[slug]
+	repository, repositoryOk := app.nlpRepositories[slug]
+
+	if !ok || !repositoryOk {
+		return nil, ErrBotNotFound
+	}
+
+	// The entities are listed again, as they may have changed since they were cached
+	app.invalidateCaches(slug)
+
+	entities, err := repository.GetEntities()
+
+	if err != nil {
+		return nil, err
+	}
+
+	discovery := dataTypes.Discover(entities)
+
+	if err := app.dataTypeRepository.SaveForBot(slug, dataTypes.Custom()); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"bot":        slug,
+		"discovered": discovery.Discovered,
+		"untyped":    discovery.Untyped,
+		"unlisted":   discovery.Unlisted,
+	}).Info("Discovered the bot's data types")
+
+	return discovery, nil
+}
+
+// handleGetDataTypes is the handler func that returns the bot's data types,
+// along with the entities received without any type
+func (appApi *appApi) handleGetDataTypes(w http.ResponseWriter, r *http.Request) {
+	dataTypes, ok := appApi.app.dataTypes[chi.URLParam(r, "slug")]
+
+	if !ok {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+		return
+	}
+
+	writeDataTypesResponse(w, dataTypes, nil)
+}
+
+// handleSaveDataTypes replaces the bot's custom data types with the ones given, such as {"nb_persons": "int"}
+func (appApi *appApi) handleSaveDataTypes(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	dataTypes, ok := appApi.app.dataTypes[slug]
+
+	if !ok {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+
+	var body nlp.DataTypeMap
+	err := decoder.Decode(&body)
+
+	if err != nil {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if invalid := body.InvalidKeys(); len(invalid) > 0 {
+		http.Error(w, "Invalid data types: "+strings.Join(invalid, ", "), http.StatusBadRequest)
+		return
+	}
+
+	if err := appApi.app.dataTypeRepository.SaveForBot(slug, body); err != nil {
+		log.WithField("bot", slug).Errorf("Could not save the data type map: %s", err)
+		http.Error(w, "Could not save the data types", http.StatusInternalServerError)
+		return
+	}
+
+	dataTypes.Set(body)
+
+	// The cached data was parsed using the previous types
+	appApi.app.invalidateCaches(slug)
+
+	writeDataTypesResponse(w, dataTypes, nil)
+}
+
+// handleDiscoverDataTypes adds the types of the entities listed by the bot's NLP provider
+// to the bot's data types, and reports the entities that could not be mapped
+func (appApi *appApi) handleDiscoverDataTypes(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	discovery, err := appApi.app.discoverDataTypes(slug)
+
+	switch err {
+	case nil:
+		writeDataTypesResponse(w, appApi.app.dataTypes[slug], discovery)
+	case ErrBotNotFound:
+		http.Error(w, "Bot not found", http.StatusNotFound)
+	default:
+		log.WithField("bot", slug).Errorf("Could not discover the data types: %s", err)
+		http.Error(w, "Could not discover the data types", http.StatusInternalServerError)
+	}
+}
+
+// writeDataTypesResponse writes the bot's data types, along with the discovery if any
+func writeDataTypesResponse(w http.ResponseWriter, dataTypes *nlp.DataTypes, discovery *nlp.Discovery) {
+	j, _ := json.Marshal(&dataTypesResponse{
+		DataTypes: dataTypes.Map(),
+		Custom:    dataTypes.Custom(),
+		Unknown:   dataTypes.Unknown(),
+		Discovery: discovery,
+	})
+
+	w.Write(j)
+}
@@ -2,9 +2,13 @@ package facebook
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/aziule/conversation-management/core/api"
 	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	log "github.com/sirupsen/logrus"
 )
 
 // bindDefaultApiEndpoints initialises the default API endpoints.
@@ -12,13 +16,232 @@ func (b *facebookBot) bindDefaultApiEndpoints() {
 	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
 		"GET",
 		"/",
-		b.handleViewBot,
+		bot.HandleViewBot(b),
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"GET",
+		"/profile",
+		b.handleViewMessengerProfile,
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/profile",
+		b.handleUpdateMessengerProfile,
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/handover/pass",
+		b.handlePassThreadControl,
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/handover/take",
+		b.handleTakeThreadControl,
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/messages",
+		b.handleSendMessage,
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/page-token",
+		b.handleUpdatePageAccessToken,
 	))
 }
 
-// handleViewBot shows details about the bot
-func (b *facebookBot) handleViewBot(w http.ResponseWriter, r *http.Request) {
-	j, _ := json.Marshal(b.definition)
+// sendMessageRequest is the request body used to send a message to a user
+type sendMessageRequest struct {
+	UserId string         `json:"user_id"`
+	Text   string         `json:"text"`
+	Tag    api.MessageTag `json:"tag"`
+}
+
+// pageAccessTokenRequest is the request body used to rotate the page access token
+type pageAccessTokenRequest struct {
+	PageAccessToken string `json:"page_access_token"`
+}
+
+// threadControlRequest is the request body used to pass or take the thread control
+type threadControlRequest struct {
+	UserId      string `json:"user_id"`
+	TargetAppId string `json:"target_app_id"`
+	Metadata    string `json:"metadata"`
+}
+
+// handleViewMessengerProfile shows the page's Messenger profile, as currently set on Facebook
+func (b *facebookBot) handleViewMessengerProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := b.fbApi.GetMessengerProfile()
+
+	if err != nil {
+		log.Errorf("Could not get the Messenger profile: %s", err)
+		http.Error(w, "Could not get the Messenger profile", http.StatusBadGateway)
+		return
+	}
+
+	j, _ := json.Marshal(profile)
+
+	w.Write(j)
+}
+
+// handleUpdateMessengerProfile stores the Messenger profile within the bot's definition
+// and syncs it with Facebook. Fields that are not provided are removed from the profile.
+func (b *facebookBot) handleUpdateMessengerProfile(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var profile api.MessengerProfile
+	err := decoder.Decode(&profile)
+
+	if err != nil {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid Messenger profile", http.StatusBadRequest)
+		return
+	}
+
+	err = b.updateDefinition(func(definition *bot.Definition) error {
+		return setMessengerProfileToDefinition(definition, &profile)
+	})
+
+	if err == ErrCouldNotSyncProfile {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err != nil {
+		log.Errorf("Could not save the bot: %s", err)
+		http.Error(w, "Could not save the bot", http.StatusInternalServerError)
+		return
+	}
+
+	j, _ := json.Marshal(profile)
 
 	w.Write(j)
 }
+
+// handlePassThreadControl passes the thread control of a user to another app.
+// The Page Inbox is used when no target app is provided.
+func (b *facebookBot) handlePassThreadControl(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body threadControlRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.UserId == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if body.TargetAppId == "" {
+		body.TargetAppId = api.PageInboxAppId
+	}
+
+	err = b.conversationHandler.passThreadControl(body.UserId, body.TargetAppId, body.Metadata)
+
+	if err != nil {
+		log.WithField("user", body.UserId).Errorf("Could not pass the thread control: %s", err)
+		http.Error(w, "Could not pass the thread control", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTakeThreadControl takes the thread control of a user back to the bot
+func (b *facebookBot) handleTakeThreadControl(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body threadControlRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.UserId == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err = b.conversationHandler.takeThreadControl(body.UserId, body.Metadata)
+
+	if err != nil {
+		log.WithField("user", body.UserId).Errorf("Could not take the thread control: %s", err)
+		http.Error(w, "Could not take the thread control", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSendMessage sends a message to a user, outside of any conversation flow.
+// Messages sent outside of the user's messaging window must be tagged.
+func (b *facebookBot) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body sendMessageRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.UserId == "" || body.Text == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err = b.conversationHandler.sendText(body.UserId, body.Text, body.Tag)
+
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case err == conversation.ErrNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case err == api.ErrInvalidMessageTag:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, api.ErrOutsideMessagingWindow), errors.Is(err, api.ErrUserBlockedPage):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		log.WithField("user", body.UserId).Errorf("Could not send the message: %s", err)
+		http.Error(w, "Could not send the message", http.StatusBadGateway)
+	}
+}
+
+// handleUpdatePageAccessToken stores the new page access token within the bot's definition
+// and starts using it right away, without having to restart the app. The Messenger profile
+// is then synced using the new token.
+func (b *facebookBot) handleUpdatePageAccessToken(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body pageAccessTokenRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.PageAccessToken == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err = b.updateDefinition(func(definition *bot.Definition) error {
+		definition.Parameters[PageAccessToken] = body.PageAccessToken
+		b.fbApi.SetPageAccessToken(body.PageAccessToken)
+
+		return nil
+	})
+
+	if err == ErrCouldNotSyncProfile {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err != nil {
+		log.Errorf("Could not save the bot: %s", err)
+		http.Error(w, "Could not save the bot", http.StatusInternalServerError)
+		return
+	}
+
+	log.WithField("bot", b.Definition().Slug).Info("Page access token updated")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,29 +2,65 @@
 package facebook
 
 import (
+	"net/http"
+	"sync"
+
 	"github.com/aziule/conversation-management/core/api"
 	"github.com/aziule/conversation-management/core/bot"
 	"github.com/aziule/conversation-management/core/conversation"
-	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
 )
 
-const VerifyToken bot.ParamName = "verify_token"
+const (
+	VerifyToken bot.ParamName = "verify_token"
+	// PageAccessToken is the page's access token. It can be rotated through the API without a restart.
+	PageAccessToken bot.ParamName = "page_access_token"
+	// PageId is the id of the page, used to route the events received on the shared webhook to the bot
+	PageId bot.ParamName = "page_id"
+	// ApiVersion is the Graph API version, such as "2.6". The app-wide version is used when it is missing.
+	ApiVersion bot.ParamName = "api_version"
+	// AppSecret is the Facebook app's secret, used to check the signature of the events.
+	// The app-wide secret is used when it is missing.
+	AppSecret bot.ParamName = "app_secret"
+	// AppId is the Facebook app's id, used to know when the thread control
+	// is passed back to the bot (Handover Protocol)
+	AppId bot.ParamName = "app_id"
+	// MarkSeen tells whether received messages should be marked as seen
+	MarkSeen bot.ParamName = "mark_seen"
+	// SimulateTyping tells whether the typing indicator should be shown before sending answers
+	SimulateTyping bot.ParamName = "simulate_typing"
+	// RateLimit is the number of requests per second sent to the Graph API for the page, such as 10
+	RateLimit bot.ParamName = "rate_limit"
+	// MaxRetries is the number of times a failed request is sent again, when it cannot be delivered twice
+	MaxRetries bot.ParamName = "max_retries"
+	// MessengerProfile stores the page's Messenger profile (Get Started, persistent menu, etc.)
+	MessengerProfile bot.ParamName = "messenger_profile"
+)
 
 // Config is the config required in order to instantiate a new FacebookBot
 type Config struct {
-	Definition             *bot.Definition
-	FbApi                  api.FacebookApi
-	NlpParser              nlp.Parser
-	ConversationRepository conversation.Repository
-	StoryRepository        conversation.StoryRepository
+	Definition    *bot.Definition
+	BotRepository bot.Repository
+	FbApi         api.FacebookApi
+	Engine        *conversation.Engine
+	AppSecret     string
 }
 
 // facebookBot is the main structure
+//
+// The definition is never changed in place, as the webhooks read it concurrently: updates are
+// made on a copy, which then replaces the definition (see updateDefinition).
 type facebookBot struct {
 	webhooks            []*bot.Webhook
 	apiEndpoints        []*bot.ApiEndpoint
+	definitionMutex     sync.RWMutex
 	definition          *bot.Definition
-	conversationHandler conversation.Handler
+	updateMutex         sync.Mutex
+	botRepository       bot.Repository
+	fbApi               api.FacebookApi
+	appSecret           string
+	conversationHandler *conversationHandler
 }
 
 // NewBot is the constructor method that creates a Facebook bot, using
@@ -33,27 +69,127 @@ type facebookBot struct {
 // Upon creation:
 // - The webhooks are attached.
 // - We load the list of stories.
+// - The bot is registered to the shared webhook, using its page id.
+// - The Messenger profile is synced with Facebook.
 func NewBot(config *Config) *facebookBot {
 	bot := &facebookBot{
-		definition: config.Definition,
+		definition:    config.Definition,
+		botRepository: config.BotRepository,
+		fbApi:         config.FbApi,
+		appSecret:     config.AppSecret,
 	}
 
-	// @todo: we need to check if all of the stories's steps are being handled
-	// and if any are missing / deprecated.
 	bot.conversationHandler = newConversationHandler(
-		bot.getDefaultStepsMapping(), // @todo: directly pass the step handler rather than the steps mapping
-		config.ConversationRepository,
-		config.StoryRepository,
-		config.NlpParser,
+		config.Engine,
 		config.FbApi,
+		config.Definition.StringParam(AppId),
+		config.Definition.BoolParam(MarkSeen),
+		config.Definition.BoolParam(SimulateTyping),
 	)
 
 	bot.bindDefaultWebhooks()
 	bot.bindDefaultApiEndpoints()
 
+	pageId := config.Definition.StringParam(PageId)
+
+	if pageId != "" {
+		pages.register(pageId, bot)
+	} else {
+		log.WithField("bot", bot.definition.Slug).Warning("No page id: the bot will not receive events from the shared webhook")
+	}
+
+	err := bot.syncMessengerProfile()
+
+	if err != nil {
+		log.WithField("bot", bot.definition.Slug).Errorf("Could not sync the Messenger profile: %s", err)
+	}
+
 	return bot
 }
 
+// buildBot is the builder registered for the Facebook platform. The Facebook API is built
+// from the bot's parameters, falling back to the app-wide API version.
+// Returns api.ErrMissingAppSecret if neither the bot nor the app have an app secret, and
+// an error if the rate limit or the number of retries are invalid.
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	botRepository, ok := utils.GetParam(conf, "bot_repository").(bot.Repository)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("bot_repository")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	version := definition.StringParam(ApiVersion)
+
+	if version == "" {
+		version, _ = utils.GetParam(conf, "fb_api_version").(string)
+	}
+
+	appSecret := definition.StringParam(AppSecret)
+
+	if appSecret == "" {
+		appSecret, _ = utils.GetParam(conf, "fb_app_secret").(string)
+	}
+
+	if appSecret == "" {
+		return nil, api.ErrMissingAppSecret
+	}
+
+	// The shared webhook is validated using the app-wide verify token
+	if verifyToken, _ := utils.GetParam(conf, "fb_verify_token").(string); verifyToken != "" {
+		pages.setVerifyToken(verifyToken)
+	}
+
+	fbApiConf := map[string]interface{}{
+		"page_access_token": definition.StringParam(PageAccessToken),
+		"page_id":           definition.StringParam(PageId),
+		"version":           version,
+		"client":            client,
+	}
+
+	// The rate limit and the number of retries are validated by the Facebook API
+	if rateLimit, ok := definition.Parameters[RateLimit]; ok {
+		fbApiConf["rate_limit"] = rateLimit
+	}
+
+	if maxRetries, ok := definition.Parameters[MaxRetries]; ok {
+		fbApiConf["max_retries"] = maxRetries
+	}
+
+	fbApi, err := api.NewFacebookApi("facebook", fbApiConf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBot(
+		&Config{
+			Definition:    definition,
+			BotRepository: botRepository,
+			FbApi:         fbApi,
+			Engine:        engine,
+			AppSecret:     appSecret,
+		},
+	), nil
+}
+
 // Webhooks returns the bot's webhooks.
 // This method is required in order to implement the Bot interface.
 func (b *facebookBot) Webhooks() []*bot.Webhook {
@@ -69,18 +205,14 @@ func (b *facebookBot) ApiEndpoints() []*bot.ApiEndpoint {
 // Definition returns the bot's definition.
 // This method is required in order to implement the Bot interface.
 func (b *facebookBot) Definition() *bot.Definition {
+	b.definitionMutex.RLock()
+	defer b.definitionMutex.RUnlock()
+
 	return b.definition
 }
 
-// getDefaultStepsMapping returns the default steps mapping between
-// a step's name and its handling func.
-// @todo: find a better name and/or move somewhere else
-func (b *facebookBot) getDefaultStepsMapping() conversation.StepsProcessMap {
-	pm := conversation.StepsProcessMap{}
-
-	pm["book_table_entrypoint"] = b.processStepBookTable
-	pm["book_table_get_nb_persons"] = b.processStepBookTableGetNbPersons
-	pm["book_table_get_time"] = b.processStepBookTableGetTime
-
-	return pm
+func init() {
+	bot.RegisterSecretParams(bot.PlatformFacebook, VerifyToken, PageAccessToken, AppSecret)
+	bot.RegisterBuilder(bot.PlatformFacebook, buildBot)
+	bot.RegisterSharedWebhooks(bot.PlatformFacebook, pages.webhooks()...)
 }
@@ -1,328 +1,282 @@
 package facebook
 
 import (
-	"errors"
 	"net/http"
+	"time"
 
 	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
 	"github.com/aziule/conversation-management/core/conversation"
-	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/mgo.v2/bson"
 )
 
-// conversationHandler is the struct responsible for handling Facebook conversations
+const (
+	// typingDelayPerChar is the time it takes to "type" a single character
+	// when simulating typing before sending an answer.
+	typingDelayPerChar = 40 * time.Millisecond
+	// maxTypingDelay caps the typing delay, so that long answers are not
+	// delayed for too long.
+	maxTypingDelay = 3 * time.Second
+)
+
+// platform is the platform name given to the conversation engine
+var platform = string(bot.PlatformFacebook)
+
+// conversationHandler is the struct responsible for handling Facebook conversations.
+// It translates Facebook messages for the conversation engine, and sends its answers back.
 type conversationHandler struct {
-	stepHandler            *conversation.StepHandler
-	conversationRepository conversation.Repository
-	storyRepository        conversation.StoryRepository
-	nlpParser              nlp.Parser
-	fbApi                  api.FacebookApi
+	engine         *conversation.Engine
+	fbApi          api.FacebookApi
+	appId          string
+	markSeen       bool
+	simulateTyping bool
+	// queue handles the events of each user in order, in the background, so that the webhook
+	// answers Facebook right away even though the answers are delayed by the typing simulation
+	queue *utils.SerialQueue
 }
 
 // newConversationHandler is the constructor method for conversationHandler
-func newConversationHandler(pm conversation.StepsProcessMap, cr conversation.Repository, sr conversation.StoryRepository, p nlp.Parser, a api.FacebookApi) *conversationHandler {
+func newConversationHandler(e *conversation.Engine, a api.FacebookApi, appId string, markSeen, simulateTyping bool) *conversationHandler {
 	return &conversationHandler{
-		stepHandler:            conversation.NewStepHandler(pm),
-		conversationRepository: cr,
-		storyRepository:        sr,
-		nlpParser:              p,
-		fbApi:                  a,
+		engine:         e,
+		fbApi:          a,
+		appId:          appId,
+		markSeen:       markSeen,
+		simulateTyping: simulateTyping,
+		queue:          utils.NewSerialQueue(),
 	}
 }
 
-// MessageReceived is the implementation of ConversationHandler.MessageReceived method.
-// It handles the whole conversation processing logic for Facebook bots.
+// MessagesReceived handles the events received from Facebook:
 //
 // - Parsing the request
-// - Validating the message
-// - Parsing NLP
-// - Managing the conversation flow
+// - Handing each message and postback over to the conversation engine
 // - Answering the user
-// - Modifying the conversation's status
-func (h *conversationHandler) MessageReceived(r *http.Request) {
-	facebookReceivedMessage, err := h.fbApi.ParseRequestMessageReceived(r)
+//
+// The request is parsed right away, and the events are then handled in the background,
+// in order for each user. The bot stays silent while a human owns the thread (Handover Protocol).
+func (h *conversationHandler) MessagesReceived(r *http.Request) {
+	facebookReceivedMessages, err := h.fbApi.ParseRequestMessagesReceived(r)
 
 	if err != nil {
 		// @todo: handle this case and return something to the user
-		log.WithField("message", facebookReceivedMessage).Errorf("Could not parse the received message: %s", err)
+		log.Errorf("Could not parse the received messages: %s", err)
 		return
 	}
 
-	user, err := h.getUser(facebookReceivedMessage.SenderId)
+	for _, facebookReceivedMessage := range facebookReceivedMessages {
+		message := facebookReceivedMessage
 
-	if err != nil {
-		// @todo: handle this case and return something to the user
-		log.WithField("user", facebookReceivedMessage.SenderId).Errorf("Could not find the user: %s", err)
-		return
+		h.queue.Push(message.SenderId, func() {
+			h.messageReceived(message)
+		})
 	}
+}
 
-	c, err := h.getConversation(user)
-
-	if err != nil {
-		// @todo: handle this case and return something to the user
-		log.WithField("user", user).Infof("Could not get / create conversation: %s", err)
+// messageReceived handles a single event received from Facebook
+func (h *conversationHandler) messageReceived(facebookReceivedMessage *api.FacebookReceivedMessage) {
+	switch facebookReceivedMessage.Event {
+	case api.FacebookEventAccountLinking:
+		h.accountLinkingChanged(facebookReceivedMessage)
+		return
+	case api.FacebookEventPassThreadControl, api.FacebookEventTakeThreadControl:
+		h.threadControlChanged(facebookReceivedMessage)
 		return
 	}
 
-	log.WithField("conversation", c).Debug("Conversation fetched")
-
-	userMessage := conversation.NewUserMessage(
-		facebookReceivedMessage.Text,
-		facebookReceivedMessage.SentAt,
-		user,
-		nil,
-	)
-
-	c.AddMessage(userMessage)
-
-	h.conversationRepository.SaveConversation(c)
+	if h.markSeen && !facebookReceivedMessage.Standby {
+		err := h.fbApi.SendSenderAction(facebookReceivedMessage.SenderId, api.SenderActionMarkSeen)
 
-	if facebookReceivedMessage.Nlp == nil {
-		// @todo: handle this case: parse the text using the NLP parser
-		log.Errorf("No data to parse")
-		return
+		if err != nil {
+			log.WithField("user", facebookReceivedMessage.SenderId).Infof("Could not mark the message as seen: %s", err)
+		}
 	}
 
-	parsedData, err := h.nlpParser.ParseNlpData(facebookReceivedMessage.Nlp)
+	messages, err := h.engine.Handle(&conversation.InboundMessage{
+		Platform:          platform,
+		SenderId:          facebookReceivedMessage.SenderId,
+		Text:              facebookReceivedMessage.Text,
+		QuickReplyPayload: facebookReceivedMessage.QuickReplyPayload,
+		Postback:          facebookReceivedMessage.PostbackPayload,
+		SentAt:            facebookReceivedMessage.SentAt,
+		Nlp:               facebookReceivedMessage.Nlp,
+		Silent:            facebookReceivedMessage.Standby,
+	})
 
 	if err != nil {
-		// @todo: handle this case and return something to the user. Make sure the
-		// conversation is saved with the message. For example, we could think
-		// about adding a flag to the message, like:
-		// - could_not_parse_nlp
-		// - could_not_process
-		// - something_else
-		// - ...
-		// => gives more context and allows us to save data & understand it even
-		// though errors occur.
-		log.WithField("nlp", facebookReceivedMessage.Nlp).Errorf("Could not parse NLP data: %s", err)
+		// @todo: handle this case and return something to the user
+		log.WithField("user", facebookReceivedMessage.SenderId).Errorf("Could not handle the message: %s", err)
 		return
 	}
 
-	userMessage.ParsedData = parsedData
+	h.answer(facebookReceivedMessage.SenderId, messages)
+}
 
-	log.WithField("data", parsedData).Debug("Data parsed from message")
+// threadControlChanged is called when the thread control has been passed or taken (Handover Protocol).
+// The conversation's status reflects who owns the thread: the bot or a human.
+func (h *conversationHandler) threadControlChanged(facebookReceivedMessage *api.FacebookReceivedMessage) {
+	status := conversation.StatusHumanIntervention
 
-	h.conversationRepository.SaveConversation(c)
+	// When the control is passed back to our app, the bot can answer again
+	if facebookReceivedMessage.Event == api.FacebookEventPassThreadControl &&
+		facebookReceivedMessage.ThreadControl.NewOwnerAppId == h.appId {
+		status = conversation.StatusOngoing
+	}
+
+	log.WithFields(log.Fields{
+		"event":  facebookReceivedMessage.Event,
+		"user":   facebookReceivedMessage.SenderId,
+		"status": status,
+	}).Info("Thread control changed")
 
-	err = h.processData(parsedData, c)
+	err := h.engine.SetStatus(platform, facebookReceivedMessage.SenderId, status)
 
 	if err != nil {
-		log.WithFields(log.Fields{
-			"data":         parsedData,
-			"conversation": c,
-		}).Errorf("Could not process the data: %s", err)
+		log.WithField("user", facebookReceivedMessage.SenderId).Errorf("Could not update the conversation's status: %s", err)
 	}
-
-	return
 }
 
-// processData is the method responsible for taking actions on a conversation using the provided NLP data
-func (h *conversationHandler) processData(data *nlp.ParsedData, c *conversation.Conversation) error {
+// accountLinkingChanged is called when the user linked or unlinked its account, using the account linking flow.
+// The authorization code is expected to be a link code, created on another platform for the user
+// (see conversation.IdentityManager.CreateLinkCode).
+func (h *conversationHandler) accountLinkingChanged(facebookReceivedMessage *api.FacebookReceivedMessage) {
 	var err error
-
-	if c.CurrentStep == "" {
-		log.WithField("c", c).Info("Try starting a new story")
-		err = h.tryStartStory(data, c)
-	} else {
-		log.WithField("c", c).Info("Try progressing in the current story")
-		err = h.tryProgressInStory(data, c)
+	fbId := facebookReceivedMessage.SenderId
+	accountLinking := facebookReceivedMessage.AccountLinking
+
+	switch accountLinking.Status {
+	case api.AccountLinkingLinked:
+		_, err = h.engine.Identities().LinkWithCode(platform, fbId, accountLinking.AuthorizationCode)
+	case api.AccountLinkingUnlinked:
+		_, err = h.engine.Identities().UnlinkIdentity(platform, fbId)
+	default:
+		log.WithField("status", accountLinking.Status).Info("Unhandled account linking status")
+		return
 	}
 
 	if err != nil {
-		// @todo: handle: save the user message here?
-		return err
+		log.WithFields(log.Fields{
+			"user":   fbId,
+			"status": accountLinking.Status,
+		}).Errorf("Could not update the account linking: %s", err)
+		return
 	}
 
-	return nil
+	log.WithFields(log.Fields{
+		"user":   fbId,
+		"status": accountLinking.Status,
+	}).Info("Account linking changed")
 }
 
-// tryStartStory will try to start a new story using the provided NLP data.
-// It will go through the available stories and see if any step can be initiated.
-func (h *conversationHandler) tryStartStory(data *nlp.ParsedData, c *conversation.Conversation) error {
-	stories, err := h.storyRepository.FindAll()
+// passThreadControl passes the thread control to another app, such as the Page Inbox,
+// so that a human can answer the user. The bot stays silent until it gets the control back.
+func (h *conversationHandler) passThreadControl(fbId, targetAppId, metadata string) error {
+	err := h.fbApi.PassThreadControl(fbId, targetAppId, metadata)
 
 	if err != nil {
-		log.Error("Could not load stories")
 		return err
 	}
 
-	var startingStep *conversation.Step
-
-	for _, story := range stories {
-		log.WithField("story", story).Debugf("Trying to step in story")
-
-		if startingStep != nil {
-			break
-		}
-
-		for _, step := range story.StartingSteps {
-			if h.stepHandler.CanStepIn(step, data) {
-				log.WithField("step", step).Debugf("Stepping in")
-
-				startingStep = step
-				break
-			}
-		}
-	}
-
-	if startingStep == nil {
-		log.WithFields(log.Fields{
-			"data":         data,
-			"conversation": c,
-		}).Info("Cannot start a story")
-
-		return errors.New("Handle this. Don't forget to save the conversation with the message")
-	}
-
-	return h.processStep(c, startingStep, data)
+	return h.engine.SetStatus(platform, fbId, conversation.StatusHumanIntervention)
 }
 
-// tryProgressInStory is the method being called when a conversation is ongoing and we try to progress
-// within the current story.
-func (h *conversationHandler) tryProgressInStory(data *nlp.ParsedData, c *conversation.Conversation) error {
-	stories, err := h.storyRepository.FindAll()
+// takeThreadControl takes the thread control back from another app, so that
+// the bot can answer the user again.
+func (h *conversationHandler) takeThreadControl(fbId, metadata string) error {
+	err := h.fbApi.TakeThreadControl(fbId, metadata)
 
 	if err != nil {
-		// @todo: log, and save conversation
-		return errors.New("Cannot load stories")
+		return err
 	}
 
-	var currentStep *conversation.Step
-
-	// Find the current step of the conversation
-	for _, story := range stories {
-		step := story.FindStep(c.CurrentStep)
+	return h.engine.SetStatus(platform, fbId, conversation.StatusOngoing)
+}
 
-		if step != nil {
-			currentStep = step
-			break
+// answer sends the messages to the user, one at a time, along with their quick replies.
+// When typing simulation is enabled, the typing indicator is shown before
+// each message, during a delay proportional to the message's length.
+func (h *conversationHandler) answer(fbId string, messages []*conversation.OutboundMessage) {
+	for _, message := range messages {
+		if h.simulateTyping {
+			h.simulateTypingFor(fbId, message.Text)
 		}
-	}
 
-	if currentStep == nil {
-		log.WithFields(log.Fields{
-			"data":         data,
-			"conversation": c,
-		}).Error("The conversation's current step does not exist in the stories")
-
-		// @todo: return a correct error message.
-		// @todo: handle this case and see how we can prevent
-		// a conversation from being blocked.
-		return errors.New("Could not find any step")
-	}
+		err := h.send(fbId, message)
 
-	var nextStep *conversation.Step
-
-	for _, step := range currentStep.NextSteps {
-		if h.stepHandler.CanStepIn(step, data) {
-			log.WithField("step", step).Debugf("Stepping in")
-
-			nextStep = step
-			break
+		if err != nil {
+			log.WithFields(log.Fields{
+				"user":    fbId,
+				"message": message.Text,
+			}).Errorf("Could not send the message: %s", err)
+			return
 		}
 	}
-
-	if nextStep == nil {
-		log.WithFields(log.Fields{
-			"data":         data,
-			"conversation": c,
-		}).Info("Cannot progress in story")
-
-		return errors.New("Handle this. Don't forget to save the conversation with the message")
-	}
-
-	return h.processStep(c, nextStep, data)
 }
 
-// processStep processes a single step, according to the fact that we should
-// be able, at that stage, to step in the step.
-//
-// So make sure to call step.CanStepIn and that the result is true
-// before calling this method.
-func (h *conversationHandler) processStep(c *conversation.Conversation, s *conversation.Step, data *nlp.ParsedData) error {
-	// Process the step
-	log.WithFields(log.Fields{
-		"step": s,
-		"data": data,
-	}).Info("Processing step")
-
-	err := h.stepHandler.Process(s, data)
-
-	if err != nil {
-		log.Errorf("Could not process the step: %s", err)
-		// @todo: handle this, and save the conversation's message otherwise it's lost
-		return errors.New("nope")
+// send sends a single message as a response, with its quick replies if any
+func (h *conversationHandler) send(fbId string, message *conversation.OutboundMessage) error {
+	if len(message.QuickReplies) == 0 {
+		return h.fbApi.SendTextToUser(fbId, message.Text, api.NewResponseSendOptions())
 	}
 
-	// Update the conversation's state
-	c.CurrentStep = s.Name
+	var quickReplies []*api.FacebookQuickReply
 
-	if s.IsLastStep() {
-		c.Status = conversation.StatusOver
-		log.WithField("conversation", c).Info("Terminating conversation")
+	for _, quickReply := range message.QuickReplies {
+		quickReplies = append(quickReplies, &api.FacebookQuickReply{
+			Title:   quickReply.Title,
+			Payload: quickReply.Payload,
+		})
 	}
 
-	h.conversationRepository.SaveConversation(c)
-
-	return nil
+	return h.fbApi.SendQuickRepliesToUser(fbId, message.Text, quickReplies, api.NewResponseSendOptions())
 }
 
-// getConversation tries to return a Facebook conversation between a given user and the bot.
-// If there is an ongoing conversation, then it will return it.
-// If this is the first conversation or the previous one is marked as done, then it will create a new one.
-func (h *conversationHandler) getConversation(user *conversation.User) (*conversation.Conversation, error) {
-	// => this will help with consolidated users (fb + slack + anything)
-	c, err := h.conversationRepository.FindLatestConversation(user)
+// sendText sends a text to the user outside of any conversation flow, such as reminders
+// or broadcasts. The user's messaging window is computed from the latest message the user sent:
+// outside of it, the message must be tagged.
+// Returns api.ErrOutsideMessagingWindow if the message cannot be sent.
+func (h *conversationHandler) sendText(fbId, text string, tag api.MessageTag) error {
+	lastUserMessageAt, err := h.engine.LastUserMessageAt(platform, fbId)
 
 	if err != nil {
-		if err != conversation.ErrNotFound {
-			return nil, err
-		}
-
-		log.WithField("user", user).Info("Starting a first conversation")
-
-		// The conversation was not found: start a new one
-		c = conversation.CreateNewConversation()
+		return err
 	}
 
-	// Start a new conversation if the previous one is over
-	if c.Status == conversation.StatusOver {
-		log.WithField("user", user).Info("Starting a new conversation")
+	options, err := api.NewSendOptions(lastUserMessageAt, time.Now(), tag)
 
-		c = conversation.CreateNewConversation()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"user":              fbId,
+			"tag":               tag,
+			"lastUserMessageAt": lastUserMessageAt,
+		}).Infof("Refusing to send the message: %s", err)
+		return err
 	}
 
-	return c, nil
+	return h.fbApi.SendTextToUser(fbId, text, options)
 }
 
-// getUser tries to find an existing user using the id provided as the facebook id.
-// If it does not find any user then it will create a new one using the facebook id.
-func (h *conversationHandler) getUser(id string) (*conversation.User, error) {
-	user, err := h.conversationRepository.FindUserByFbId(id)
+// simulateTypingFor shows the typing indicator to the user and waits for
+// the time it would take to type the given text.
+func (h *conversationHandler) simulateTypingFor(fbId string, text string) {
+	err := h.fbApi.SendSenderAction(fbId, api.SenderActionTypingOn)
 
-	if err != nil && err != conversation.ErrNotFound {
-		return nil, err
+	if err != nil {
+		log.WithField("user", fbId).Infof("Could not show the typing indicator: %s", err)
+		return
 	}
 
-	if user == nil {
-		log.WithField("fbId", id).Infof("Inserting a new user")
-
-		user = &conversation.User{
-			Id:   bson.NewObjectId(),
-			FbId: id,
-		}
+	time.Sleep(typingDelay(text))
+}
 
-		// Insert the user
-		err = h.conversationRepository.InsertUser(user)
+// typingDelay returns the delay for typing the given text, capped to maxTypingDelay
+func typingDelay(text string) time.Duration {
+	delay := time.Duration(len([]rune(text))) * typingDelayPerChar
 
-		if err != nil {
-			// @todo: handle this case and return something to the user
-			log.WithField("fbId", id).Info("Could not insert the user")
-			return nil, err
-		}
+	if delay > maxTypingDelay {
+		return maxTypingDelay
 	}
 
-	return user, nil
+	return delay
 }
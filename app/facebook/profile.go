@@ -0,0 +1,137 @@
+package facebook
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrCouldNotSyncProfile = errors.New("Could not sync the Messenger profile")
+
+// messengerProfileFromDefinition returns the Messenger profile stored within the definition's parameters.
+// Returns an empty profile if none is stored.
+func messengerProfileFromDefinition(definition *bot.Definition) (*api.MessengerProfile, error) {
+	profile := &api.MessengerProfile{}
+	raw, ok := definition.Parameters[MessengerProfile]
+
+	if !ok || raw == nil {
+		return profile, nil
+	}
+
+	// The stored value can either come from JSON or BSON: use JSON to convert it
+	data, err := json.Marshal(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, profile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// setMessengerProfileToDefinition stores the Messenger profile within the definition's parameters
+func setMessengerProfileToDefinition(definition *bot.Definition, profile *api.MessengerProfile) error {
+	data, err := json.Marshal(profile)
+
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	err = json.Unmarshal(data, &raw)
+
+	if err != nil {
+		return err
+	}
+
+	if definition.Parameters == nil {
+		definition.Parameters = make(map[bot.ParamName]interface{})
+	}
+
+	definition.Parameters[MessengerProfile] = raw
+
+	return nil
+}
+
+// syncMessengerProfile pushes the Messenger profile stored in the bot's definition to Facebook.
+// The fields that are set are updated, and the ones that are not set are deleted.
+func (b *facebookBot) syncMessengerProfile() error {
+	definition := b.Definition()
+	profile, err := messengerProfileFromDefinition(definition)
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := definition.Parameters[MessengerProfile]; !ok {
+		log.WithField("bot", definition.Slug).Debug("No Messenger profile to sync")
+		return nil
+	}
+
+	if !profile.IsEmpty() {
+		err = b.fbApi.SetMessengerProfile(profile)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	emptyFields := profile.EmptyFields()
+
+	if len(emptyFields) > 0 {
+		err = b.fbApi.DeleteMessengerProfileFields(emptyFields)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	log.WithField("bot", definition.Slug).Info("Messenger profile synced")
+
+	return nil
+}
+
+// updateDefinition applies the update to a copy of the bot's definition, saves it and then
+// replaces the definition with it, so that the webhooks never read a definition being changed.
+// The Messenger profile is then synced, so that Facebook always shows the profile of the stored
+// definition. The errors returned by the sync are ErrCouldNotSyncProfile errors, as the definition was saved.
+func (b *facebookBot) updateDefinition(update func(definition *bot.Definition) error) error {
+	// Updates are made one at a time, so that none of them is lost
+	b.updateMutex.Lock()
+	defer b.updateMutex.Unlock()
+
+	definition := b.Definition().Copy()
+
+	err := update(definition)
+
+	if err != nil {
+		return err
+	}
+
+	err = b.botRepository.Save(definition)
+
+	if err != nil {
+		return err
+	}
+
+	b.definitionMutex.Lock()
+	b.definition = definition
+	b.definitionMutex.Unlock()
+
+	err = b.syncMessengerProfile()
+
+	if err != nil {
+		log.WithField("bot", definition.Slug).Errorf("Could not sync the Messenger profile: %s", err)
+		return ErrCouldNotSyncProfile
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package facebook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	log "github.com/sirupsen/logrus"
+)
+
+// pages routes the events received on the shared webhook to the bots, using their page id
+var pages = newPageRouter()
+
+// pageRouter is the struct responsible for routing the events of a single Facebook app,
+// which can serve many pages, to the bot of each page.
+type pageRouter struct {
+	mutex sync.RWMutex
+	bots  map[string]*facebookBot
+	// verifyToken is the app-wide token used to validate the shared webhook
+	verifyToken string
+}
+
+// routedPayload is the webhook payload, as sent by Facebook. The entries are kept raw
+// so that each of them can be handed over to its bot as is.
+type routedPayload struct {
+	Object string            `json:"object"`
+	Entry  []json.RawMessage `json:"entry"`
+}
+
+// routedEntry contains the fields used to find the page an entry was sent to
+type routedEntry struct {
+	Id        string           `json:"id"`
+	Messaging []*routedMessage `json:"messaging"`
+	Standby   []*routedMessage `json:"standby"`
+}
+
+// routedMessage contains the recipient of a message, which is the page
+type routedMessage struct {
+	Recipient struct {
+		Id string `json:"id"`
+	} `json:"recipient"`
+}
+
+// newPageRouter is the constructor method for pageRouter
+func newPageRouter() *pageRouter {
+	return &pageRouter{
+		bots: make(map[string]*facebookBot),
+	}
+}
+
+// register registers the bot of the given page. A bot registered for the
+// same page is replaced.
+func (router *pageRouter) register(pageId string, b *facebookBot) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	if _, ok := router.bots[pageId]; ok {
+		log.WithField("page", pageId).Warning("Another bot is already registered for the page: replacing it")
+	}
+
+	router.bots[pageId] = b
+}
+
+// setVerifyToken sets the app-wide token used to validate the shared webhook
+func (router *pageRouter) setVerifyToken(verifyToken string) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	router.verifyToken = verifyToken
+}
+
+// find returns the bot of the given page, or nil if there is none
+func (router *pageRouter) find(pageId string) *facebookBot {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+
+	return router.bots[pageId]
+}
+
+// webhooks returns the webhooks shared by all of the Facebook bots
+func (router *pageRouter) webhooks() []*bot.Webhook {
+	return []*bot.Webhook{
+		bot.NewWebhook("GET", "/", router.handleValidateWebhook),
+		bot.NewWebhook("POST", "/", router.handleEventsReceived),
+	}
+}
+
+// handleValidateWebhook tries to validate the shared webhook. The webhook is subscribed by the app
+// rather than by any of its pages: only the app-wide verify token is accepted, and the shared
+// webhook cannot be validated without it.
+func (router *pageRouter) handleValidateWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New shared Facebook webhook validation request")
+
+	router.mutex.RLock()
+	verifyToken := router.verifyToken
+	router.mutex.RUnlock()
+
+	challenge, err := api.VerifyWebhookSubscription(r.URL.Query(), verifyToken)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Validate the webhook by writing back the "hub.challenge" query param
+	w.Write([]byte(challenge))
+}
+
+// handleEventsReceived is called when Facebook sends events for any of the app's pages.
+// Facebook can batch the events of many pages within a single request: each entry is handed
+// over to the bot of the page it was sent to. The payload's signature is checked against the
+// app secret of every bot an entry is routed to, so that a bot cannot forge the events of
+// another bot's page. The whole request is rejected if any of them does not match.
+func (router *pageRouter) handleEventsReceived(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		http.Error(w, "Could not read the request's body", http.StatusBadRequest)
+		return
+	}
+
+	var payload routedPayload
+	err = json.Unmarshal(body, &payload)
+
+	if err != nil {
+		log.WithField("body", string(body)).Infof("Could not parse JSON from the request: %s", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	signature := r.Header.Get(api.SignatureHeader)
+	bots := make([]*facebookBot, len(payload.Entry))
+
+	for i, rawEntry := range payload.Entry {
+		pageId := entryPageId(rawEntry)
+		b := router.find(pageId)
+
+		if b == nil {
+			log.WithField("page", pageId).Warning("No bot registered for the page: ignoring the event")
+			continue
+		}
+
+		err = api.VerifyPayloadSignature(body, signature, b.appSecret)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"page": pageId,
+				"bot":  b.Definition().Slug,
+			}).Infof("Rejecting the events: %s", err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		bots[i] = b
+	}
+
+	for i, rawEntry := range payload.Entry {
+		b := bots[i]
+
+		if b == nil {
+			continue
+		}
+
+		pageId := entryPageId(rawEntry)
+
+		entryBody, err := json.Marshal(&routedPayload{
+			Object: payload.Object,
+			Entry:  []json.RawMessage{rawEntry},
+		})
+
+		if err != nil {
+			log.WithField("page", pageId).Errorf("Could not encode the entry: %s", err)
+			continue
+		}
+
+		entryRequest, err := http.NewRequest(r.Method, r.URL.String(), bytes.NewReader(entryBody))
+
+		if err != nil {
+			log.WithField("page", pageId).Errorf("Could not create the request: %s", err)
+			continue
+		}
+
+		entryRequest.Header = r.Header
+
+		log.WithFields(log.Fields{
+			"page": pageId,
+			"bot":  b.Definition().Slug,
+		}).Debug("Routing the event to the bot")
+
+		b.conversationHandler.MessagesReceived(entryRequest)
+	}
+}
+
+// entryPageId returns the id of the page an entry was sent to: the recipient of its first
+// message, or the entry's id when there is no message.
+func entryPageId(rawEntry json.RawMessage) string {
+	var entry routedEntry
+
+	err := json.Unmarshal(rawEntry, &entry)
+
+	if err != nil {
+		return ""
+	}
+
+	messages := entry.Messaging
+
+	if len(messages) == 0 {
+		messages = entry.Standby
+	}
+
+	if len(messages) > 0 && messages[0].Recipient.Id != "" {
+		return messages[0].Recipient.Id
+	}
+
+	return entry.Id
+}
@@ -1,62 +1,52 @@
 package facebook
 
 import (
+	"bytes"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/aziule/conversation-management/core/api"
 	"github.com/aziule/conversation-management/core/bot"
-	"github.com/aziule/conversation-management/core/utils"
 	log "github.com/sirupsen/logrus"
 )
 
 // handleMessageReceived is called when a new message is sent by the user to the page.
-// We delegate the handling to the Conversation Handler, responsible for most of the logic.
+// The payload's signature is checked using the app secret, and the handling is then delegated
+// to the Conversation Handler, responsible for most of the logic.
 func (bot *facebookBot) handleMessageReceived(w http.ResponseWriter, r *http.Request) {
 	log.Debug("New Facebook message received")
 
-	bot.conversationHandler.MessageReceived(r)
-}
-
-// handleValidateWebhook tries to validate the Facebook webhook
-// More information here: https://developers.facebook.com/docs/messenger-platform/getting-started/quick-start
-func (bot *facebookBot) handleValidateWebhook(w http.ResponseWriter, r *http.Request) {
-	log.Debug("New Facebook webhook validation request")
-
-	queryParams := r.URL.Query()
-
-	hubMode, err := utils.GetSingleQueryParam(queryParams, "hub.mode")
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
 
 	if err != nil {
-		log.WithField("param", "hub.mode").Infof("Could not fetch param: %s", err)
-		return
-	}
-
-	if hubMode != "subscribe" {
-		log.WithFields(log.Fields{
-			"expected": "subscribe",
-			"mode":     hubMode,
-		}).Info("Invalid hub mode")
+		log.Infof("Could not read the request's body: %s", err)
+		http.Error(w, "Could not read the request's body", http.StatusBadRequest)
 		return
 	}
 
-	verifyToken, err := utils.GetSingleQueryParam(queryParams, "hub.verify_token")
+	err = api.VerifyPayloadSignature(body, r.Header.Get(api.SignatureHeader), bot.appSecret)
 
 	if err != nil {
-		log.WithField("param", "hub.verify_token").Infof("Could not fetch param: %s", err)
+		log.WithField("bot", bot.Definition().Slug).Infof("Rejecting the event: %s", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
-	if verifyToken != bot.definition.Parameters[VerifyToken] {
-		log.WithFields(log.Fields{
-			"expected": bot.definition.Parameters[VerifyToken],
-			"token":    verifyToken,
-		}).Info("Invalid verify token")
-		return
-	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	bot.conversationHandler.MessagesReceived(r)
+}
+
+// handleValidateWebhook tries to validate the Facebook webhook
+// More information here: https://developers.facebook.com/docs/messenger-platform/getting-started/quick-start
+func (bot *facebookBot) handleValidateWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New Facebook webhook validation request")
 
-	challenge, err := utils.GetSingleQueryParam(queryParams, "hub.challenge")
+	challenge, err := api.VerifyWebhookSubscription(r.URL.Query(), bot.Definition().StringParam(VerifyToken))
 
 	if err != nil {
-		log.WithField("param", "hub.challenge").Infof("Could not fetch param: %s", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
 
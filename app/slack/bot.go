@@ -0,0 +1,138 @@
+// Package slack defines Slack-related bot methods and behaviour.
+package slack
+
+import (
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const (
+	// Token is the bot user OAuth token (xoxb-...), given when installing the app
+	Token bot.ParamName = "token"
+	// SigningSecret is the app's signing secret, used to make sure the
+	// requests come from Slack
+	SigningSecret bot.ParamName = "signing_secret"
+	// BaseUrl is the base url of the Web API. It is optional and can be
+	// used to target a local stand-in.
+	BaseUrl bot.ParamName = "base_url"
+)
+
+// Config is the config required in order to instantiate a new SlackBot
+type Config struct {
+	Definition *bot.Definition
+	SlackApi   api.SlackApi
+	Engine     *conversation.Engine
+}
+
+// slackBot is the main structure
+type slackBot struct {
+	webhooks            []*bot.Webhook
+	apiEndpoints        []*bot.ApiEndpoint
+	definition          *bot.Definition
+	slackApi            api.SlackApi
+	conversationHandler *conversationHandler
+	// events are the ids of the events received recently, used to ignore the retried events
+	events *seenEvents
+}
+
+// NewBot is the constructor method that creates a Slack bot, using
+// the Config struct as method parameters.
+//
+// Upon creation:
+// - The webhooks are attached: the Events API request url and the
+// interactivity request url need to be set to these webhooks in the app's settings.
+func NewBot(config *Config) *slackBot {
+	bot := &slackBot{
+		definition: config.Definition,
+		slackApi:   config.SlackApi,
+		events:     newSeenEvents(eventTtl),
+	}
+
+	bot.conversationHandler = newConversationHandler(config.Engine, config.SlackApi)
+
+	bot.bindDefaultWebhooks()
+	bot.bindDefaultApiEndpoints()
+
+	return bot
+}
+
+// buildBot is the builder registered for the Slack platform.
+// The Slack API is built from the bot's parameters.
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	// The requests could not be verified without it
+	if definition.StringParam(SigningSecret) == "" {
+		return nil, utils.ErrInvalidOrMissingParam(string(SigningSecret))
+	}
+
+	slackApi, err := api.NewSlackApi("slack", map[string]interface{}{
+		"token":    definition.StringParam(Token),
+		"base_url": definition.StringParam(BaseUrl),
+		"client":   client,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBot(
+		&Config{
+			Definition: definition,
+			SlackApi:   slackApi,
+			Engine:     engine,
+		},
+	), nil
+}
+
+// bindDefaultApiEndpoints initialises the default API endpoints.
+func (b *slackBot) bindDefaultApiEndpoints() {
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"GET",
+		"/",
+		bot.HandleViewBot(b),
+	))
+}
+
+// Webhooks returns the bot's webhooks.
+// This method is required in order to implement the Bot interface.
+func (b *slackBot) Webhooks() []*bot.Webhook {
+	return b.webhooks
+}
+
+// ApiEndpoints returns the bot's available API endpoints.
+// This method is required in order to implement the Bot interface.
+func (b *slackBot) ApiEndpoints() []*bot.ApiEndpoint {
+	return b.apiEndpoints
+}
+
+// Definition returns the bot's definition.
+// This method is required in order to implement the Bot interface.
+func (b *slackBot) Definition() *bot.Definition {
+	return b.definition
+}
+
+func init() {
+	bot.RegisterSecretParams(bot.PlatformSlack, Token, SigningSecret)
+	bot.RegisterBuilder(bot.PlatformSlack, buildBot)
+}
@@ -0,0 +1,156 @@
+package slack
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Slack channel types
+const (
+	channelTypeIm = "im"
+)
+
+var (
+	// platform is the platform name given to the conversation engine
+	platform = string(bot.PlatformSlack)
+	// mentionPattern matches the user mentions, such as "<@U024BE7LH>" or "<@U024BE7LH|bob>"
+	mentionPattern = regexp.MustCompile(`<@[UW][A-Z0-9]+(\|[^>]*)?>`)
+)
+
+// conversationHandler is the struct responsible for handling Slack conversations.
+// It translates Slack events for the conversation engine, and sends its answers back.
+type conversationHandler struct {
+	engine   *conversation.Engine
+	slackApi api.SlackApi
+	// queue handles the events of each user in order
+	queue *utils.SerialQueue
+}
+
+// newConversationHandler is the constructor method for conversationHandler
+func newConversationHandler(e *conversation.Engine, a api.SlackApi) *conversationHandler {
+	return &conversationHandler{
+		engine:   e,
+		slackApi: a,
+		queue:    utils.NewSerialQueue(),
+	}
+}
+
+// Push handles the event in the background, after the user's previous events
+func (h *conversationHandler) Push(event *api.SlackEvent) {
+	h.queue.Push(senderId(event), func() {
+		h.EventReceived(event)
+	})
+}
+
+// EventReceived handles the events received from Slack:
+//
+// - Direct messages sent to the bot
+// - Mentions of the bot in channels
+// - Quick reply buttons pressed by the user
+//
+// Users are identified by their workspace and Slack user id, and the answers are sent
+// to the channel the event comes from. The mentions are removed from the text, so that
+// they are not sent to the NLP service.
+func (h *conversationHandler) EventReceived(event *api.SlackEvent) {
+	// Never answer bots, including ourselves
+	if event.BotId != "" || event.UserId == "" {
+		return
+	}
+
+	// Channel messages are only handled when the bot is mentioned
+	if event.Type == api.SlackEventMessage && event.ChannelType != channelTypeIm {
+		return
+	}
+
+	in := &conversation.InboundMessage{
+		Platform: platform,
+		SenderId: senderId(event),
+		Text:     stripMentions(event.Text),
+		SentAt:   event.SentAt,
+	}
+
+	if event.Type == api.SlackEventBlockActions {
+		in.QuickReplyPayload = event.ActionValue
+	}
+
+	messages, err := h.engine.Handle(in)
+
+	if err != nil {
+		// @todo: handle this case and return something to the user
+		log.WithField("user", senderId(event)).Errorf("Could not handle the event: %s", err)
+		return
+	}
+
+	h.answer(event.ChannelId, messages)
+}
+
+// answer sends the messages to the channel, one at a time.
+// Quick replies are sent as buttons, in an actions block below the text.
+func (h *conversationHandler) answer(channelId string, messages []*conversation.OutboundMessage) {
+	for _, message := range messages {
+		err := h.slackApi.PostMessage(channelId, message.Text, toBlocks(message))
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"channel": channelId,
+				"message": message.Text,
+			}).Errorf("Could not send the message: %s", err)
+			return
+		}
+	}
+}
+
+// senderId returns the id identifying the user who sent the event. Slack user ids are only
+// unique within a workspace, so they are prefixed with the workspace's id, such as "T061EG9R6:U2147483697".
+func senderId(event *api.SlackEvent) string {
+	if event.TeamId == "" {
+		return event.UserId
+	}
+
+	return event.TeamId + ":" + event.UserId
+}
+
+// stripMentions removes the user mentions from the text, such as "<@U024BE7LH> book a table"
+func stripMentions(text string) string {
+	return strings.Join(strings.Fields(mentionPattern.ReplaceAllString(text, " ")), " ")
+}
+
+// toBlocks translates a message to Block Kit blocks.
+// Returns nil for text-only messages, which are sent as plain text.
+func toBlocks(message *conversation.OutboundMessage) []*api.SlackBlock {
+	if len(message.QuickReplies) == 0 {
+		return nil
+	}
+
+	actions := &api.SlackBlock{Type: "actions"}
+
+	for i, quickReply := range message.QuickReplies {
+		actions.Elements = append(actions.Elements, &api.SlackElement{
+			Type: "button",
+			Text: &api.SlackText{
+				Type: "plain_text",
+				Text: quickReply.Title,
+			},
+			Value:    quickReply.Payload,
+			ActionId: "quick_reply_" + strconv.Itoa(i),
+		})
+	}
+
+	return []*api.SlackBlock{
+		{
+			Type: "section",
+			Text: &api.SlackText{
+				Type: "mrkdwn",
+				Text: message.Text,
+			},
+		},
+		actions,
+	}
+}
@@ -0,0 +1,52 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// eventTtl is how long the ids of the received events are remembered. Slack retries
+// an event up to 3 times within a few minutes when it did not get an answer.
+const eventTtl = 10 * time.Minute
+
+// seenEvents remembers the ids of the events received recently, so that the events
+// Slack sends again are only handled once.
+type seenEvents struct {
+	mutex     sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	lastPurge time.Time
+}
+
+// newSeenEvents is the constructor method for seenEvents
+func newSeenEvents(ttl time.Duration) *seenEvents {
+	return &seenEvents{
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+	}
+}
+
+// Seen remembers the event id, and tells whether it was already seen within the TTL.
+// The expired ids are forgotten along the way.
+func (s *seenEvents) Seen(eventId string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.lastPurge) > s.ttl {
+		for id, seenAt := range s.seen {
+			if now.Sub(seenAt) > s.ttl {
+				delete(s.seen, id)
+			}
+		}
+
+		s.lastPurge = now
+	}
+
+	if seenAt, ok := s.seen[eventId]; ok && now.Sub(seenAt) <= s.ttl {
+		return true
+	}
+
+	s.seen[eventId] = now
+
+	return false
+}
@@ -0,0 +1,166 @@
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	signatureHeader = "X-Slack-Signature"
+	timestampHeader = "X-Slack-Request-Timestamp"
+	retryNumHeader  = "X-Slack-Retry-Num"
+	// signatureVersion is the version of the signatures sent by Slack
+	signatureVersion = "v0"
+	// maxRequestAge is the maximum age of a request, to prevent replay attacks
+	maxRequestAge = 5 * time.Minute
+)
+
+var (
+	ErrInvalidSignature     = errors.New("Invalid request signature")
+	ErrRequestTooOld        = errors.New("The request is too old")
+	ErrMissingSigningSecret = errors.New("Missing signing secret")
+)
+
+// handleEventReceived is called when the Events API sends a new event to the webhook.
+// Slack expects an answer within 3 seconds, so the events are handled asynchronously,
+// in order for each user. Slack sends the events again when it did not get an answer:
+// the events already received are ignored, using their id.
+func (b *slackBot) handleEventReceived(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New Slack event received")
+
+	if !b.verifyRequest(w, r) {
+		return
+	}
+
+	event, err := b.slackApi.ParseEvent(r)
+
+	if err != nil {
+		log.Debugf("Ignoring the event: %s", err)
+		return
+	}
+
+	if event.Type == api.SlackEventUrlVerification {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(event.Challenge))
+		return
+	}
+
+	if event.EventId != "" && b.events.Seen(event.EventId, time.Now()) {
+		log.WithFields(log.Fields{
+			"event": event.EventId,
+			"retry": r.Header.Get(retryNumHeader),
+		}).Debug("Ignoring the event: it was already received")
+		return
+	}
+
+	b.conversationHandler.Push(event)
+}
+
+// handleInteractionReceived is called when the user interacts with a Block Kit component,
+// such as a quick reply button.
+func (b *slackBot) handleInteractionReceived(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New Slack interaction received")
+
+	if !b.verifyRequest(w, r) {
+		return
+	}
+
+	event, err := b.slackApi.ParseInteraction(r)
+
+	if err != nil {
+		log.Debugf("Ignoring the interaction: %s", err)
+		return
+	}
+
+	b.conversationHandler.Push(event)
+}
+
+// verifyRequest checks the request's signature and writes an error to the response when invalid.
+// The request's body is kept intact, so that it can be parsed afterwards.
+// More information here: https://api.slack.com/authentication/verifying-requests-from-slack
+func (b *slackBot) verifyRequest(w http.ResponseWriter, r *http.Request) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	if err != nil {
+		http.Error(w, "Could not read the request's body", http.StatusBadRequest)
+		return false
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	err = verifySignature(
+		b.definition.StringParam(SigningSecret),
+		r.Header.Get(timestampHeader),
+		r.Header.Get(signatureHeader),
+		body,
+		time.Now(),
+	)
+
+	if err != nil {
+		log.WithField("bot", b.definition.Slug).Infof("Rejecting the request: %s", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+// verifySignature checks that the signature is the HMAC-SHA256 of the version,
+// timestamp and body, signed using the signing secret.
+// Returns ErrRequestTooOld if the timestamp is more than maxRequestAge away from now, and
+// ErrMissingSigningSecret if there is no signing secret, as anyone could sign the requests.
+func verifySignature(signingSecret, timestamp, signature string, body []byte, now time.Time) error {
+	if signingSecret == "" {
+		return ErrMissingSigningSecret
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if math.Abs(now.Sub(time.Unix(seconds, 0)).Seconds()) > maxRequestAge.Seconds() {
+		return ErrRequestTooOld
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(signatureVersion + ":" + timestamp + ":"))
+	mac.Write(body)
+
+	expected := signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// bindDefaultWebhooks initialises the default Slack-related webhooks.
+func (b *slackBot) bindDefaultWebhooks() {
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/",
+		b.handleEventReceived,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/interactions",
+		b.handleInteractionReceived,
+	))
+}
@@ -0,0 +1,142 @@
+// Package sms defines SMS-related bot methods and behaviour.
+package sms
+
+import (
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const (
+	// AccountSid is the id of the provider's account
+	AccountSid bot.ParamName = "account_sid"
+	// AuthToken is the provider's auth token, also used to sign the webhooks
+	AuthToken bot.ParamName = "auth_token"
+	// PhoneNumber is the bot's phone number, in the E.164 format
+	PhoneNumber bot.ParamName = "phone_number"
+	// BaseUrl is the base url of the REST API. It is optional and can be
+	// used to target a local stand-in.
+	BaseUrl bot.ParamName = "base_url"
+)
+
+// Config is the config required in order to instantiate a new SmsBot
+type Config struct {
+	Definition *bot.Definition
+	SmsApi     api.SmsApi
+	Engine     *conversation.Engine
+	// WebhookUrl is the public url of the bot's webhooks, used to validate the
+	// requests' signatures. When empty, it is guessed from the requests.
+	WebhookUrl string
+}
+
+// smsBot is the main structure
+type smsBot struct {
+	webhooks            []*bot.Webhook
+	apiEndpoints        []*bot.ApiEndpoint
+	definition          *bot.Definition
+	smsApi              api.SmsApi
+	webhookUrl          string
+	conversationHandler *conversationHandler
+}
+
+// NewBot is the constructor method that creates a SMS bot, using
+// the Config struct as method parameters.
+//
+// Upon creation:
+// - The webhooks are attached: the phone number's incoming messages webhook
+// needs to be set to the bot's webhook in the provider's console.
+func NewBot(config *Config) *smsBot {
+	bot := &smsBot{
+		definition: config.Definition,
+		smsApi:     config.SmsApi,
+		webhookUrl: config.WebhookUrl,
+	}
+
+	bot.conversationHandler = newConversationHandler(config.Engine, config.SmsApi)
+
+	bot.bindDefaultWebhooks()
+	bot.bindDefaultApiEndpoints()
+
+	return bot
+}
+
+// buildBot is the builder registered for the SMS platform.
+// The SMS API is built from the bot's parameters.
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	// The webhook url is optional
+	webhookUrl, _ := utils.GetParam(conf, "webhook_url").(string)
+
+	smsApi, err := api.NewSmsApi("twilio", map[string]interface{}{
+		"account_sid": definition.StringParam(AccountSid),
+		"auth_token":  definition.StringParam(AuthToken),
+		"from":        definition.StringParam(PhoneNumber),
+		"base_url":    definition.StringParam(BaseUrl),
+		"client":      client,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBot(
+		&Config{
+			Definition: definition,
+			SmsApi:     smsApi,
+			Engine:     engine,
+			WebhookUrl: webhookUrl,
+		},
+	), nil
+}
+
+// bindDefaultApiEndpoints initialises the default API endpoints.
+func (b *smsBot) bindDefaultApiEndpoints() {
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"GET",
+		"/",
+		bot.HandleViewBot(b),
+	))
+}
+
+// Webhooks returns the bot's webhooks.
+// This method is required in order to implement the Bot interface.
+func (b *smsBot) Webhooks() []*bot.Webhook {
+	return b.webhooks
+}
+
+// ApiEndpoints returns the bot's available API endpoints.
+// This method is required in order to implement the Bot interface.
+func (b *smsBot) ApiEndpoints() []*bot.ApiEndpoint {
+	return b.apiEndpoints
+}
+
+// Definition returns the bot's definition.
+// This method is required in order to implement the Bot interface.
+func (b *smsBot) Definition() *bot.Definition {
+	return b.definition
+}
+
+func init() {
+	bot.RegisterSecretParams(bot.PlatformSms, AuthToken)
+	bot.RegisterBuilder(bot.PlatformSms, buildBot)
+}
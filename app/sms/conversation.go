@@ -0,0 +1,158 @@
+package sms
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// platform is the platform name given to the conversation engine
+var platform = string(bot.PlatformSms)
+
+// conversationHandler is the struct responsible for handling SMS conversations.
+// It translates text messages for the conversation engine, and sends its answers back.
+//
+// Text messages cannot contain quick replies: they are sent as numbered options instead,
+// and the options offered to each user are remembered so that a reply such as "2"
+// can be translated back to the matching quick reply. The messages of each user are handled
+// in order, so that a reply is always matched against the options it answers.
+type conversationHandler struct {
+	engine  *conversation.Engine
+	smsApi  api.SmsApi
+	mutex   sync.Mutex
+	options map[string][]*conversation.QuickReply
+	// queue handles the messages of each user in order
+	queue *utils.SerialQueue
+}
+
+// newConversationHandler is the constructor method for conversationHandler
+func newConversationHandler(e *conversation.Engine, a api.SmsApi) *conversationHandler {
+	return &conversationHandler{
+		engine:  e,
+		smsApi:  a,
+		options: make(map[string][]*conversation.QuickReply),
+		queue:   utils.NewSerialQueue(),
+	}
+}
+
+// Push handles the message in the background, after the user's previous messages
+func (h *conversationHandler) Push(message *api.SmsMessage) {
+	h.queue.Push(message.From, func() {
+		h.MessageReceived(message)
+	})
+}
+
+// MessageReceived handles the text messages received from the users.
+// Users are identified by their phone number.
+func (h *conversationHandler) MessageReceived(message *api.SmsMessage) {
+	in := &conversation.InboundMessage{
+		Platform: platform,
+		SenderId: message.From,
+		Text:     message.Text,
+		SentAt:   message.SentAt,
+	}
+
+	if quickReply := h.pickOption(message.From, message.Text); quickReply != nil {
+		in.Text = quickReply.Title
+		in.QuickReplyPayload = quickReply.Payload
+	}
+
+	messages, err := h.engine.Handle(in)
+
+	if err != nil {
+		// @todo: handle this case and return something to the user
+		log.WithField("user", message.From).Errorf("Could not handle the message: %s", err)
+		return
+	}
+
+	h.answer(message.From, messages)
+}
+
+// answer sends the messages to the user, one at a time.
+// Quick replies are appended to the text as numbered options.
+func (h *conversationHandler) answer(phoneNumber string, messages []*conversation.OutboundMessage) {
+	for _, message := range messages {
+		if len(message.QuickReplies) > 0 {
+			h.setOptions(phoneNumber, message.QuickReplies)
+		}
+
+		err := h.smsApi.SendMessage(phoneNumber, formatText(message))
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"user":    phoneNumber,
+				"message": message.Text,
+			}).Errorf("Could not send the message: %s", err)
+			return
+		}
+	}
+}
+
+// setOptions remembers the options offered to the user
+func (h *conversationHandler) setOptions(phoneNumber string, quickReplies []*conversation.QuickReply) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.options[phoneNumber] = quickReplies
+}
+
+// pickOption returns the option chosen by the user, either by its number or by its title.
+// The options are forgotten once the user replied. Returns nil if the text does not match any option.
+func (h *conversationHandler) pickOption(phoneNumber, text string) *conversation.QuickReply {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	options, ok := h.options[phoneNumber]
+
+	if !ok {
+		return nil
+	}
+
+	delete(h.options, phoneNumber)
+
+	return matchOption(options, text)
+}
+
+// matchOption returns the option matching the text: "2", "2." or "2)" for the
+// second option, or the option's title, case-insensitively.
+func matchOption(options []*conversation.QuickReply, text string) *conversation.QuickReply {
+	text = strings.TrimSpace(text)
+	number := strings.TrimRight(text, ".)")
+
+	if i, err := strconv.Atoi(number); err == nil {
+		if i >= 1 && i <= len(options) {
+			return options[i-1]
+		}
+
+		return nil
+	}
+
+	for _, option := range options {
+		if strings.EqualFold(option.Title, text) {
+			return option
+		}
+	}
+
+	return nil
+}
+
+// formatText returns the message's text, followed by its quick replies as numbered options
+func formatText(message *conversation.OutboundMessage) string {
+	if len(message.QuickReplies) == 0 {
+		return message.Text
+	}
+
+	lines := []string{message.Text}
+
+	for i, quickReply := range message.QuickReplies {
+		lines = append(lines, strconv.Itoa(i+1)+". "+quickReply.Title)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package sms
+
+import (
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	log "github.com/sirupsen/logrus"
+)
+
+// emptyResponse is the TwiML response telling the provider not to answer by itself:
+// the answers are sent using the REST API.
+const emptyResponse = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+
+// handleMessageReceived is called when the provider sends a new text message to the webhook.
+// The messages are handled in the background, in order for each user, so that the webhook replies straight away.
+func (b *smsBot) handleMessageReceived(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New text message received")
+
+	message, err := b.smsApi.ParseMessage(r, b.requestUrl(r))
+
+	if err != nil {
+		if err == api.ErrInvalidSignature {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b.conversationHandler.Push(message)
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write([]byte(emptyResponse))
+}
+
+// requestUrl returns the public url the provider called. It is built from the configured
+// webhook url when set, or guessed from the request otherwise.
+func (b *smsBot) requestUrl(r *http.Request) string {
+	if b.webhookUrl != "" {
+		// The webhook is mounted on the root of the webhooks
+		if r.URL.RawQuery == "" {
+			return b.webhookUrl
+		}
+
+		return b.webhookUrl + "?" + r.URL.RawQuery
+	}
+
+	scheme := "http"
+
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// bindDefaultWebhooks initialises the default SMS-related webhooks.
+func (b *smsBot) bindDefaultWebhooks() {
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/",
+		b.handleMessageReceived,
+	))
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/nlp"
+	log "github.com/sirupsen/logrus"
+)
+
+// @todo: we need to check if all of the stories's steps are being handled
+// and if any are missing / deprecated.
+// defaultStepsProcessMap returns the default steps mapping between
+// a step's name and its handling func. It is shared by all of the platforms.
+func defaultStepsProcessMap() conversation.StepsProcessMap {
+	pm := conversation.StepsProcessMap{}
+
+	pm["book_table_entrypoint"] = processStepBookTable
+	pm["book_table_get_nb_persons"] = processStepBookTableGetNbPersons
+	pm["book_table_get_time"] = processStepBookTableGetTime
+
+	return pm
+}
+
+// defaultValidators returns the validators of the entities used by the default steps.
+// They are used unless the bot sets its own ones.
+func defaultValidators() nlp.Validators {
+	return nlp.Validators{
+		"nb_persons":   {nlp.Between(1, 20)},
+		"booking_date": {nlp.InFuture()},
+	}
+}
+
+// defaultDataTypes returns the types of the entities used by the default steps.
+// The bots' own data type maps override them.
+func defaultDataTypes() nlp.DataTypeMap {
+	return nlp.DataTypeMap{
+		"nb_persons":   nlp.IntEntity,
+		"booking_date": nlp.DateTimeEntity,
+	}
+}
+
+// processStepBookTable processes the "book_table_entrypoint" step
+func processStepBookTable(step *conversation.Step, data *nlp.ParsedData) ([]*conversation.OutboundMessage, error) {
+	log.Info("BOOK TABLE")
+
+	return []*conversation.OutboundMessage{
+		conversation.NewTextMessage("Sure, let's book a table!"),
+		conversation.NewTextMessage("How many persons will be there?"),
+	}, nil
+}
+
+// processStepBookTableGetNbPersons processes the "book_table_get_nb_persons" step
+func processStepBookTableGetNbPersons(step *conversation.Step, data *nlp.ParsedData) ([]*conversation.OutboundMessage, error) {
+	log.Info("BOOK TABLE - GET NB PERSONS")
+
+	if entity := data.Entity("nb_persons"); entity != nil {
+		if nbPersons, ok := entity.Int(); ok {
+			return []*conversation.OutboundMessage{
+				conversation.NewTextMessage(fmt.Sprintf("Got it, a table for %d, thanks!", nbPersons)),
+			}, nil
+		}
+	}
+
+	return []*conversation.OutboundMessage{
+		conversation.NewTextMessage("Got it, thanks!"),
+	}, nil
+}
+
+// processStepBookTableGetTime processes the "book_table_get_time" step
+func processStepBookTableGetTime(step *conversation.Step, data *nlp.ParsedData) ([]*conversation.OutboundMessage, error) {
+	log.Info("BOOK TABLE - GET TIME")
+
+	return []*conversation.OutboundMessage{
+		conversation.NewTextMessage("Perfect, your table is booked!"),
+	}, nil
+}
@@ -0,0 +1,178 @@
+// Package telegram defines Telegram-related bot methods and behaviour.
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Token is the bot's token, given by the BotFather
+	Token bot.ParamName = "token"
+	// SecretToken is the secret sent by Telegram along with every update,
+	// used to make sure the updates come from Telegram. When missing, a secret
+	// is generated on startup and registered along with the webhook.
+	SecretToken bot.ParamName = "secret_token"
+	// BaseUrl is the base url of the Bot API. It is optional and can be
+	// used to target a local Bot API server.
+	BaseUrl bot.ParamName = "base_url"
+)
+
+// Config is the config required in order to instantiate a new TelegramBot
+type Config struct {
+	Definition  *bot.Definition
+	TelegramApi api.TelegramApi
+	Engine      *conversation.Engine
+	// WebhookUrl is the public url of the bot's webhook. The webhook is
+	// registered on startup when it is set.
+	WebhookUrl string
+}
+
+// telegramBot is the main structure
+type telegramBot struct {
+	webhooks            []*bot.Webhook
+	apiEndpoints        []*bot.ApiEndpoint
+	definition          *bot.Definition
+	telegramApi         api.TelegramApi
+	conversationHandler *conversationHandler
+	// secretToken is the secret Telegram sends along with every update
+	secretToken string
+}
+
+// NewBot is the constructor method that creates a Telegram bot, using
+// the Config struct as method parameters.
+//
+// Upon creation:
+// - The webhooks are attached.
+// - The webhook is registered on Telegram, along with the secret token. A secret token
+// is generated when the definition does not give any.
+func NewBot(config *Config) *telegramBot {
+	bot := &telegramBot{
+		definition:  config.Definition,
+		telegramApi: config.TelegramApi,
+		secretToken: config.Definition.StringParam(SecretToken),
+	}
+
+	bot.conversationHandler = newConversationHandler(config.Engine, config.TelegramApi)
+
+	bot.bindDefaultWebhooks()
+	bot.bindDefaultApiEndpoints()
+
+	if bot.secretToken == "" && config.WebhookUrl != "" {
+		secretToken, err := newSecretToken()
+
+		if err != nil {
+			log.WithField("bot", bot.definition.Slug).Errorf("Could not generate the secret token: %s", err)
+		}
+
+		bot.secretToken = secretToken
+	}
+
+	if config.WebhookUrl != "" && bot.secretToken != "" {
+		err := bot.telegramApi.SetWebhook(config.WebhookUrl, bot.secretToken)
+
+		if err != nil {
+			log.WithField("bot", bot.definition.Slug).Errorf("Could not register the webhook: %s", err)
+		}
+	}
+
+	return bot
+}
+
+// buildBot is the builder registered for the Telegram platform.
+// The Telegram API is built from the bot's parameters.
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	// The webhook url is optional, unless there is no secret token to register along with it
+	webhookUrl, _ := utils.GetParam(conf, "webhook_url").(string)
+
+	if webhookUrl == "" && definition.StringParam(SecretToken) == "" {
+		return nil, utils.ErrInvalidOrMissingParam(string(SecretToken))
+	}
+
+	telegramApi, err := api.NewTelegramApi("telegram", map[string]interface{}{
+		"token":    definition.StringParam(Token),
+		"base_url": definition.StringParam(BaseUrl),
+		"client":   client,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBot(
+		&Config{
+			Definition:  definition,
+			TelegramApi: telegramApi,
+			Engine:      engine,
+			WebhookUrl:  webhookUrl,
+		},
+	), nil
+}
+
+// newSecretToken generates a random secret token, made of the characters allowed by Telegram
+func newSecretToken() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// bindDefaultApiEndpoints initialises the default API endpoints.
+func (b *telegramBot) bindDefaultApiEndpoints() {
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"GET",
+		"/",
+		bot.HandleViewBot(b),
+	))
+}
+
+// Webhooks returns the bot's webhooks.
+// This method is required in order to implement the Bot interface.
+func (b *telegramBot) Webhooks() []*bot.Webhook {
+	return b.webhooks
+}
+
+// ApiEndpoints returns the bot's available API endpoints.
+// This method is required in order to implement the Bot interface.
+func (b *telegramBot) ApiEndpoints() []*bot.ApiEndpoint {
+	return b.apiEndpoints
+}
+
+// Definition returns the bot's definition.
+// This method is required in order to implement the Bot interface.
+func (b *telegramBot) Definition() *bot.Definition {
+	return b.definition
+}
+
+func init() {
+	bot.RegisterSecretParams(bot.PlatformTelegram, Token, SecretToken)
+	bot.RegisterBuilder(bot.PlatformTelegram, buildBot)
+}
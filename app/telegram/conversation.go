@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// platform is the platform name given to the conversation engine
+var platform = string(bot.PlatformTelegram)
+
+// conversationHandler is the struct responsible for handling Telegram conversations.
+// It translates Telegram updates for the conversation engine, and sends its answers back.
+type conversationHandler struct {
+	engine      *conversation.Engine
+	telegramApi api.TelegramApi
+	// queue handles the updates of each user in order
+	queue *utils.SerialQueue
+}
+
+// newConversationHandler is the constructor method for conversationHandler
+func newConversationHandler(e *conversation.Engine, a api.TelegramApi) *conversationHandler {
+	return &conversationHandler{
+		engine:      e,
+		telegramApi: a,
+		queue:       utils.NewSerialQueue(),
+	}
+}
+
+// UpdateReceived handles the updates received from Telegram: messages, locations
+// and callback queries sent when pressing the buttons of an inline keyboard.
+// The request is parsed right away, and the update is then handled in the background,
+// in order for each user.
+func (h *conversationHandler) UpdateReceived(r *http.Request) {
+	update, err := h.telegramApi.ParseUpdate(r)
+
+	if err != nil {
+		log.Infof("Could not parse the received update: %s", err)
+		return
+	}
+
+	h.queue.Push(senderId(update), func() {
+		h.updateReceived(update)
+	})
+}
+
+// updateReceived handles a single update. Users are identified by their user id, so that the
+// members of a group chat are told apart, and the answers are sent to the chat of the update.
+func (h *conversationHandler) updateReceived(update *api.TelegramUpdate) {
+	if update.CallbackQueryId != "" {
+		err := h.telegramApi.AnswerCallbackQuery(update.CallbackQueryId)
+
+		if err != nil {
+			log.WithField("callbackQuery", update.CallbackQueryId).Infof("Could not answer the callback query: %s", err)
+		}
+	}
+
+	in := &conversation.InboundMessage{
+		Platform:          platform,
+		SenderId:          senderId(update),
+		Text:              update.Text,
+		QuickReplyPayload: update.CallbackData,
+		SentAt:            update.SentAt,
+	}
+
+	if update.Location != nil {
+		in.Location = &conversation.Location{
+			Latitude:  update.Location.Latitude,
+			Longitude: update.Location.Longitude,
+		}
+	}
+
+	messages, err := h.engine.Handle(in)
+
+	if err != nil {
+		// @todo: handle this case and return something to the user
+		log.WithFields(log.Fields{
+			"chat": update.ChatId,
+			"user": update.SenderId,
+		}).Errorf("Could not handle the update: %s", err)
+		return
+	}
+
+	h.answer(update.ChatId, messages)
+}
+
+// senderId returns the id identifying the user who sent the update. The chat id is used
+// when the update has no sender, such as the posts of a channel.
+func senderId(update *api.TelegramUpdate) string {
+	if update.SenderId == 0 {
+		return strconv.FormatInt(update.ChatId, 10)
+	}
+
+	return strconv.FormatInt(update.SenderId, 10)
+}
+
+// answer sends the messages to the chat, one at a time.
+// Quick replies are sent as an inline keyboard, with one button per row.
+func (h *conversationHandler) answer(chatId int64, messages []*conversation.OutboundMessage) {
+	for _, message := range messages {
+		var keyboard [][]*api.TelegramInlineButton
+
+		for _, quickReply := range message.QuickReplies {
+			keyboard = append(keyboard, []*api.TelegramInlineButton{
+				{
+					Text:         quickReply.Title,
+					CallbackData: quickReply.Payload,
+				},
+			})
+		}
+
+		err := h.telegramApi.SendMessage(chatId, message.Text, keyboard)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"chat":    chatId,
+				"message": message.Text,
+			}).Errorf("Could not send the message: %s", err)
+			return
+		}
+	}
+}
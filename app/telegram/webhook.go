@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/bot"
+	log "github.com/sirupsen/logrus"
+)
+
+// secretTokenHeader is the header containing the secret token sent by Telegram
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// handleUpdateReceived is called when Telegram sends a new update to the webhook.
+// The secret token is checked before delegating the handling to the Conversation Handler:
+// the updates are rejected when the bot has none.
+func (b *telegramBot) handleUpdateReceived(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New Telegram update received")
+
+	token := r.Header.Get(secretTokenHeader)
+
+	if b.secretToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(b.secretToken)) != 1 {
+		log.WithField("bot", b.definition.Slug).Info("Invalid secret token")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	b.conversationHandler.UpdateReceived(r)
+}
+
+// bindDefaultWebhooks initialises the default Telegram-related webhooks.
+func (b *telegramBot) bindDefaultWebhooks() {
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/",
+		b.handleUpdateReceived,
+	))
+}
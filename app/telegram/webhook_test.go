@@ -0,0 +1,145 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	_ "github.com/aziule/conversation-management/infrastructure/telegram"
+)
+
+// newTestBot creates a bot whose Telegram API calls a fake Bot API server. The secret token
+// registered along with the webhook is sent to the returned channel.
+func newTestBot(t *testing.T, parameters map[bot.ParamName]interface{}, webhookUrl string) (*telegramBot, chan string, func()) {
+	secretTokens := make(chan string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			SecretToken string `json:"secret_token"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		if strings.HasSuffix(r.URL.Path, "/setWebhook") {
+			secretTokens <- payload.SecretToken
+		}
+
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+
+	parameters[BaseUrl] = server.URL
+
+	definition := &bot.Definition{
+		Slug:       "booking",
+		Platform:   bot.PlatformTelegram,
+		Parameters: parameters,
+	}
+
+	telegramApi, err := api.NewTelegramApi("telegram", map[string]interface{}{
+		"token":    "123456:test-token",
+		"base_url": server.URL,
+		"client":   server.Client(),
+	})
+
+	if err != nil {
+		server.Close()
+		t.Fatalf("Could not create the Telegram API: %s", err)
+	}
+
+	b := NewBot(&Config{
+		Definition:  definition,
+		TelegramApi: telegramApi,
+		WebhookUrl:  webhookUrl,
+	})
+
+	return b, secretTokens, server.Close
+}
+
+// TestSecretToken checks that the secret token is generated and registered along with the webhook
+// when the definition has none, and that only the updates sending it are accepted
+func TestSecretToken(t *testing.T) {
+	b, secretTokens, closeServer := newTestBot(t, map[bot.ParamName]interface{}{}, "https://example.com/api/bots/booking/webhooks/")
+	defer closeServer()
+
+	var registered string
+
+	select {
+	case registered = <-secretTokens:
+	default:
+		t.Fatal("The webhook was not registered")
+	}
+
+	if registered == "" || registered != b.secretToken {
+		t.Fatalf("Unexpected secret token: %q, the bot uses %q", registered, b.secretToken)
+	}
+
+	cases := []struct {
+		name     string
+		token    string
+		expected int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"invalid", "not-the-secret", http.StatusUnauthorized},
+		// Edited messages are not handled: the update is accepted, but ignored
+		{"valid", registered, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"update_id":1,"edited_message":{"message_id":1,"date":1,"chat":{"id":1},"text":"hi"}}`))
+
+			if c.token != "" {
+				r.Header.Set(secretTokenHeader, c.token)
+			}
+
+			w := httptest.NewRecorder()
+			b.handleUpdateReceived(w, r)
+
+			if w.Code != c.expected {
+				t.Errorf("Expected status %d, got %d", c.expected, w.Code)
+			}
+		})
+	}
+}
+
+// TestSecretTokenWithoutWebhook checks that the updates are rejected when the bot has no secret token
+func TestSecretTokenWithoutWebhook(t *testing.T) {
+	b, secretTokens, closeServer := newTestBot(t, map[bot.ParamName]interface{}{}, "")
+	defer closeServer()
+
+	if len(secretTokens) > 0 {
+		t.Fatal("The webhook should not be registered without any url")
+	}
+
+	w := httptest.NewRecorder()
+	b.handleUpdateReceived(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"update_id":1}`)))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+// TestSenderId checks that the users are identified by their user id, even within group chats
+func TestSenderId(t *testing.T) {
+	cases := []struct {
+		name     string
+		update   *api.TelegramUpdate
+		expected string
+	}{
+		{"private chat", &api.TelegramUpdate{ChatId: 1111, SenderId: 1111}, "1111"},
+		{"group chat", &api.TelegramUpdate{ChatId: -2222, SenderId: 3333}, "3333"},
+		{"no sender", &api.TelegramUpdate{ChatId: -4444}, "-4444"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if id := senderId(c.update); id != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, id)
+			}
+		})
+	}
+}
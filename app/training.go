@@ -0,0 +1,316 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// createIntentRequest is the request body used to create an intent within the NLP service
+type createIntentRequest struct {
+	Name string `json:"name"`
+}
+
+// createEntityRequest is the request body used to create an entity within the NLP service,
+// along with its values
+type createEntityRequest struct {
+	Name   string             `json:"name"`
+	Roles  []string           `json:"roles"`
+	Values []*nlp.EntityValue `json:"values"`
+}
+
+// syncResponse is the response body listing the review items synced to the NLP service
+type syncResponse struct {
+	Synced []*nlp.ReviewItem `json:"synced"`
+}
+
+// handleCreateIntent creates an intent within the bot's NLP service
+func (appApi *appApi) handleCreateIntent(w http.ResponseWriter, r *http.Request) {
+	trainer, ok := appApi.trainer(w, r)
+
+	if !ok {
+		return
+	}
+
+	var body createIntentRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil || body.Name == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := trainer.CreateIntent(body.Name); err != nil {
+		log.WithField("intent", body.Name).Errorf("Could not create the intent: %s", err)
+		http.Error(w, "Could not create the intent", http.StatusBadGateway)
+		return
+	}
+
+	appApi.app.invalidateCaches(chi.URLParam(r, "slug"))
+
+	j, _ := json.Marshal(nlp.NewIntent(body.Name))
+
+	w.Write(j)
+}
+
+// handleCreateEntity creates an entity within the bot's NLP service, along with its values
+func (appApi *appApi) handleCreateEntity(w http.ResponseWriter, r *http.Request) {
+	trainer, ok := appApi.trainer(w, r)
+
+	if !ok {
+		return
+	}
+
+	var body createEntityRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil || body.Name == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := trainer.CreateEntity(body.Name, body.Roles); err != nil {
+		log.WithField("entity", body.Name).Errorf("Could not create the entity: %s", err)
+		http.Error(w, "Could not create the entity", http.StatusBadGateway)
+		return
+	}
+
+	// The entity was created, even if its values could not all be added
+	defer appApi.app.invalidateCaches(chi.URLParam(r, "slug"))
+
+	for _, value := range body.Values {
+		if err := trainer.AddEntityValue(body.Name, value); err != nil {
+			log.WithField("entity", body.Name).Errorf("Could not add the value: %s", err)
+			http.Error(w, "Could not add the value "+value.Value, http.StatusBadGateway)
+			return
+		}
+	}
+
+	j, _ := json.Marshal(body)
+
+	w.Write(j)
+}
+
+// handleAddEntityValue adds a value, along with its synonyms, to an entity of the bot's NLP service
+func (appApi *appApi) handleAddEntityValue(w http.ResponseWriter, r *http.Request) {
+	trainer, ok := appApi.trainer(w, r)
+
+	if !ok {
+		return
+	}
+
+	entity := chi.URLParam(r, "entity")
+
+	var body nlp.EntityValue
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil || body.Value == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := trainer.AddEntityValue(entity, &body); err != nil {
+		log.WithField("entity", entity).Errorf("Could not add the value: %s", err)
+		http.Error(w, "Could not add the value", http.StatusBadGateway)
+		return
+	}
+
+	appApi.app.invalidateCaches(chi.URLParam(r, "slug"))
+
+	j, _ := json.Marshal(body)
+
+	w.Write(j)
+}
+
+// handleAddUtterances uploads labeled samples to the bot's NLP service, in bulk.
+// None of them is uploaded if any of them is not valid.
+func (appApi *appApi) handleAddUtterances(w http.ResponseWriter, r *http.Request) {
+	trainer, ok := appApi.trainer(w, r)
+
+	if !ok {
+		return
+	}
+
+	var body []*nlp.Utterance
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil || len(body) == 0 {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	for i, utterance := range body {
+		if utterance == nil || utterance.Validate() != nil {
+			http.Error(w, fmt.Sprintf("Invalid utterance at index %d", i), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := trainer.AddUtterances(body); err != nil {
+		log.WithField("utterances", len(body)).Errorf("Could not add the utterances: %s", err)
+		http.Error(w, "Could not add the utterances", http.StatusBadGateway)
+		return
+	}
+
+	appApi.app.invalidateCaches(chi.URLParam(r, "slug"))
+
+	j, _ := json.Marshal(body)
+
+	w.Write(j)
+}
+
+// handleListReviewItems lists the bot's review items, filtered by the optional "status" query param
+func (appApi *appApi) handleListReviewItems(w http.ResponseWriter, r *http.Request) {
+	queue, ok := appApi.reviewQueue(w, r)
+
+	if !ok {
+		return
+	}
+
+	items, err := queue.Items(nlp.ReviewStatus(r.URL.Query().Get("status")))
+
+	if err != nil {
+		log.Errorf("Could not get the review items: %s", err)
+		http.Error(w, "Could not get the review items", http.StatusInternalServerError)
+		return
+	}
+
+	j, _ := json.Marshal(items)
+
+	w.Write(j)
+}
+
+// handleLabelReviewItem labels a review item with its intent and entities,
+// such as {"intent": "book_table", "entities": [{"entity": "nb_persons", "body": "four"}]}
+func (appApi *appApi) handleLabelReviewItem(w http.ResponseWriter, r *http.Request) {
+	queue, ok := appApi.reviewQueue(w, r)
+
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	var body nlp.Utterance
+	err := json.NewDecoder(r.Body).Decode(&body)
+
+	if err != nil || !bson.IsObjectIdHex(id) {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	item, err := queue.Label(bson.ObjectIdHex(id), &body)
+
+	writeReviewItemResponse(w, item, err)
+}
+
+// handleDismissReviewItem dismisses a review item, so that it is not labeled
+func (appApi *appApi) handleDismissReviewItem(w http.ResponseWriter, r *http.Request) {
+	queue, ok := appApi.reviewQueue(w, r)
+
+	if !ok {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	if !bson.IsObjectIdHex(id) {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	item, err := queue.Dismiss(bson.ObjectIdHex(id))
+
+	writeReviewItemResponse(w, item, err)
+}
+
+// handleSyncReviewItems sends the labeled review items to the bot's NLP service
+func (appApi *appApi) handleSyncReviewItems(w http.ResponseWriter, r *http.Request) {
+	queue, ok := appApi.reviewQueue(w, r)
+
+	if !ok {
+		return
+	}
+
+	trainer, ok := appApi.trainer(w, r)
+
+	if !ok {
+		return
+	}
+
+	items, err := queue.Sync(trainer)
+
+	if err != nil {
+		log.Errorf("Could not sync the review items: %s", err)
+		http.Error(w, "Could not sync the review items", http.StatusBadGateway)
+		return
+	}
+
+	if len(items) > 0 {
+		appApi.app.invalidateCaches(chi.URLParam(r, "slug"))
+	}
+
+	j, _ := json.Marshal(&syncResponse{Synced: items})
+
+	w.Write(j)
+}
+
+// trainer returns the trainer of the bot given by the "slug" URL param, or writes the error
+// when the bot is not found or its NLP service cannot be trained
+func (appApi *appApi) trainer(w http.ResponseWriter, r *http.Request) (nlp.Trainer, bool) {
+	slug := chi.URLParam(r, "slug")
+
+	if _, ok := appApi.app.dataTypes[slug]; !ok {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	trainer, ok := appApi.app.trainers[slug]
+
+	if !ok {
+		http.Error(w, "The bot's NLP service cannot be trained", http.StatusNotImplemented)
+		return nil, false
+	}
+
+	return trainer, true
+}
+
+// reviewQueue returns the review queue of the bot given by the "slug" URL param,
+// or writes the error when the bot is not found
+func (appApi *appApi) reviewQueue(w http.ResponseWriter, r *http.Request) (*nlp.ReviewQueue, bool) {
+	queue, ok := appApi.app.reviewQueues[chi.URLParam(r, "slug")]
+
+	if !ok {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+	}
+
+	return queue, ok
+}
+
+// writeReviewItemResponse writes the review item resulting from a label or a dismissal, or the error
+func writeReviewItemResponse(w http.ResponseWriter, item *nlp.ReviewItem, err error) {
+	switch err {
+	case nil:
+		j, _ := json.Marshal(item)
+		w.Write(j)
+	case nlp.ErrReviewItemNotFound:
+		http.Error(w, "Review item not found", http.StatusNotFound)
+	case nlp.ErrInvalidUtterance:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case nlp.ErrAlreadySynced:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Errorf("Could not update the review item: %s", err)
+		http.Error(w, "Could not update the review item", http.StatusInternalServerError)
+	}
+}
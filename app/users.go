@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/conversation"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// identityRequest is the request body identifying a user by one of its identities
+type identityRequest struct {
+	Platform   string `json:"platform"`
+	ExternalId string `json:"external_id"`
+}
+
+// mergeUsersRequest is the request body used to merge a user into another one
+type mergeUsersRequest struct {
+	TargetId string `json:"target_id"`
+	SourceId string `json:"source_id"`
+}
+
+// unmergeUserRequest is the request body used to undo the merge of a user
+type unmergeUserRequest struct {
+	UserId string `json:"user_id"`
+}
+
+// handleCreateLinkCode creates a one-time code for the user owning the identity, so that
+// the user can link another account by sending "/link <code>" on another platform.
+func (appApi *appApi) handleCreateLinkCode(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body identityRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.Platform == "" || body.ExternalId == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	linkCode, err := appApi.app.identities.CreateLinkCode(body.Platform, body.ExternalId)
+
+	if err != nil {
+		log.Errorf("Could not create the link code: %s", err)
+		http.Error(w, "Could not create the link code", http.StatusInternalServerError)
+		return
+	}
+
+	j, _ := json.Marshal(linkCode)
+
+	w.Write(j)
+}
+
+// handleMergeUsers merges a user into another one: the target user gets the
+// source user's identities and conversations.
+func (appApi *appApi) handleMergeUsers(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body mergeUsersRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || !bson.IsObjectIdHex(body.TargetId) || !bson.IsObjectIdHex(body.SourceId) {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := appApi.app.identities.Merge(bson.ObjectIdHex(body.TargetId), bson.ObjectIdHex(body.SourceId))
+
+	writeUserResponse(w, user, err)
+}
+
+// handleUnmergeUser undoes the merge of a user
+func (appApi *appApi) handleUnmergeUser(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body unmergeUserRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || !bson.IsObjectIdHex(body.UserId) {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := appApi.app.identities.Unmerge(bson.ObjectIdHex(body.UserId))
+
+	writeUserResponse(w, user, err)
+}
+
+// writeUserResponse writes the user resulting from a merge or unmerge, or the error
+func writeUserResponse(w http.ResponseWriter, user *conversation.User, err error) {
+	switch err {
+	case nil:
+		j, _ := json.Marshal(user)
+		w.Write(j)
+	case conversation.ErrNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case conversation.ErrAlreadyMerged, conversation.ErrNotMerged, conversation.ErrSameUser:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Errorf("Could not update the users: %s", err)
+		http.Error(w, "Could not update the users", http.StatusInternalServerError)
+	}
+}
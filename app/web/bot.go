@@ -0,0 +1,118 @@
+// Package web defines the web chat bot: a first-party channel used to run the
+// stories on a website, through an embeddable widget.
+package web
+
+import (
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SessionSecret is the secret used to sign the session tokens.
+	// When it is missing, a random secret is used and the sessions
+	// do not survive restarts.
+	SessionSecret bot.ParamName = "session_secret"
+	// AllowedOrigin is the origins of the websites embedding the widget, separated by commas, such as
+	// "https://a.example.com, https://b.example.com". Only these origins can send the session cookie.
+	// "*" allows any other origin, as long as it sends the session token explicitly, as the widget does.
+	// Same-origin requests are always allowed.
+	AllowedOrigin bot.ParamName = "allowed_origin"
+)
+
+// Config is the config required in order to instantiate a new WebBot
+type Config struct {
+	Definition *bot.Definition
+	Engine     *conversation.Engine
+}
+
+// webBot is the main structure
+type webBot struct {
+	webhooks            []*bot.Webhook
+	apiEndpoints        []*bot.ApiEndpoint
+	definition          *bot.Definition
+	sessions            *sessionSigner
+	hub                 *hub
+	conversationHandler *conversationHandler
+}
+
+// NewBot is the constructor method that creates a web chat bot, using
+// the Config struct as method parameters.
+//
+// Upon creation:
+// - The webhooks are attached: sessions, WebSocket, long-polling and the widget.
+func NewBot(config *Config) *webBot {
+	secret := config.Definition.StringParam(SessionSecret)
+
+	if secret == "" {
+		log.WithField("bot", config.Definition.Slug).Warn("No session secret: the sessions will not survive restarts")
+	}
+
+	bot := &webBot{
+		definition: config.Definition,
+		sessions:   newSessionSigner(secret),
+		hub:        newHub(),
+	}
+
+	bot.conversationHandler = newConversationHandler(config.Engine, bot.hub)
+
+	bot.bindDefaultWebhooks()
+	bot.bindDefaultApiEndpoints()
+
+	return bot
+}
+
+// buildBot is the builder registered for the web chat platform
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	return NewBot(
+		&Config{
+			Definition: definition,
+			Engine:     engine,
+		},
+	), nil
+}
+
+// bindDefaultApiEndpoints initialises the default API endpoints.
+func (b *webBot) bindDefaultApiEndpoints() {
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"GET",
+		"/",
+		bot.HandleViewBot(b),
+	))
+}
+
+// Webhooks returns the bot's webhooks.
+// This method is required in order to implement the Bot interface.
+func (b *webBot) Webhooks() []*bot.Webhook {
+	return b.webhooks
+}
+
+// ApiEndpoints returns the bot's available API endpoints.
+// This method is required in order to implement the Bot interface.
+func (b *webBot) ApiEndpoints() []*bot.ApiEndpoint {
+	return b.apiEndpoints
+}
+
+// Definition returns the bot's definition.
+// This method is required in order to implement the Bot interface.
+func (b *webBot) Definition() *bot.Definition {
+	return b.definition
+}
+
+func init() {
+	bot.RegisterSecretParams(bot.PlatformWeb, SessionSecret)
+	bot.RegisterBuilder(bot.PlatformWeb, buildBot)
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"time"
+
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// platform is the platform name given to the conversation engine
+var platform = string(bot.PlatformWeb)
+
+// clientMessage is a message sent by the widget. The payload is set when
+// the user picked a quick reply. The timezone is the browser's one, when known.
+type clientMessage struct {
+	Text     string `json:"text"`
+	Payload  string `json:"payload"`
+	Timezone string `json:"timezone"`
+}
+
+// conversationHandler is the struct responsible for handling web chat conversations.
+// It hands the messages over to the conversation engine, and pushes its answers to the hub.
+type conversationHandler struct {
+	engine *conversation.Engine
+	hub    *hub
+	// queue handles the messages of each session in order
+	queue *utils.SerialQueue
+}
+
+// newConversationHandler is the constructor method for conversationHandler
+func newConversationHandler(e *conversation.Engine, h *hub) *conversationHandler {
+	return &conversationHandler{
+		engine: e,
+		hub:    h,
+		queue:  utils.NewSerialQueue(),
+	}
+}
+
+// Push handles the message in the background, after the session's previous messages
+func (h *conversationHandler) Push(sessionId string, message *clientMessage) {
+	h.queue.Push(sessionId, func() {
+		h.MessageReceived(sessionId, message)
+	})
+}
+
+// MessageReceived handles a message sent by the user of a session.
+// The typing indicator is shown while the engine processes the message.
+func (h *conversationHandler) MessageReceived(sessionId string, message *clientMessage) {
+	h.hub.Push(sessionId, &event{Type: eventTypingOn})
+	defer h.hub.Push(sessionId, &event{Type: eventTypingOff})
+
+	messages, err := h.engine.Handle(&conversation.InboundMessage{
+		Platform:          platform,
+		SenderId:          sessionId,
+		Text:              message.Text,
+		QuickReplyPayload: message.Payload,
+		SentAt:            time.Now(),
+		Timezone:          message.Timezone,
+	})
+
+	if err != nil {
+		// @todo: handle this case and return something to the user
+		log.WithField("session", sessionId).Errorf("Could not handle the message: %s", err)
+		return
+	}
+
+	for _, outbound := range messages {
+		e := &event{
+			Type: eventMessage,
+			Text: outbound.Text,
+		}
+
+		for _, qr := range outbound.QuickReplies {
+			e.QuickReplies = append(e.QuickReplies, &quickReply{
+				Title:   qr.Title,
+				Payload: qr.Payload,
+			})
+		}
+
+		h.hub.Push(sessionId, e)
+	}
+}
+
+// createLinkCode creates a one-time code used to link the session's user to another account
+func (h *conversationHandler) createLinkCode(sessionId string) (*conversation.LinkCode, error) {
+	return h.engine.Identities().CreateLinkCode(platform, sessionId)
+}
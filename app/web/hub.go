@@ -0,0 +1,138 @@
+package web
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// eventsBufferSize is the number of events kept for a session while no client is connected
+	eventsBufferSize = 64
+	// sessionTtl is the time after which an idle session is forgotten
+	sessionTtl = time.Hour
+)
+
+// Event types pushed to the clients
+const (
+	eventMessage   = "message"
+	eventTypingOn  = "typing_on"
+	eventTypingOff = "typing_off"
+)
+
+// event is an event pushed to the client: a bot reply or a typing event
+type event struct {
+	Type         string        `json:"type"`
+	Text         string        `json:"text,omitempty"`
+	QuickReplies []*quickReply `json:"quick_replies,omitempty"`
+}
+
+// quickReply is a suggested answer, displayed as a button by the widget
+type quickReply struct {
+	Title   string `json:"title"`
+	Payload string `json:"payload"`
+}
+
+// session holds the events waiting to be delivered to a session's client
+type session struct {
+	id        string
+	events    chan *event
+	consumers int
+	lastSeen  time.Time
+}
+
+// hub dispatches the events to the sessions. Events are buffered until a client,
+// connected through a WebSocket or long-polling, consumes them.
+type hub struct {
+	mutex    sync.Mutex
+	sessions map[string]*session
+}
+
+// newHub is the constructor method for hub. Idle sessions are periodically forgotten.
+func newHub() *hub {
+	h := &hub{
+		sessions: make(map[string]*session),
+	}
+
+	go h.expireSessions()
+
+	return h
+}
+
+// Push adds an event to the session's buffer. The event is dropped if the buffer is full,
+// which happens when the client has not been connected for a while.
+func (h *hub) Push(sessionId string, e *event) {
+	s := h.get(sessionId)
+
+	select {
+	case s.events <- e:
+	default:
+		log.WithFields(log.Fields{
+			"session": sessionId,
+			"event":   e.Type,
+		}).Info("The session's buffer is full: dropping the event")
+	}
+}
+
+// Attach returns the session's events, for a client to consume them.
+// Detach must be called once the client is done.
+func (h *hub) Attach(sessionId string) <-chan *event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s := h.getLocked(sessionId)
+	s.consumers++
+
+	return s.events
+}
+
+// Detach marks the end of a client's consumption
+func (h *hub) Detach(sessionId string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s := h.getLocked(sessionId)
+	s.consumers--
+	s.lastSeen = time.Now()
+}
+
+// get returns the session, creating it if needed
+func (h *hub) get(sessionId string) *session {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.getLocked(sessionId)
+}
+
+// getLocked returns the session, creating it if needed. The mutex must be held.
+func (h *hub) getLocked(sessionId string) *session {
+	s, ok := h.sessions[sessionId]
+
+	if !ok {
+		s = &session{
+			id:     sessionId,
+			events: make(chan *event, eventsBufferSize),
+		}
+		h.sessions[sessionId] = s
+	}
+
+	s.lastSeen = time.Now()
+
+	return s
+}
+
+// expireSessions forgets the sessions without any client and idle for longer than sessionTtl
+func (h *hub) expireSessions() {
+	for range time.Tick(sessionTtl / 4) {
+		h.mutex.Lock()
+
+		for id, s := range h.sessions {
+			if s.consumers == 0 && time.Since(s.lastSeen) > sessionTtl {
+				delete(h.sessions, id)
+			}
+		}
+
+		h.mutex.Unlock()
+	}
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// sessionCookie is the name of the cookie holding the session token
+	sessionCookie = "cm_session"
+	// sessionHeader is the header holding the session token, for clients that cannot use cookies
+	sessionHeader = "X-Session-Token"
+	// sessionQueryParam is the query parameter holding the session token, for WebSocket
+	// connections and simple cross-origin requests
+	sessionQueryParam = "token"
+)
+
+var (
+	ErrInvalidSessionToken = errors.New("Invalid session token")
+)
+
+// sessionSigner issues and verifies the session tokens identifying the anonymous users.
+// A token is made of a random session id and of its HMAC-SHA256 signature: "<id>.<signature>".
+type sessionSigner struct {
+	secret []byte
+}
+
+// newSessionSigner is the constructor method for sessionSigner.
+// A random secret is generated if the given one is empty.
+func newSessionSigner(secret string) *sessionSigner {
+	if secret == "" {
+		secret = randomHex(32)
+	}
+
+	return &sessionSigner{
+		secret: []byte(secret),
+	}
+}
+
+// New creates a new session and returns its id and token
+func (s *sessionSigner) New() (string, string) {
+	id := randomHex(16)
+
+	return id, id + "." + s.sign(id)
+}
+
+// Verify checks the token and returns the session id it holds.
+// Returns ErrInvalidSessionToken if the token is malformed or forged.
+func (s *sessionSigner) Verify(token string) (string, error) {
+	parts := strings.SplitN(token, ".", 2)
+
+	if len(parts) != 2 || parts[0] == "" {
+		return "", ErrInvalidSessionToken
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(s.sign(parts[0]))) {
+		return "", ErrInvalidSessionToken
+	}
+
+	return parts[0], nil
+}
+
+// FromRequest verifies the session token sent along with the request
+// and returns the session id it holds. The session cookie is only used when withCookie is true.
+func (s *sessionSigner) FromRequest(r *http.Request, withCookie bool) (string, error) {
+	return s.Verify(tokenFromRequest(r, withCookie))
+}
+
+// sign returns the hex-encoded signature of the session id
+func (s *sessionSigner) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomHex returns n random bytes, hex-encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+
+	// crypto/rand only fails when the system's source of randomness is unavailable
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// tokenFromRequest returns the session token sent along with the request, looking for it
+// in the query, the headers and, when withCookie is true, the cookies, in this order.
+func tokenFromRequest(r *http.Request, withCookie bool) string {
+	if token := r.URL.Query().Get(sessionQueryParam); token != "" {
+		return token
+	}
+
+	if token := r.Header.Get(sessionHeader); token != "" {
+		return token
+	}
+
+	if !withCookie {
+		return ""
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
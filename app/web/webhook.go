@@ -0,0 +1,363 @@
+package web
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// pollTimeout is the maximum time a long-polling request waits for events
+	pollTimeout = 25 * time.Second
+	// writeTimeout is the maximum time to write a WebSocket frame
+	writeTimeout = 10 * time.Second
+	// pingInterval is the interval between two WebSocket pings, keeping the connection alive
+	pingInterval = 30 * time.Second
+	// maxMessageSize is the maximum size of a message sent by the client
+	maxMessageSize = 4096
+)
+
+// sessionResponse is the JSON response returned when creating a session
+type sessionResponse struct {
+	Token string `json:"token"`
+}
+
+// handleCreateSession returns the session token of the client, creating a new session
+// if the client does not have a valid one. The token is also set as a cookie.
+func (b *webBot) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	b.setCorsHeaders(w, r)
+
+	token := tokenFromRequest(r, b.trustedOrigin(r))
+
+	if _, err := b.sessions.Verify(token); err != nil {
+		_, token = b.sessions.New()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	j, _ := json.Marshal(&sessionResponse{Token: token})
+
+	w.Write(j)
+}
+
+// handleWebSocket upgrades the connection to a WebSocket. The client sends its messages
+// through it, and the events of the session are pushed to it.
+func (b *webBot) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := b.sessions.FromRequest(r, b.trustedOrigin(r))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	upgrader := &websocket.Upgrader{
+		CheckOrigin: b.checkOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+
+	if err != nil {
+		// The upgrader already replied to the client
+		log.WithField("session", sessionId).Infof("Could not upgrade the connection: %s", err)
+		return
+	}
+
+	defer conn.Close()
+
+	events := b.hub.Attach(sessionId)
+	defer b.hub.Detach(sessionId)
+
+	done := make(chan struct{})
+	go b.readMessages(conn, sessionId, done)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-events:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			if err := conn.WriteJSON(e); err != nil {
+				log.WithField("session", sessionId).Infof("Could not write the event: %s", err)
+				// The event is lost for this client: keep it for the next one
+				b.hub.Push(sessionId, e)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// readMessages reads the messages sent by the client through the WebSocket,
+// until the connection is closed.
+func (b *webBot) readMessages(conn *websocket.Conn, sessionId string, done chan struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(maxMessageSize)
+
+	for {
+		message := &clientMessage{}
+
+		if err := conn.ReadJSON(message); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.WithField("session", sessionId).Infof("The connection was closed: %s", err)
+			}
+
+			return
+		}
+
+		b.conversationHandler.Push(sessionId, message)
+	}
+}
+
+// handlePoll is the long-polling fallback: it waits for the session's events and returns
+// them as a JSON array. An empty array is returned when no event happened before pollTimeout.
+func (b *webBot) handlePoll(w http.ResponseWriter, r *http.Request) {
+	b.setCorsHeaders(w, r)
+
+	sessionId, err := b.sessions.FromRequest(r, b.trustedOrigin(r))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	events := b.hub.Attach(sessionId)
+	defer b.hub.Detach(sessionId)
+
+	polled := []*event{}
+	timeout := time.NewTimer(pollTimeout)
+	defer timeout.Stop()
+
+	select {
+	case e := <-events:
+		polled = append(polled, e)
+	case <-timeout.C:
+	case <-r.Context().Done():
+		return
+	}
+
+	// Return all of the events that are already available
+drain:
+	for {
+		select {
+		case e := <-events:
+			polled = append(polled, e)
+		default:
+			break drain
+		}
+	}
+
+	j, _ := json.Marshal(polled)
+
+	w.Write(j)
+}
+
+// handleMessageReceived is used by long-polling clients to send their messages.
+// The body is JSON, but it can be sent as text/plain to avoid CORS preflight requests.
+func (b *webBot) handleMessageReceived(w http.ResponseWriter, r *http.Request) {
+	b.setCorsHeaders(w, r)
+
+	sessionId, err := b.sessions.FromRequest(r, b.trustedOrigin(r))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+
+	if err != nil {
+		http.Error(w, "Could not read the request's body", http.StatusBadRequest)
+		return
+	}
+
+	message := &clientMessage{}
+
+	if err := json.Unmarshal(body, message); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	b.conversationHandler.Push(sessionId, message)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// linkCodeResponse is the JSON response returned when creating a link code
+type linkCodeResponse struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleCreateLinkCode creates a one-time code for the session's user. The user can then link
+// another account by sending "/link <code>" on another platform, or by using the code as
+// the authorization code of Messenger's account linking flow.
+func (b *webBot) handleCreateLinkCode(w http.ResponseWriter, r *http.Request) {
+	b.setCorsHeaders(w, r)
+
+	sessionId, err := b.sessions.FromRequest(r, b.trustedOrigin(r))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	linkCode, err := b.conversationHandler.createLinkCode(sessionId)
+
+	if err != nil {
+		log.WithField("session", sessionId).Errorf("Could not create the link code: %s", err)
+		http.Error(w, "Could not create the link code", http.StatusInternalServerError)
+		return
+	}
+
+	j, _ := json.Marshal(&linkCodeResponse{
+		Code:      linkCode.Code,
+		ExpiresAt: linkCode.ExpiresAt,
+	})
+
+	w.Write(j)
+}
+
+// handleWidget serves the JS widget
+func (b *webBot) handleWidget(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Write([]byte(widgetJs))
+}
+
+// checkOrigin tells whether the request's origin is allowed: either a trusted origin,
+// or any origin when the bot allows them all.
+func (b *webBot) checkOrigin(r *http.Request) bool {
+	if b.trustedOrigin(r) {
+		return true
+	}
+
+	_, anyOrigin := b.allowedOrigins()
+
+	return anyOrigin
+}
+
+// trustedOrigin tells whether the request's origin can use the session cookie: either
+// the same origin, or one of the bot's allowed origins. Allowing any origin with "*"
+// does not make them trusted, as any website could then act on behalf of the visitors.
+func (b *webBot) trustedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if origin == "" {
+		return true
+	}
+
+	origins, _ := b.allowedOrigins()
+
+	for _, allowed := range origins {
+		if origin == allowed {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+
+	return err == nil && u.Host == r.Host
+}
+
+// allowedOrigins returns the bot's allowed origins, and whether any origin is allowed
+func (b *webBot) allowedOrigins() ([]string, bool) {
+	var origins []string
+	anyOrigin := false
+
+	for _, origin := range strings.Split(b.definition.StringParam(AllowedOrigin), ",") {
+		origin = strings.TrimSpace(origin)
+
+		switch origin {
+		case "":
+		case "*":
+			anyOrigin = true
+		default:
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins, anyOrigin
+}
+
+// setCorsHeaders allows the websites embedding the widget to call the webhooks.
+// Credentials are only allowed for the trusted origins.
+func (b *webBot) setCorsHeaders(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+
+	if origin == "" {
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	if b.trustedOrigin(r) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		return
+	}
+
+	if _, anyOrigin := b.allowedOrigins(); anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+}
+
+// bindDefaultWebhooks initialises the default web chat webhooks.
+func (b *webBot) bindDefaultWebhooks() {
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/session",
+		b.handleCreateSession,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"GET",
+		"/ws",
+		b.handleWebSocket,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"GET",
+		"/poll",
+		b.handlePoll,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/messages",
+		b.handleMessageReceived,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/link-code",
+		b.handleCreateLinkCode,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"GET",
+		"/widget.js",
+		b.handleWidget,
+	))
+}
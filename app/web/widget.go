@@ -0,0 +1,132 @@
+package web
+
+// widgetJs is the minimal chat widget, embedded in websites using:
+//
+// <script src="https://example.com/api/bots/{slug}/webhooks/widget.js" async></script>
+//
+// It connects using a WebSocket, and falls back to long-polling when WebSockets are not available.
+const widgetJs = `(function () {
+  var script = document.currentScript;
+  var base = script.src.replace(/\/widget\.js(\?.*)?$/, "");
+  var storageKey = "cm_session:" + base;
+  var token = null;
+  var socket = null;
+
+  var root = document.createElement("div");
+  root.style.cssText = "position:fixed;bottom:16px;right:16px;width:300px;font:14px sans-serif;" +
+    "background:#fff;border:1px solid #ccc;border-radius:8px;box-shadow:0 2px 8px rgba(0,0,0,.2);z-index:2147483647";
+  root.innerHTML = '<div data-log style="height:320px;overflow-y:auto;padding:8px"></div>' +
+    '<div data-typing style="display:none;padding:0 8px;color:#888">...</div>' +
+    '<form data-form style="display:flex;border-top:1px solid #eee">' +
+    '<input data-input style="flex:1;border:0;padding:8px" placeholder="Type a message" autocomplete="off">' +
+    '<button style="border:0;background:none;padding:8px">Send</button></form>';
+  document.body.appendChild(root);
+
+  var log = root.querySelector("[data-log]");
+  var typing = root.querySelector("[data-typing]");
+  var input = root.querySelector("[data-input]");
+
+  function append(text, mine) {
+    var line = document.createElement("div");
+    line.textContent = text;
+    line.style.cssText = "margin:4px 0;padding:6px 10px;border-radius:12px;max-width:80%;clear:both;" +
+      (mine ? "float:right;background:#0084ff;color:#fff" : "float:left;background:#f0f0f0");
+    log.appendChild(line);
+    log.scrollTop = log.scrollHeight;
+    return line;
+  }
+
+  function showQuickReplies(quickReplies) {
+    var container = document.createElement("div");
+    container.style.clear = "both";
+    quickReplies.forEach(function (quickReply) {
+      var button = document.createElement("button");
+      button.textContent = quickReply.title;
+      button.style.cssText = "margin:2px;padding:4px 10px;border:1px solid #0084ff;border-radius:12px;background:#fff;color:#0084ff";
+      button.onclick = function () {
+        container.remove();
+        send(quickReply.title, quickReply.payload);
+      };
+      container.appendChild(button);
+    });
+    log.appendChild(container);
+    log.scrollTop = log.scrollHeight;
+  }
+
+  function handle(event) {
+    if (event.type === "typing_on" || event.type === "typing_off") {
+      typing.style.display = event.type === "typing_on" ? "block" : "none";
+      return;
+    }
+    append(event.text, false);
+    if (event.quick_replies) {
+      showQuickReplies(event.quick_replies);
+    }
+  }
+
+  function timezone() {
+    try {
+      return Intl.DateTimeFormat().resolvedOptions().timeZone || "";
+    } catch (e) {
+      return "";
+    }
+  }
+
+  function send(text, payload) {
+    append(text, true);
+    var message = JSON.stringify({ text: text, payload: payload || "", timezone: timezone() });
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(message);
+      return;
+    }
+    fetch(base + "/messages?token=" + encodeURIComponent(token), {
+      method: "POST",
+      headers: { "Content-Type": "text/plain" },
+      body: message
+    });
+  }
+
+  function poll() {
+    fetch(base + "/poll?token=" + encodeURIComponent(token))
+      .then(function (response) { return response.json(); })
+      .then(function (events) { events.forEach(handle); poll(); })
+      .catch(function () { setTimeout(poll, 5000); });
+  }
+
+  function connect() {
+    if (!window.WebSocket) {
+      poll();
+      return;
+    }
+    var opened = false;
+    socket = new WebSocket(base.replace(/^http/, "ws") + "/ws?token=" + encodeURIComponent(token));
+    socket.onopen = function () { opened = true; };
+    socket.onmessage = function (message) { handle(JSON.parse(message.data)); };
+    socket.onclose = function () {
+      socket = null;
+      if (opened) {
+        setTimeout(connect, 1000);
+      } else {
+        poll();
+      }
+    };
+  }
+
+  root.querySelector("[data-form]").onsubmit = function (e) {
+    e.preventDefault();
+    if (input.value && token) {
+      send(input.value);
+      input.value = "";
+    }
+  };
+
+  var stored = window.localStorage.getItem(storageKey) || "";
+  fetch(base + "/session?token=" + encodeURIComponent(stored), { method: "POST" })
+    .then(function (response) { return response.json(); })
+    .then(function (session) {
+      token = session.token;
+      window.localStorage.setItem(storageKey, token);
+      connect();
+    });
+})();
+`
@@ -0,0 +1,97 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	log "github.com/sirupsen/logrus"
+)
+
+// bindDefaultApiEndpoints initialises the default API endpoints.
+func (b *whatsAppBot) bindDefaultApiEndpoints() {
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"GET",
+		"/",
+		bot.HandleViewBot(b),
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/messages",
+		b.handleSendMessage,
+	))
+
+	b.apiEndpoints = append(b.apiEndpoints, bot.NewApiEndpoint(
+		"POST",
+		"/templates",
+		b.handleSendTemplate,
+	))
+}
+
+// sendMessageRequest is the request body used to send a text to a user
+type sendMessageRequest struct {
+	UserId string `json:"user_id"`
+	Text   string `json:"text"`
+}
+
+// sendTemplateRequest is the request body used to send a template to a user
+type sendTemplateRequest struct {
+	UserId   string                `json:"user_id"`
+	Template *api.WhatsAppTemplate `json:"template"`
+}
+
+// handleSendMessage sends a text to a user, outside of any conversation flow.
+// Texts can only be sent within the user's messaging window.
+func (b *whatsAppBot) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body sendMessageRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.UserId == "" || body.Text == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err = b.conversationHandler.sendText(body.UserId, body.Text)
+
+	writeSendError(w, body.UserId, err)
+}
+
+// handleSendTemplate sends a template to a user. Templates can be sent at any time.
+func (b *whatsAppBot) handleSendTemplate(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+
+	var body sendTemplateRequest
+	err := decoder.Decode(&body)
+
+	if err != nil || body.UserId == "" || body.Template == nil || body.Template.Name == "" || body.Template.LanguageCode == "" {
+		log.Errorf("Could not decode the request body: %s", err)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err = b.conversationHandler.sendTemplate(body.UserId, body.Template)
+
+	writeSendError(w, body.UserId, err)
+}
+
+// writeSendError writes the response of a send request, given its error
+func writeSendError(w http.ResponseWriter, userId string, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case err == conversation.ErrNotFound:
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, api.ErrOutsideMessagingWindow), errors.Is(err, api.ErrUserBlockedPage):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		log.WithField("user", userId).Errorf("Could not send the message: %s", err)
+		http.Error(w, "Could not send the message", http.StatusBadGateway)
+	}
+}
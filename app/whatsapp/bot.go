@@ -0,0 +1,149 @@
+// Package whatsapp defines WhatsApp-related bot methods and behaviour.
+package whatsapp
+
+import (
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const (
+	// VerifyToken is the token used to validate the webhook
+	VerifyToken bot.ParamName = "verify_token"
+	// AccessToken is the access token of the WhatsApp Business account
+	AccessToken bot.ParamName = "access_token"
+	// AppSecret is the secret of the Meta app, used to check the signature of the notifications
+	AppSecret bot.ParamName = "app_secret"
+	// PhoneNumberId is the id of the bot's phone number
+	PhoneNumberId bot.ParamName = "phone_number_id"
+	// ApiVersion is the Graph API version, such as "17.0". The app-wide version is used when it is missing.
+	ApiVersion bot.ParamName = "api_version"
+	// BaseUrl is the base url of the Graph API. It is optional and can be
+	// used to target a local stand-in.
+	BaseUrl bot.ParamName = "base_url"
+	// ListButtonText is the text of the button opening the list of quick replies,
+	// when there are too many of them to be sent as buttons. Defaults to "Options".
+	ListButtonText bot.ParamName = "list_button_text"
+)
+
+// Config is the config required in order to instantiate a new WhatsAppBot
+type Config struct {
+	Definition  *bot.Definition
+	WhatsAppApi api.WhatsAppApi
+	Engine      *conversation.Engine
+}
+
+// whatsAppBot is the main structure
+type whatsAppBot struct {
+	webhooks            []*bot.Webhook
+	apiEndpoints        []*bot.ApiEndpoint
+	definition          *bot.Definition
+	whatsAppApi         api.WhatsAppApi
+	appSecret           string
+	conversationHandler *conversationHandler
+}
+
+// NewBot is the constructor method that creates a WhatsApp bot, using
+// the Config struct as method parameters.
+//
+// Upon creation:
+// - The webhooks are attached.
+func NewBot(config *Config) *whatsAppBot {
+	bot := &whatsAppBot{
+		definition:  config.Definition,
+		whatsAppApi: config.WhatsAppApi,
+		appSecret:   config.Definition.StringParam(AppSecret),
+	}
+
+	listButtonText := config.Definition.StringParam(ListButtonText)
+
+	if listButtonText == "" {
+		listButtonText = defaultListButtonText
+	}
+
+	bot.conversationHandler = newConversationHandler(config.Engine, config.WhatsAppApi, listButtonText)
+
+	bot.bindDefaultWebhooks()
+	bot.bindDefaultApiEndpoints()
+
+	return bot
+}
+
+// buildBot is the builder registered for the WhatsApp platform. The WhatsApp API is built
+// from the bot's parameters, falling back to the app-wide Graph API version.
+// The app secret is required, as the notifications cannot be trusted without it.
+func buildBot(conf utils.BuilderConf) (interface{}, error) {
+	definition, ok := utils.GetParam(conf, "definition").(*bot.Definition)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("definition")
+	}
+
+	engine, ok := utils.GetParam(conf, "engine").(*conversation.Engine)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("engine")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	if definition.StringParam(AppSecret) == "" {
+		return nil, api.ErrMissingAppSecret
+	}
+
+	version := definition.StringParam(ApiVersion)
+
+	if version == "" {
+		version, _ = utils.GetParam(conf, "fb_api_version").(string)
+	}
+
+	whatsAppApi, err := api.NewWhatsAppApi("whatsapp", map[string]interface{}{
+		"access_token":    definition.StringParam(AccessToken),
+		"phone_number_id": definition.StringParam(PhoneNumberId),
+		"version":         version,
+		"base_url":        definition.StringParam(BaseUrl),
+		"client":          client,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBot(
+		&Config{
+			Definition:  definition,
+			WhatsAppApi: whatsAppApi,
+			Engine:      engine,
+		},
+	), nil
+}
+
+// Webhooks returns the bot's webhooks.
+// This method is required in order to implement the Bot interface.
+func (b *whatsAppBot) Webhooks() []*bot.Webhook {
+	return b.webhooks
+}
+
+// ApiEndpoints returns the bot's available API endpoints.
+// This method is required in order to implement the Bot interface.
+func (b *whatsAppBot) ApiEndpoints() []*bot.ApiEndpoint {
+	return b.apiEndpoints
+}
+
+// Definition returns the bot's definition.
+// This method is required in order to implement the Bot interface.
+func (b *whatsAppBot) Definition() *bot.Definition {
+	return b.definition
+}
+
+func init() {
+	bot.RegisterSecretParams(bot.PlatformWhatsApp, VerifyToken, AccessToken, AppSecret)
+	bot.RegisterBuilder(bot.PlatformWhatsApp, buildBot)
+}
@@ -0,0 +1,178 @@
+package whatsapp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultListButtonText is the default text of the button opening the list of quick replies
+const defaultListButtonText = "Options"
+
+// platform is the platform name given to the conversation engine
+var platform = string(bot.PlatformWhatsApp)
+
+// conversationHandler is the struct responsible for handling WhatsApp conversations.
+// It translates WhatsApp messages for the conversation engine, and sends its answers back.
+type conversationHandler struct {
+	engine         *conversation.Engine
+	whatsAppApi    api.WhatsAppApi
+	listButtonText string
+	// queue handles the messages of each user in order, in the background,
+	// so that the webhook answers WhatsApp right away
+	queue *utils.SerialQueue
+}
+
+// newConversationHandler is the constructor method for conversationHandler
+func newConversationHandler(e *conversation.Engine, a api.WhatsAppApi, listButtonText string) *conversationHandler {
+	return &conversationHandler{
+		engine:         e,
+		whatsAppApi:    a,
+		listButtonText: listButtonText,
+		queue:          utils.NewSerialQueue(),
+	}
+}
+
+// NotificationReceived handles the notifications received from WhatsApp.
+// The request is parsed right away, and the statuses are logged. Every message is then handed over
+// to the conversation engine in the background, in order for each user.
+// Users are identified by their WhatsApp id.
+func (h *conversationHandler) NotificationReceived(r *http.Request) {
+	notification, err := h.whatsAppApi.ParseNotification(r)
+
+	if err != nil {
+		log.Infof("Could not parse the received notification: %s", err)
+		return
+	}
+
+	for _, status := range notification.Statuses {
+		fields := log.Fields{
+			"message": status.MessageId,
+			"user":    status.RecipientId,
+			"status":  status.Status,
+		}
+
+		if status.ErrorCode != 0 {
+			log.WithFields(fields).Infof("The message could not be delivered: %s (code %d)", status.ErrorTitle, status.ErrorCode)
+			continue
+		}
+
+		log.WithFields(fields).Debug("Message status changed")
+	}
+
+	for _, notificationMessage := range notification.Messages {
+		message := notificationMessage
+
+		h.queue.Push(message.From, func() {
+			h.messageReceived(message)
+		})
+	}
+}
+
+// messageReceived hands a single message over to the conversation engine and answers the user
+func (h *conversationHandler) messageReceived(message *api.WhatsAppMessage) {
+	in := &conversation.InboundMessage{
+		Platform:          platform,
+		SenderId:          message.From,
+		Text:              message.Text,
+		QuickReplyPayload: message.ReplyId,
+		SentAt:            message.SentAt,
+	}
+
+	if message.Location != nil {
+		in.Location = &conversation.Location{
+			Latitude:  message.Location.Latitude,
+			Longitude: message.Location.Longitude,
+		}
+	}
+
+	messages, err := h.engine.Handle(in)
+
+	if err != nil {
+		// @todo: handle this case and return something to the user
+		log.WithField("user", message.From).Errorf("Could not handle the message: %s", err)
+		return
+	}
+
+	h.answer(message.From, messages)
+}
+
+// answer sends the messages to the user, one at a time. Quick replies are sent as reply buttons
+// when there are few of them, as a list otherwise.
+// The user just sent a message, so the answers are always within the messaging window.
+func (h *conversationHandler) answer(waId string, messages []*conversation.OutboundMessage) {
+	for _, message := range messages {
+		err := h.send(waId, message)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"user":    waId,
+				"message": message.Text,
+			}).Errorf("Could not send the message: %s", err)
+			return
+		}
+	}
+}
+
+// send sends a single message, choosing the kind of message depending on its quick replies
+func (h *conversationHandler) send(waId string, message *conversation.OutboundMessage) error {
+	switch {
+	case len(message.QuickReplies) == 0:
+		return h.whatsAppApi.SendText(waId, message.Text)
+	case len(message.QuickReplies) <= api.WhatsAppMaxButtons:
+		var buttons []*api.WhatsAppButton
+
+		for _, quickReply := range message.QuickReplies {
+			buttons = append(buttons, &api.WhatsAppButton{
+				Id:    quickReply.Payload,
+				Title: quickReply.Title,
+			})
+		}
+
+		return h.whatsAppApi.SendButtons(waId, message.Text, buttons)
+	default:
+		var rows []*api.WhatsAppListRow
+
+		for _, quickReply := range message.QuickReplies {
+			rows = append(rows, &api.WhatsAppListRow{
+				Id:    quickReply.Payload,
+				Title: quickReply.Title,
+			})
+		}
+
+		return h.whatsAppApi.SendList(waId, message.Text, h.listButtonText, rows)
+	}
+}
+
+// sendText sends a text to the user outside of any conversation flow, such as reminders.
+// Free-form messages can only be sent within the user's messaging window: outside of it,
+// a template must be sent using sendTemplate.
+// Returns api.ErrOutsideMessagingWindow if the message cannot be sent.
+func (h *conversationHandler) sendText(waId, text string) error {
+	lastUserMessageAt, err := h.engine.LastUserMessageAt(platform, waId)
+
+	if err != nil {
+		return err
+	}
+
+	if !api.IsWithinMessagingWindow(lastUserMessageAt, time.Now()) {
+		log.WithFields(log.Fields{
+			"user":              waId,
+			"lastUserMessageAt": lastUserMessageAt,
+		}).Info("Refusing to send the message: outside of the messaging window")
+		return api.ErrOutsideMessagingWindow
+	}
+
+	return h.whatsAppApi.SendText(waId, text)
+}
+
+// sendTemplate sends a template to the user. Templates can be sent at any time,
+// including outside of the user's messaging window.
+func (h *conversationHandler) sendTemplate(waId string, template *api.WhatsAppTemplate) error {
+	return h.whatsAppApi.SendTemplate(waId, template)
+}
@@ -0,0 +1,71 @@
+package whatsapp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/bot"
+	log "github.com/sirupsen/logrus"
+)
+
+// handleNotificationReceived is called when the WhatsApp Cloud API sends a new notification:
+// messages sent by the users and statuses of the messages we sent.
+// The payload's signature is checked using the app secret, and the handling is then delegated
+// to the Conversation Handler, responsible for most of the logic.
+func (b *whatsAppBot) handleNotificationReceived(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New WhatsApp notification received")
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		http.Error(w, "Could not read the request's body", http.StatusBadRequest)
+		return
+	}
+
+	err = api.VerifyPayloadSignature(body, r.Header.Get(api.SignatureHeader), b.appSecret)
+
+	if err != nil {
+		log.WithField("bot", b.definition.Slug).Infof("Rejecting the notification: %s", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	b.conversationHandler.NotificationReceived(r)
+}
+
+// handleValidateWebhook tries to validate the WhatsApp webhook, the same way as Messenger webhooks
+// More information here: https://developers.facebook.com/docs/whatsapp/cloud-api/guides/set-up-webhooks
+func (b *whatsAppBot) handleValidateWebhook(w http.ResponseWriter, r *http.Request) {
+	log.Debug("New WhatsApp webhook validation request")
+
+	challenge, err := api.VerifyWebhookSubscription(r.URL.Query(), b.definition.StringParam(VerifyToken))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Validate the webhook by writing back the "hub.challenge" query param
+	w.Write([]byte(challenge))
+}
+
+// bindDefaultWebhooks initialises the default WhatsApp-related webhooks.
+func (b *whatsAppBot) bindDefaultWebhooks() {
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"GET",
+		"/",
+		b.handleValidateWebhook,
+	))
+
+	b.webhooks = append(b.webhooks, bot.NewWebhook(
+		"POST",
+		"/",
+		b.handleNotificationReceived,
+	))
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/aziule/conversation-management/app"
+)
+
+// ChatCommand is the command responsible for chatting with the bot on the console.
+// The stories run in-process, without any server or database, which makes it easy to test them.
+// A bot can be given, in which case its definition is loaded from the database.
+type ChatCommand struct {
+	options app.ChatOptions
+}
+
+// NewChatCommand returns a new ChatCommand
+func NewChatCommand() *ChatCommand {
+	return &ChatCommand{}
+}
+
+// Usage returns the usage text for the command
+func (c *ChatCommand) Usage() string {
+	return `chat [-bot=slug] [-config=./config.json] [-nlp=keyword|wit] [-training=training.json] [-wit-token=token] [-debug=true] [-verbose=false]:
+	Chats with the bot on the console, using an offline NLP parser by default.
+	The bot's definition, data types and validators are loaded from the config's database when a bot is given`
+}
+
+// Execute runs the command
+func (c *ChatCommand) Execute(f *flag.FlagSet) error {
+	return app.Chat(&c.options)
+}
+
+// FlagSet returns the command's flag set
+func (c *ChatCommand) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.options.Bot, "bot", "", "The slug of the bot to chat with")
+	f.StringVar(&c.options.ConfigFile, "config", "config.json", "Config file path, used to load the bot")
+	f.StringVar(&c.options.Nlp, "nlp", "keyword", "The NLP parser used to parse the messages")
+	f.StringVar(&c.options.TrainingFile, "training", "", "The training data file of the keyword parser")
+	f.StringVar(&c.options.WitBearerToken, "wit-token", "", "The bearer token of the wit parser")
+	f.BoolVar(&c.options.Debug, "debug", true, "Show the conversation's state after each answer")
+	f.BoolVar(&c.options.Verbose, "verbose", false, "Show the logs")
+}
+
+// Name returns the command's name, to be used when invoking it from the cli
+func (c *ChatCommand) Name() string {
+	return "chat"
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrInvalidToken           = errors.New("Invalid or expired access token")
+	ErrUserBlockedPage        = errors.New("The user cannot receive messages from the page")
+	ErrRateLimited            = errors.New("Rate limited by the API")
+	ErrOutsideMessagingWindow = errors.New("Message sent outside of the allowed messaging window")
+	ErrGraphApi               = errors.New("Graph API error")
+)
+
+// Graph API error codes and subcodes.
+// More information here: https://developers.facebook.com/docs/messenger-platform/reference/send-api/error-codes
+const (
+	graphCodeUnknown          = 1
+	graphCodeService          = 2
+	graphCodeAppRateLimit     = 4
+	graphCodePermission       = 10
+	graphCodeUserRateLimit    = 17
+	graphCodePageRateLimit    = 32
+	graphCodeInvalidToken     = 190
+	graphCodeUnavailableUser  = 551
+	graphCodeSendRateLimit    = 613
+	graphSubcodeUserBlocked   = 1545041
+	graphSubcodeOutsideWindow = 2018278
+	graphSubcodeNoPermission  = 2018065
+	// WhatsApp Cloud API error codes
+	whatsAppCodeRateLimit    = 130429
+	whatsAppCodeSpamLimit    = 131056
+	whatsAppCodeReEngagement = 131047
+)
+
+// GraphError represents an error returned by the Graph API.
+// Its underlying error, available through Unwrap, is one of the
+// package-level errors (ErrInvalidToken, ErrRateLimited, etc.).
+type GraphError struct {
+	Err         error
+	StatusCode  int
+	Code        int
+	Subcode     int
+	Type        string
+	Message     string
+	FbTraceId   string
+	IsTransient bool
+}
+
+// NewGraphError creates a new GraphError and decodes the kind of error from its codes
+func NewGraphError(statusCode, code, subcode int, errorType, message, fbTraceId string, isTransient bool) *GraphError {
+	graphError := &GraphError{
+		StatusCode:  statusCode,
+		Code:        code,
+		Subcode:     subcode,
+		Type:        errorType,
+		Message:     message,
+		FbTraceId:   fbTraceId,
+		IsTransient: isTransient,
+	}
+
+	switch {
+	case code == graphCodeInvalidToken:
+		graphError.Err = ErrInvalidToken
+	case code == graphCodeAppRateLimit, code == graphCodeUserRateLimit, code == graphCodePageRateLimit, code == graphCodeSendRateLimit,
+		code == whatsAppCodeRateLimit, code == whatsAppCodeSpamLimit:
+		graphError.Err = ErrRateLimited
+	case code == graphCodeUnavailableUser, subcode == graphSubcodeUserBlocked:
+		graphError.Err = ErrUserBlockedPage
+	case code == graphCodePermission && (subcode == graphSubcodeOutsideWindow || subcode == graphSubcodeNoPermission),
+		code == whatsAppCodeReEngagement:
+		graphError.Err = ErrOutsideMessagingWindow
+	default:
+		graphError.Err = ErrGraphApi
+	}
+
+	return graphError
+}
+
+// Error returns the error's message.
+// This method is required in order to implement the error interface.
+func (e *GraphError) Error() string {
+	return fmt.Sprintf("%s (code %d, subcode %d, fbtrace_id %s): %s", e.Err, e.Code, e.Subcode, e.FbTraceId, e.Message)
+}
+
+// Unwrap returns the underlying error, so that errors.Is can be used
+func (e *GraphError) Unwrap() error {
+	return e.Err
+}
+
+// IsRateLimited tells whether the request was rejected because of the rate limits,
+// in which case it was not processed
+func (e *GraphError) IsRateLimited() bool {
+	return e.Err == ErrRateLimited || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Retryable tells whether sending the same request again may succeed.
+// As the request may have been processed, only idempotent requests should be sent again,
+// unless it was rate limited (see IsRateLimited).
+func (e *GraphError) Retryable() bool {
+	return e.IsTransient ||
+		e.Err == ErrRateLimited ||
+		e.StatusCode >= 500 ||
+		e.Code == graphCodeUnknown ||
+		e.Code == graphCodeService
+}
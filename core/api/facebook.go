@@ -7,7 +7,46 @@ import (
 	"github.com/aziule/conversation-management/core/utils"
 )
 
-const builderPrefix = "api_"
+const (
+	builderPrefix = "api_"
+
+	// PageInboxAppId is the app id of the Page Inbox, used by the Handover Protocol
+	// to let human agents answer the users.
+	PageInboxAppId = "263902037430900"
+)
+
+// FacebookEventType represents the kind of event received through the webhook
+type FacebookEventType string
+
+const (
+	FacebookEventMessage           FacebookEventType = "message"
+	FacebookEventPostback          FacebookEventType = "postback"
+	FacebookEventPassThreadControl FacebookEventType = "pass_thread_control"
+	FacebookEventTakeThreadControl FacebookEventType = "take_thread_control"
+	FacebookEventAccountLinking    FacebookEventType = "account_linking"
+)
+
+// Account linking statuses
+const (
+	AccountLinkingLinked   = "linked"
+	AccountLinkingUnlinked = "unlinked"
+)
+
+// SenderAction represents an action the page can perform on a user's thread,
+// such as marking the last message as seen or showing the typing indicator.
+type SenderAction string
+
+const (
+	SenderActionMarkSeen  SenderAction = "mark_seen"
+	SenderActionTypingOn  SenderAction = "typing_on"
+	SenderActionTypingOff SenderAction = "typing_off"
+)
+
+// Messenger limits on quick replies
+const (
+	FacebookMaxQuickReplies    = 13
+	FacebookMaxQuickReplyTitle = 20
+)
 
 // RegisterFacebookApiBuilder registers a new service builder
 func RegisterFacebookApiBuilder(name string, builder utils.ServiceBuilder) {
@@ -35,19 +74,136 @@ func NewFacebookApi(name string, conf utils.BuilderConf) (FacebookApi, error) {
 
 // FacebookApi is the interface representing a Facebook API
 type FacebookApi interface {
-	ParseRequestMessageReceived(r *http.Request) (*FacebookReceivedMessage, error)
-	SendTextToUser(recipientId, text string) error
+	// ParseRequestMessagesReceived parses every event of the request, in order
+	ParseRequestMessagesReceived(r *http.Request) ([]*FacebookReceivedMessage, error)
+	SendTextToUser(recipientId, text string, options *SendOptions) error
+	// SendQuickRepliesToUser sends a text to the user, along with quick replies the user can tap
+	SendQuickRepliesToUser(recipientId, text string, quickReplies []*FacebookQuickReply, options *SendOptions) error
+	SendSenderAction(recipientId string, action SenderAction) error
+	GetMessengerProfile() (*MessengerProfile, error)
+	SetMessengerProfile(profile *MessengerProfile) error
+	DeleteMessengerProfileFields(fields []string) error
+	PassThreadControl(recipientId, targetAppId, metadata string) error
+	TakeThreadControl(recipientId, metadata string) error
+	// SetPageAccessToken replaces the page access token, without having to recreate the API
+	SetPageAccessToken(pageAccessToken string)
 }
 
 // FacebookReceivedMessage is the base struct for received messages
 // @todo: see how to rename to FacebookFacebookReceivedMessage if facebook.go
 // is the only file in the api package
 type FacebookReceivedMessage struct {
+	Event             FacebookEventType
 	Mid               string
 	SenderId          string
 	RecipientId       string
 	SentAt            time.Time
 	Text              string
 	QuickReplyPayload string
-	Nlp               []byte
+	// PostbackPayload is the payload of the button pressed by the user, such as the Get Started
+	// button or a persistent menu item. The button's title is given as the text.
+	PostbackPayload string
+	Nlp             []byte
+	// Standby is true when the message was received while another app
+	// owns the thread (Handover Protocol)
+	Standby        bool
+	ThreadControl  *FacebookThreadControl
+	AccountLinking *FacebookAccountLinking
+}
+
+// FacebookQuickReply is a quick reply sent along with a message.
+// Its payload is sent back within the user's message when tapped.
+type FacebookQuickReply struct {
+	Title   string
+	Payload string
+}
+
+// FacebookAccountLinking contains the information sent along with the account linking events.
+// The authorization code is only set when the account is linked.
+type FacebookAccountLinking struct {
+	Status            string
+	AuthorizationCode string
+}
+
+// FacebookThreadControl contains the information sent along with
+// the Handover Protocol events
+type FacebookThreadControl struct {
+	NewOwnerAppId      string
+	PreviousOwnerAppId string
+	Metadata           string
+}
+
+// Messenger profile fields, as named by the Messenger Profile API
+const (
+	ProfileFieldGetStarted         = "get_started"
+	ProfileFieldPersistentMenu     = "persistent_menu"
+	ProfileFieldGreeting           = "greeting"
+	ProfileFieldWhitelistedDomains = "whitelisted_domains"
+)
+
+// MessengerProfile represents the page's Messenger profile: the Get Started button,
+// the persistent menu, the greeting text and the whitelisted domains.
+// More information here: https://developers.facebook.com/docs/messenger-platform/reference/messenger-profile-api
+type MessengerProfile struct {
+	GetStarted         *GetStartedButton `json:"get_started,omitempty"`
+	PersistentMenu     []*PersistentMenu `json:"persistent_menu,omitempty"`
+	Greeting           []*Greeting       `json:"greeting,omitempty"`
+	WhitelistedDomains []string          `json:"whitelisted_domains,omitempty"`
+}
+
+// GetStartedButton represents the Get Started button, shown on the welcome screen.
+// Its payload is sent back as a postback, such as "intent:greet", when the button is pressed.
+type GetStartedButton struct {
+	Payload string `json:"payload"`
+}
+
+// Greeting represents the greeting text shown on the welcome screen, for a given locale
+type Greeting struct {
+	Locale string `json:"locale"`
+	Text   string `json:"text"`
+}
+
+// PersistentMenu represents the persistent menu, for a given locale
+type PersistentMenu struct {
+	Locale                string      `json:"locale"`
+	ComposerInputDisabled bool        `json:"composer_input_disabled"`
+	CallToActions         []*MenuItem `json:"call_to_actions,omitempty"`
+}
+
+// MenuItem represents a single item of the persistent menu. It can be a postback,
+// a web url or a nested menu.
+type MenuItem struct {
+	Type          string      `json:"type"`
+	Title         string      `json:"title"`
+	Payload       string      `json:"payload,omitempty"`
+	Url           string      `json:"url,omitempty"`
+	CallToActions []*MenuItem `json:"call_to_actions,omitempty"`
+}
+
+// EmptyFields returns the profile fields that are not set
+func (profile *MessengerProfile) EmptyFields() []string {
+	var fields []string
+
+	if profile.GetStarted == nil {
+		fields = append(fields, ProfileFieldGetStarted)
+	}
+
+	if len(profile.PersistentMenu) == 0 {
+		fields = append(fields, ProfileFieldPersistentMenu)
+	}
+
+	if len(profile.Greeting) == 0 {
+		fields = append(fields, ProfileFieldGreeting)
+	}
+
+	if len(profile.WhitelistedDomains) == 0 {
+		fields = append(fields, ProfileFieldWhitelistedDomains)
+	}
+
+	return fields
+}
+
+// IsEmpty tells whether none of the profile fields are set
+func (profile *MessengerProfile) IsEmpty() bool {
+	return len(profile.EmptyFields()) == 4
 }
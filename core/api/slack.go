@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const slackBuilderPrefix = "api_slack_"
+
+// Slack event types handled by the bots
+const (
+	SlackEventUrlVerification = "url_verification"
+	SlackEventMessage         = "message"
+	SlackEventAppMention      = "app_mention"
+	SlackEventBlockActions    = "block_actions"
+)
+
+// RegisterSlackApiBuilder registers a new service builder
+func RegisterSlackApiBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(slackBuilderPrefix+name, builder)
+}
+
+// NewSlackApi tries to create a SlackApi using the available builders.
+// Returns ErrServiceBuilderNotFound if the slackApi builder isn't found.
+// Returns an error in case of any error during the build process.
+func NewSlackApi(name string, conf utils.BuilderConf) (SlackApi, error) {
+	slackApiBuilder, err := utils.GetServiceBuilder(slackBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	slackApi, err := slackApiBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return slackApi.(SlackApi), nil
+}
+
+// SlackApi is the interface representing the Slack Web API, Events API
+// and interactive components
+type SlackApi interface {
+	ParseEvent(r *http.Request) (*SlackEvent, error)
+	ParseInteraction(r *http.Request) (*SlackEvent, error)
+	PostMessage(channelId, text string, blocks []*SlackBlock) error
+}
+
+// SlackEvent is the base struct for the events received from Slack: Events API
+// callbacks (url_verification, message, app_mention) and block actions.
+type SlackEvent struct {
+	Type string
+	// EventId is the unique id of an Events API callback, kept by Slack when it retries
+	// to send the event. It is not set for block actions.
+	EventId string
+	// Challenge is only set for url_verification events
+	Challenge   string
+	TeamId      string
+	UserId      string
+	ChannelId   string
+	ChannelType string
+	// BotId is set when the message was sent by a bot, including ours
+	BotId  string
+	Text   string
+	SentAt time.Time
+	// ActionValue is the value of the button pressed by the user, for block actions
+	ActionValue string
+}
+
+// SlackBlock represents a Block Kit layout block
+type SlackBlock struct {
+	Type     string          `json:"type"`
+	Text     *SlackText      `json:"text,omitempty"`
+	Elements []*SlackElement `json:"elements,omitempty"`
+}
+
+// SlackText represents a Block Kit text object
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackElement represents a Block Kit interactive element, such as a button
+type SlackElement struct {
+	Type     string     `json:"type"`
+	Text     *SlackText `json:"text,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	ActionId string     `json:"action_id,omitempty"`
+}
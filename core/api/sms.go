@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const smsBuilderPrefix = "api_sms_"
+
+var (
+	ErrInvalidSignature = errors.New("Invalid request signature")
+)
+
+// RegisterSmsApiBuilder registers a new service builder
+func RegisterSmsApiBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(smsBuilderPrefix+name, builder)
+}
+
+// NewSmsApi tries to create a SmsApi using the available builders.
+// Returns ErrServiceBuilderNotFound if the smsApi builder isn't found.
+// Returns an error in case of any error during the build process.
+func NewSmsApi(name string, conf utils.BuilderConf) (SmsApi, error) {
+	smsApiBuilder, err := utils.GetServiceBuilder(smsBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	smsApi, err := smsApiBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return smsApi.(SmsApi), nil
+}
+
+// SmsApi is the interface representing an SMS provider's API
+type SmsApi interface {
+	// ParseMessage validates the request's signature and creates a SmsMessage from it.
+	// The webhook url is the public url the provider called, used to compute the signature.
+	// Returns ErrInvalidSignature if the request does not come from the provider.
+	ParseMessage(r *http.Request, webhookUrl string) (*SmsMessage, error)
+	SendMessage(to, text string) error
+}
+
+// SmsMessage is the struct representing a received text message
+type SmsMessage struct {
+	MessageId string
+	// From is the user's phone number
+	From string
+	// To is the bot's phone number
+	To     string
+	Text   string
+	SentAt time.Time
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const telegramBuilderPrefix = "api_telegram_"
+
+// RegisterTelegramApiBuilder registers a new service builder
+func RegisterTelegramApiBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(telegramBuilderPrefix+name, builder)
+}
+
+// NewTelegramApi tries to create a TelegramApi using the available builders.
+// Returns ErrServiceBuilderNotFound if the telegramApi builder isn't found.
+// Returns an error in case of any error during the build process.
+func NewTelegramApi(name string, conf utils.BuilderConf) (TelegramApi, error) {
+	telegramApiBuilder, err := utils.GetServiceBuilder(telegramBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	telegramApi, err := telegramApiBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return telegramApi.(TelegramApi), nil
+}
+
+// TelegramApi is the interface representing the Telegram Bot API
+type TelegramApi interface {
+	ParseUpdate(r *http.Request) (*TelegramUpdate, error)
+	SendMessage(chatId int64, text string, keyboard [][]*TelegramInlineButton) error
+	AnswerCallbackQuery(callbackQueryId string) error
+	SetWebhook(url, secretToken string) error
+}
+
+// TelegramUpdate is the base struct for the updates received from Telegram:
+// messages, callback queries sent from inline keyboards and locations.
+type TelegramUpdate struct {
+	UpdateId int64
+	ChatId   int64
+	SenderId int64
+	SentAt   time.Time
+	Text     string
+	// CallbackQueryId and CallbackData are set when the user pressed a button
+	// of an inline keyboard
+	CallbackQueryId string
+	CallbackData    string
+	Location        *TelegramLocation
+}
+
+// TelegramLocation represents a location shared by the user
+type TelegramLocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// TelegramInlineButton represents a button of an inline keyboard
+type TelegramInlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// SignatureHeader is the header holding the signature of the events sent by the Graph API
+const SignatureHeader = "X-Hub-Signature-256"
+
+var (
+	ErrInvalidHubMode     = errors.New("Invalid hub mode")
+	ErrInvalidVerifyToken = errors.New("Invalid verify token")
+	ErrMissingAppSecret   = errors.New("Missing app secret: the payload signatures cannot be verified")
+)
+
+// VerifyWebhookSubscription validates the verification request sent by the Graph API when
+// subscribing to a webhook (Messenger, WhatsApp, etc.), using the query params of the request.
+// It returns the challenge that needs to be written back in order to validate the webhook.
+// More information here: https://developers.facebook.com/docs/graph-api/webhooks/getting-started
+func VerifyWebhookSubscription(queryParams url.Values, expectedVerifyToken string) (string, error) {
+	hubMode, err := utils.GetSingleQueryParam(queryParams, "hub.mode")
+
+	if err != nil {
+		log.WithField("param", "hub.mode").Infof("Could not fetch param: %s", err)
+		return "", err
+	}
+
+	if hubMode != "subscribe" {
+		log.WithFields(log.Fields{
+			"expected": "subscribe",
+			"mode":     hubMode,
+		}).Info("Invalid hub mode")
+		return "", ErrInvalidHubMode
+	}
+
+	verifyToken, err := utils.GetSingleQueryParam(queryParams, "hub.verify_token")
+
+	if err != nil {
+		log.WithField("param", "hub.verify_token").Infof("Could not fetch param: %s", err)
+		return "", err
+	}
+
+	if expectedVerifyToken == "" || subtle.ConstantTimeCompare([]byte(verifyToken), []byte(expectedVerifyToken)) != 1 {
+		log.WithField("token", verifyToken).Info("Invalid verify token")
+		return "", ErrInvalidVerifyToken
+	}
+
+	challenge, err := utils.GetSingleQueryParam(queryParams, "hub.challenge")
+
+	if err != nil {
+		log.WithField("param", "hub.challenge").Infof("Could not fetch param: %s", err)
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// VerifyPayloadSignature checks the signature of the events sent by the Graph API, found within the
+// SignatureHeader: the "sha256=" prefixed HMAC of the request's body, using the app secret.
+// Returns ErrMissingAppSecret if there is no app secret, as anyone could sign the payloads.
+// More information here: https://developers.facebook.com/docs/messenger-platform/webhooks#security
+func VerifyPayloadSignature(body []byte, signature, appSecret string) error {
+	if appSecret == "" {
+		return ErrMissingAppSecret
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(strings.TrimPrefix(signature, "sha256=")), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
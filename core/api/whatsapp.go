@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const whatsAppBuilderPrefix = "api_whatsapp_"
+
+// WhatsApp message types handled by the bots
+const (
+	WhatsAppMessageText        = "text"
+	WhatsAppMessageInteractive = "interactive"
+	WhatsAppMessageButton      = "button"
+	WhatsAppMessageLocation    = "location"
+)
+
+// WhatsApp limits on interactive messages
+const (
+	WhatsAppMaxButtons      = 3
+	WhatsAppMaxButtonTitle  = 20
+	WhatsAppMaxListRows     = 10
+	WhatsAppMaxListRowTitle = 24
+	WhatsAppMaxListButton   = 20
+)
+
+// RegisterWhatsAppApiBuilder registers a new service builder
+func RegisterWhatsAppApiBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(whatsAppBuilderPrefix+name, builder)
+}
+
+// NewWhatsAppApi tries to create a WhatsAppApi using the available builders.
+// Returns ErrServiceBuilderNotFound if the whatsAppApi builder isn't found.
+// Returns an error in case of any error during the build process.
+func NewWhatsAppApi(name string, conf utils.BuilderConf) (WhatsAppApi, error) {
+	whatsAppApiBuilder, err := utils.GetServiceBuilder(whatsAppBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	whatsAppApi, err := whatsAppApiBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return whatsAppApi.(WhatsAppApi), nil
+}
+
+// WhatsAppApi is the interface representing the WhatsApp Cloud API.
+// Free-form messages (text, buttons, lists) can only be sent within the user's
+// messaging window: outside of it, only templates can be sent.
+type WhatsAppApi interface {
+	ParseNotification(r *http.Request) (*WhatsAppNotification, error)
+	SendText(to, text string) error
+	SendButtons(to, text string, buttons []*WhatsAppButton) error
+	SendList(to, text, buttonText string, rows []*WhatsAppListRow) error
+	SendTemplate(to string, template *WhatsAppTemplate) error
+}
+
+// WhatsAppNotification is the struct representing a webhook notification,
+// made of the messages sent by the users and of the statuses of the messages we sent.
+type WhatsAppNotification struct {
+	Messages []*WhatsAppMessage
+	Statuses []*WhatsAppStatus
+}
+
+// WhatsAppMessage is the struct representing a message received from a user
+type WhatsAppMessage struct {
+	Id string
+	// From is the user's WhatsApp id (its phone number)
+	From string
+	// PhoneNumberId is the id of the bot's phone number
+	PhoneNumberId string
+	Type          string
+	Text          string
+	// ReplyId is the id of the button or list row chosen by the user
+	ReplyId  string
+	SentAt   time.Time
+	Location *WhatsAppLocation
+}
+
+// WhatsAppLocation is the struct representing a location shared by the user
+type WhatsAppLocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// WhatsAppStatus is the struct representing the status of a message sent to a user:
+// sent, delivered, read or failed.
+type WhatsAppStatus struct {
+	MessageId   string
+	RecipientId string
+	Status      string
+	Timestamp   time.Time
+	ErrorCode   int
+	ErrorTitle  string
+}
+
+// WhatsAppButton is a reply button of an interactive message
+type WhatsAppButton struct {
+	Id    string
+	Title string
+}
+
+// WhatsAppListRow is a row of an interactive list message
+type WhatsAppListRow struct {
+	Id          string
+	Title       string
+	Description string
+}
+
+// WhatsAppTemplate is a pre-approved template message, the only kind of message that
+// can be sent outside of the user's messaging window.
+type WhatsAppTemplate struct {
+	Name         string   `json:"name"`
+	LanguageCode string   `json:"language"`
+	Parameters   []string `json:"parameters"`
+}
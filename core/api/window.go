@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+// MessagingType represents the messaging type of a message sent using the Send API
+type MessagingType string
+
+// MessageTag represents a tag allowing a message to be sent outside of the messaging window
+type MessageTag string
+
+const (
+	MessagingTypeResponse   MessagingType = "RESPONSE"
+	MessagingTypeUpdate     MessagingType = "UPDATE"
+	MessagingTypeMessageTag MessagingType = "MESSAGE_TAG"
+
+	MessageTagConfirmedEventUpdate MessageTag = "CONFIRMED_EVENT_UPDATE"
+	MessageTagPostPurchaseUpdate   MessageTag = "POST_PURCHASE_UPDATE"
+	MessageTagAccountUpdate        MessageTag = "ACCOUNT_UPDATE"
+	MessageTagHumanAgent           MessageTag = "HUMAN_AGENT"
+
+	// MessagingWindow is the time during which the page can send any message
+	// to a user, after the user's last message.
+	MessagingWindow = 24 * time.Hour
+	// HumanAgentWindow is the time during which a human agent can answer
+	// a user, after the user's last message, using the HUMAN_AGENT tag.
+	HumanAgentWindow = 7 * 24 * time.Hour
+)
+
+var ErrInvalidMessageTag = errors.New("Invalid message tag")
+
+// SendOptions are the options used when sending a message: its messaging type
+// and, for messages sent outside of the messaging window, its tag.
+type SendOptions struct {
+	MessagingType MessagingType
+	Tag           MessageTag
+}
+
+// NewResponseSendOptions returns the options to use when answering a message sent by the user
+func NewResponseSendOptions() *SendOptions {
+	return &SendOptions{
+		MessagingType: MessagingTypeResponse,
+	}
+}
+
+// NewSendOptions computes the options to use when sending a message that is not a response,
+// given the time of the user's last message.
+//
+// Within the messaging window, the message is sent as an update. Outside of it, the message
+// must be tagged, otherwise ErrOutsideMessagingWindow is returned. The HUMAN_AGENT tag can only be
+// used within the HumanAgentWindow.
+// A zero lastUserMessageAt means the user never sent any message.
+func NewSendOptions(lastUserMessageAt, now time.Time, tag MessageTag) (*SendOptions, error) {
+	if tag != "" && !isValidMessageTag(tag) {
+		return nil, ErrInvalidMessageTag
+	}
+
+	elapsed := now.Sub(lastUserMessageAt)
+
+	if IsWithinMessagingWindow(lastUserMessageAt, now) {
+		return &SendOptions{
+			MessagingType: MessagingTypeUpdate,
+		}, nil
+	}
+
+	if tag == "" {
+		return nil, ErrOutsideMessagingWindow
+	}
+
+	if tag == MessageTagHumanAgent && (lastUserMessageAt.IsZero() || elapsed > HumanAgentWindow) {
+		return nil, ErrOutsideMessagingWindow
+	}
+
+	return &SendOptions{
+		MessagingType: MessagingTypeMessageTag,
+		Tag:           tag,
+	}, nil
+}
+
+// isValidMessageTag tells whether the tag is one of the supported message tags
+func isValidMessageTag(tag MessageTag) bool {
+	switch tag {
+	case MessageTagConfirmedEventUpdate, MessageTagPostPurchaseUpdate, MessageTagAccountUpdate, MessageTagHumanAgent:
+		return true
+	}
+
+	return false
+}
+
+// IsWithinMessagingWindow tells whether any message can be sent to the user, given the time
+// of the user's last message. A zero lastUserMessageAt means the user never sent any message.
+func IsWithinMessagingWindow(lastUserMessageAt, now time.Time) bool {
+	return !lastUserMessageAt.IsZero() && now.Sub(lastUserMessageAt) <= MessagingWindow
+}
@@ -18,9 +18,44 @@ type Platform string
 
 const (
 	PlatformFacebook Platform = "facebook"
+	PlatformTelegram Platform = "telegram"
+	PlatformSlack    Platform = "slack"
+	PlatformWeb      Platform = "web"
+	PlatformSms      Platform = "sms"
+	PlatformWhatsApp Platform = "whatsapp"
+	PlatformConsole  Platform = "console"
 	builderPrefix             = "bot_"
 )
 
+// NlpParam is the bot's NLP config, shared by all of the platforms. It holds the "provider"
+// parsing the messages' text, such as "rasa", along with the provider's params.
+// The app-wide NLP provider is used when it is missing.
+const NlpParam ParamName = "nlp"
+
+// ThresholdsParam holds the minimum confidences the NLP data must reach for the bot to act on it,
+// such as {"intent": 0.7, "entity": 0.5, "ambiguity": 0.1, "candidates": 3}, and their overrides
+// by step name, such as {"steps": {"book_table_get_nb_persons": {"entity": 0.8}}}.
+// The default thresholds are used when it is missing.
+const ThresholdsParam ParamName = "nlp_thresholds"
+
+// ValidatorsParam holds the validation rules of the entities' values, by entity name,
+// such as {"nb_persons": {"min": 1, "max": 20}} (see nlp.NewValidators).
+const ValidatorsParam ParamName = "nlp_validators"
+
+// TimezoneParam is the timezone of the bot's users, such as "Europe/Paris", used to understand
+// the dates and times sent by the users whose timezone is unknown.
+const TimezoneParam ParamName = "timezone"
+
+// CacheParam holds the TTLs of the bot's NLP cache, such as {"parse_ttl": "1h", "list_ttl": "5m"},
+// overriding the app-wide ones (see nlp.NewCacheConf). A zero TTL disables the caching.
+const CacheParam ParamName = "nlp_cache"
+
+// RedactedParam replaces the values of the secret parameters within the definitions shown by the API
+const RedactedParam = "[redacted]"
+
+// platformBuilderPrefix is the prefix of the builders creating the bots of each platform
+const platformBuilderPrefix = "bot_platform_"
+
 // RegisterRepositoryBuilder registers a new service builder using a package-level prefix
 func RegisterRepositoryBuilder(name string, builder utils.ServiceBuilder) {
 	utils.RegisterServiceBuilder(builderPrefix+name, builder)
@@ -45,6 +80,48 @@ func NewRepository(name string, conf utils.BuilderConf) (Repository, error) {
 	return repository.(Repository), nil
 }
 
+// RegisterBuilder registers the builder of the bots running on the given platform.
+// Platforms register themselves, so that new platforms can be added without
+// modifying the app.
+func RegisterBuilder(platform Platform, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(platformBuilderPrefix+string(platform), builder)
+}
+
+// secretParams stores the parameters holding secrets, by platform
+var secretParams = make(map[Platform][]ParamName)
+
+// RegisterSecretParams registers the parameters of the platform's bots holding secrets, such as
+// access tokens, so that they are never shown by the API (see Definition.Public).
+func RegisterSecretParams(platform Platform, names ...ParamName) {
+	secretParams[platform] = append(secretParams[platform], names...)
+}
+
+// NewBot tries to create a Bot running on the definition's platform, using the available builders.
+// The definition is given to the builder within the conf, using the "definition" key.
+// Returns ErrServiceBuilderNotFound if the platform's builder isn't found.
+// Returns an error in case of any error during the build process.
+func NewBot(definition *Definition, conf utils.BuilderConf) (Bot, error) {
+	botBuilder, err := utils.GetServiceBuilder(platformBuilderPrefix + string(definition.Platform))
+
+	if err != nil {
+		return nil, err
+	}
+
+	botConf := utils.BuilderConf{"definition": definition}
+
+	for name, value := range conf {
+		botConf[name] = value
+	}
+
+	b, err := botBuilder(botConf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.(Bot), nil
+}
+
 // Bot is the main interface for a Bot
 type Bot interface {
 	Webhooks() []*Webhook
@@ -64,6 +141,56 @@ type Definition struct {
 	UpdatedAt  time.Time                 `json:"updated_at" bson:"updated_at"`
 }
 
+// BoolParam returns the value of a boolean parameter.
+// Returns false if the parameter is missing or is not a boolean.
+func (d *Definition) BoolParam(name ParamName) bool {
+	value, ok := d.Parameters[name].(bool)
+
+	return ok && value
+}
+
+// StringParam returns the value of a string parameter.
+// Returns an empty string if the parameter is missing or is not a string.
+func (d *Definition) StringParam(name ParamName) string {
+	value, _ := d.Parameters[name].(string)
+
+	return value
+}
+
+// MapParam returns the value of a parameter holding an object, such as a service's config.
+// Returns nil if the parameter is missing or is not an object.
+func (d *Definition) MapParam(name ParamName) map[string]interface{} {
+	// Objects are decoded as bson.M when the definition is loaded from the db
+	return utils.ToMap(d.Parameters[name])
+}
+
+// Copy returns a copy of the definition, with its own parameters map, so that the parameters
+// can be changed without affecting the readers of the original definition.
+func (d *Definition) Copy() *Definition {
+	definition := *d
+	definition.Parameters = make(map[ParamName]interface{}, len(d.Parameters))
+
+	for name, value := range d.Parameters {
+		definition.Parameters[name] = value
+	}
+
+	return &definition
+}
+
+// Public returns a copy of the definition that can be shown, where the values of the
+// platform's secret parameters are redacted.
+func (d *Definition) Public() *Definition {
+	definition := d.Copy()
+
+	for _, name := range secretParams[d.Platform] {
+		if _, ok := definition.Parameters[name]; ok {
+			definition.Parameters[name] = RedactedParam
+		}
+	}
+
+	return definition
+}
+
 // Repository is the interface responsible for fetching / saving bots
 type Repository interface {
 	FindAll() ([]*Definition, error)
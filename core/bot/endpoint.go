@@ -1,6 +1,7 @@
 package bot
 
 import (
+	"encoding/json"
 	"net/http"
 )
 
@@ -38,3 +39,28 @@ func NewApiEndpoint(method, path string, handler http.HandlerFunc) *ApiEndpoint
 		Handler:     handler,
 	}
 }
+
+// sharedWebhooks stores the webhooks shared by all of the bots of a platform
+var sharedWebhooks = make(map[Platform][]*Webhook)
+
+// RegisterSharedWebhooks registers webhooks shared by all of the bots of a platform,
+// such as a single webhook routing the events to the right bot.
+// They are mounted on /api/webhooks/{platform}.
+func RegisterSharedWebhooks(platform Platform, webhooks ...*Webhook) {
+	sharedWebhooks[platform] = append(sharedWebhooks[platform], webhooks...)
+}
+
+// SharedWebhooks returns the registered shared webhooks, by platform
+func SharedWebhooks() map[Platform][]*Webhook {
+	return sharedWebhooks
+}
+
+// HandleViewBot returns the handler showing details about the bot. The secret
+// parameters of the bot's definition are redacted.
+func HandleViewBot(b Bot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		j, _ := json.Marshal(b.Definition().Public())
+
+		w.Write(j)
+	}
+}
@@ -4,9 +4,9 @@ package conversation
 
 import (
 	"errors"
-	"net/http"
 	"time"
 
+	"github.com/aziule/conversation-management/core/nlp"
 	"github.com/aziule/conversation-management/core/utils"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/mgo.v2/bson"
@@ -49,25 +49,20 @@ func NewRepository(name string, conf utils.BuilderConf) (Repository, error) {
 	return repository.(Repository), nil
 }
 
-// Handler is here to handle generic, conversation-related tasks.
-// It is the object that will contain the conversation logic for
-// each platform.
-//
-// It makes the link between the application and the underlying
-// data access layers.
-type Handler interface {
-	// MessageReceived is the main entry point when a new message is
-	// received from any given user / channel / whatsoever.
-	// It handles the whole conversation logic for a given platform.
-	MessageReceived(r *http.Request)
-}
-
 // Repository is the main interface for accessing conversation-related objects
 type Repository interface {
+	// FindLatestConversation finds the latest conversation of the user,
+	// including the conversations of the users merged into it.
 	FindLatestConversation(user *User) (*Conversation, error)
 	SaveConversation(conversation *Conversation) error
-	FindUserByFbId(fbId string) (*User, error)
+	FindUserById(id bson.ObjectId) (*User, error)
+	// FindUserByIdentity finds the user owning the identity, without following the merges
+	FindUserByIdentity(platform, externalId string) (*User, error)
 	InsertUser(user *User) error
+	UpdateUser(user *User) error
+	InsertLinkCode(linkCode *LinkCode) error
+	// ConsumeLinkCode finds and deletes the link code, so that it can only be used once
+	ConsumeLinkCode(code string) (*LinkCode, error)
 }
 
 // MessageWithType is the struct grouping a message along with its type.
@@ -80,18 +75,23 @@ type MessageWithType struct {
 // @todo: manage the internal state (current step, status, etc.)
 // Conversation is the struct that will handle our conversations between
 // the bot and the various users.
+// The conversation stores the id of its user, so that it can be found before any message is added.
 type Conversation struct {
 	Id          bson.ObjectId      `bson:"_id"`
+	UserId      bson.ObjectId      `bson:"user_id,omitempty"`
 	Status      Status             `bson:"status"`
 	CurrentStep string             `bson:"step"`
 	Messages    []*MessageWithType `bson:"messages"`
-	CreatedAt   time.Time          `bson:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at"`
+	// Disambiguation is the question the user is asked to answer, if any
+	Disambiguation *Disambiguation `bson:"disambiguation,omitempty"`
+	CreatedAt      time.Time       `bson:"created_at"`
+	UpdatedAt      time.Time       `bson:"updated_at"`
 }
 
-// CreateNewConversation initialises a new conversation
-func CreateNewConversation() *Conversation {
+// CreateNewConversation initialises a new conversation with the user
+func CreateNewConversation(userId bson.ObjectId) *Conversation {
 	return &Conversation{
+		UserId:      userId,
 		Status:      StatusOngoing,
 		CurrentStep: "",
 		Messages:    nil,
@@ -108,6 +108,47 @@ func (conversation *Conversation) AddMessage(message *UserMessage) {
 	)
 }
 
+// LastUserMessageAt returns the time of the latest message sent by the user from the identity,
+// as the platforms' messaging windows only count the messages sent on the platform.
+// Returns a zero time if the user did not send any message from the identity.
+func (conversation *Conversation) LastUserMessageAt(platform, externalId string) time.Time {
+	var last time.Time
+
+	for _, m := range conversation.Messages {
+		userMessage, ok := m.Message.(*UserMessage)
+
+		if !ok || !userMessage.IsSentFrom(platform, externalId) {
+			continue
+		}
+
+		if sentAt := userMessage.SentAt(); sentAt.After(last) {
+			last = sentAt
+		}
+	}
+
+	return last
+}
+
+// Slots returns the entities collected from the user's messages, by name.
+// When an entity was given more than once, the latest value is kept.
+func (conversation *Conversation) Slots() map[string]*nlp.ParsedEntity {
+	slots := make(map[string]*nlp.ParsedEntity)
+
+	for _, m := range conversation.Messages {
+		userMessage, ok := m.Message.(*UserMessage)
+
+		if !ok || userMessage.ParsedData == nil {
+			continue
+		}
+
+		for _, entity := range userMessage.ParsedData.Entities {
+			slots[entity.Entity.Name] = entity
+		}
+	}
+
+	return slots
+}
+
 // IsNew tells us if the conversation is a new one
 func (conversation *Conversation) IsNew() bool {
 	return len(conversation.Messages) == 0
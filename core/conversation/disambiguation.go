@@ -0,0 +1,114 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/nlp"
+)
+
+const (
+	disambiguationText = "Did you mean %s or %s?"
+	// intentPayloadPrefix prefixes the payloads of the quick replies choosing an intent
+	intentPayloadPrefix = "intent:"
+)
+
+// Disambiguation is a question asked to the user, when the NLP data did not tell which
+// one of its candidate intents the user meant. It is kept within the conversation until answered.
+type Disambiguation struct {
+	// Candidates are the names of the intents the user can choose from
+	Candidates []string `bson:"candidates"`
+	// Data is the ambiguous NLP data, used once the user chose the intent
+	Data *nlp.ParsedData `bson:"data"`
+}
+
+// NewDisambiguation is the constructor method for Disambiguation
+func NewDisambiguation(data *nlp.ParsedData, candidates ...string) *Disambiguation {
+	return &Disambiguation{
+		Candidates: candidates,
+		Data:       data,
+	}
+}
+
+// Question returns the message asking the user to choose an intent, with a quick reply per intent
+func (d *Disambiguation) Question() *OutboundMessage {
+	var labels []string
+	var quickReplies []*QuickReply
+
+	for _, candidate := range d.Candidates {
+		label := intentLabel(candidate)
+		labels = append(labels, fmt.Sprintf("\"%s\"", label))
+		quickReplies = append(quickReplies, &QuickReply{
+			Title:   label,
+			Payload: intentPayloadPrefix + candidate,
+		})
+	}
+
+	return &OutboundMessage{
+		Text:         fmt.Sprintf(disambiguationText, strings.Join(labels[:len(labels)-1], ", "), labels[len(labels)-1]),
+		QuickReplies: quickReplies,
+	}
+}
+
+// Resolve returns the NLP data to use given the user's answer. The user chooses an intent using
+// the quick replies, by typing its label, or by sending a text understood as one of the candidates.
+// The ambiguous data is then used with the chosen intent, along with the answer's entities.
+// Returns the answer's data if the user did not choose any of the candidates.
+func (d *Disambiguation) Resolve(in *InboundMessage, answer *nlp.ParsedData) *nlp.ParsedData {
+	chosen := d.choose(in, answer)
+
+	if chosen == "" {
+		return answer
+	}
+
+	intent := nlp.NewParsedIntent(chosen, 1)
+	entities := append([]*nlp.ParsedEntity{}, d.Data.Entities...)
+
+	if answer != nil {
+		entities = append(entities, answer.Entities...)
+	}
+
+	resolved := nlp.NewParsedData(intent, entities)
+	resolved.Intents = []*nlp.ParsedIntent{intent}
+
+	return resolved
+}
+
+// choose returns the name of the intent chosen by the user, or an empty string if there is none
+func (d *Disambiguation) choose(in *InboundMessage, answer *nlp.ParsedData) string {
+	text := strings.TrimSpace(in.Text)
+
+	for _, candidate := range d.Candidates {
+		if in.QuickReplyPayload == intentPayloadPrefix+candidate {
+			return candidate
+		}
+
+		if strings.EqualFold(text, intentLabel(candidate)) || strings.EqualFold(text, candidate) {
+			return candidate
+		}
+	}
+
+	if answer == nil || answer.Intent == nil {
+		return ""
+	}
+
+	for _, candidate := range d.Candidates {
+		if answer.Intent.Intent.Name == candidate {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// intentLabel returns a label of the intent that can be shown to the user,
+// such as "Book table" for "book_table"
+func intentLabel(name string) string {
+	label := strings.TrimSpace(strings.Replace(name, "_", " ", -1))
+
+	if label == "" {
+		return name
+	}
+
+	return strings.ToUpper(label[:1]) + label[1:]
+}
@@ -0,0 +1,694 @@
+package conversation
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	accountsLinkedText  = "Your accounts are now linked."
+	invalidLinkCodeText = "This code is invalid or has expired."
+	// tooManyLinkAttemptsText is sent when the user tried too many invalid codes
+	tooManyLinkAttemptsText = "Too many invalid codes. Please try again later."
+)
+
+var (
+	ErrCannotStartStory    = errors.New("Cannot start a story")
+	ErrCannotProgressStory = errors.New("Cannot progress in the current story")
+	ErrCannotLoadStories   = errors.New("Cannot load stories")
+	ErrStepNotFound        = errors.New("The conversation's current step does not exist in the stories")
+	ErrCannotProcessStep   = errors.New("Cannot process the step")
+)
+
+// InboundMessage is a message received from a user, as normalized by
+// the platforms. This is what the Engine takes as an input.
+type InboundMessage struct {
+	// Platform is the name of the platform the message was received on
+	Platform string
+	// SenderId is the id of the user on the platform
+	SenderId          string
+	Text              string
+	QuickReplyPayload string
+	// Postback is the payload of a button the user pressed, such as Messenger's Get Started button.
+	// It is the name of the intent to act on, which can be prefixed by "intent:".
+	Postback string
+	SentAt   time.Time
+	// Timezone is the user's timezone, such as "Europe/Paris", when the platform knows it
+	Timezone string
+	// Location is set when the user shared a location
+	Location *Location
+	// Nlp contains the NLP data, when it is already parsed by the platform
+	Nlp []byte
+	// Silent tells the engine to record the message without answering it,
+	// for example when a human is answering the user instead of the bot.
+	Silent bool
+}
+
+// Location represents a location shared by the user
+type Location struct {
+	Latitude  float64 `bson:"latitude"`
+	Longitude float64 `bson:"longitude"`
+}
+
+// OutboundMessage is a message to send to a user. This is what the Engine
+// outputs, and it is up to the platforms to translate it.
+type OutboundMessage struct {
+	Text         string
+	QuickReplies []*QuickReply
+}
+
+// QuickReply is a suggested answer the user can choose
+type QuickReply struct {
+	Title   string
+	Payload string
+}
+
+// NewTextMessage is the constructor method for an OutboundMessage containing text only
+func NewTextMessage(text string) *OutboundMessage {
+	return &OutboundMessage{
+		Text: text,
+	}
+}
+
+// Engine is the platform-agnostic conversation engine. It drives the stories
+// using the messages received from the users, and returns the messages to send back.
+//
+// Platforms are thin adapters around it: they parse their webhooks into
+// InboundMessages and send the OutboundMessages back to their users.
+type Engine struct {
+	stepHandler            *StepHandler
+	identities             *IdentityManager
+	conversationRepository Repository
+	storyRepository        StoryRepository
+	nlpParser              nlp.Parser
+	textParser             nlp.TextParser
+	validators             nlp.Validators
+	reviewQueue            *nlp.ReviewQueue
+	// timezone is the timezone of the bot's users, used when a user's timezone is unknown
+	timezone string
+}
+
+// NewEngine is the constructor method for Engine
+func NewEngine(pm StepsProcessMap, cr Repository, sr StoryRepository, p nlp.Parser) *Engine {
+	return &Engine{
+		stepHandler:            NewStepHandler(pm),
+		identities:             NewIdentityManager(cr),
+		conversationRepository: cr,
+		storyRepository:        sr,
+		nlpParser:              p,
+	}
+}
+
+// SetIdentities sets the identity manager used to find the users and to link their accounts.
+// It must be shared by the engines of all of the bots, so that the link attempts of a user
+// are limited across bots. The engine uses its own identity manager until then.
+func (e *Engine) SetIdentities(m *IdentityManager) {
+	e.identities = m
+}
+
+// SetTextParser sets the parser used to parse the messages' text, when the
+// platform does not provide already parsed NLP data. It can be an nlp.Api,
+// sending the text to the NLP service.
+func (e *Engine) SetTextParser(p nlp.TextParser) {
+	e.textParser = p
+}
+
+// SetThresholds sets the minimum confidences the NLP data must reach for the bot to act on it.
+// The default thresholds are used until then.
+func (e *Engine) SetThresholds(thresholds *Thresholds) {
+	e.stepHandler.SetThresholds(thresholds)
+}
+
+// SetValidators sets the validators of the entities' values. The invalid values are not used,
+// and the user is asked for another value when the conversation cannot go on without them.
+func (e *Engine) SetValidators(validators nlp.Validators) {
+	e.validators = validators
+}
+
+// SetTimezone sets the timezone of the bot's users, such as "Europe/Paris", used to understand
+// the dates and times sent by the users whose timezone is unknown.
+// Returns an error if the timezone is unknown.
+func (e *Engine) SetTimezone(timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return err
+	}
+
+	e.timezone = timezone
+
+	return nil
+}
+
+// SetReviewQueue sets the queue where the messages the NLP service did not understand well enough
+// are put, for an operator to label them. No message is put in a queue until then.
+func (e *Engine) SetReviewQueue(queue *nlp.ReviewQueue) {
+	e.reviewQueue = queue
+}
+
+// Handle is the main entry point when a new message is received from any given user / platform.
+// It handles the whole conversation logic:
+//
+// - Finding the user and the conversation
+// - Parsing NLP
+// - Managing the conversation flow
+// - Modifying the conversation's status
+//
+// It returns the messages to send back to the user. No message is returned while
+// a human is answering the user instead of the bot.
+//
+// Users can link their accounts by sending "/link <code>", using a code created on
+// another platform (see IdentityManager.CreateLinkCode), unless a human is answering them.
+//
+// When the user's intent is ambiguous, the user is asked to choose between the most
+// confident intents, and the answer is used to resolve the ambiguous message.
+func (e *Engine) Handle(in *InboundMessage) ([]*OutboundMessage, error) {
+	user, err := e.identities.GetOrCreateUser(in.Platform, in.SenderId)
+
+	if err != nil {
+		log.WithField("user", in.SenderId).Errorf("Could not find the user: %s", err)
+		return nil, err
+	}
+
+	e.updateTimezone(user, in.Timezone)
+
+	c, err := e.getConversation(user)
+
+	if err != nil {
+		log.WithField("user", user).Infof("Could not get / create conversation: %s", err)
+		return nil, err
+	}
+
+	log.WithField("conversation", c).Debug("Conversation fetched")
+
+	userMessage := NewUserMessage(
+		in.Text,
+		in.SentAt,
+		user,
+		nil,
+	)
+	userMessage.Location = in.Location
+	userMessage.Platform = in.Platform
+	userMessage.ExternalId = in.SenderId
+
+	c.AddMessage(userMessage)
+
+	e.conversationRepository.SaveConversation(c)
+
+	if in.Silent || c.Status == StatusHumanIntervention {
+		log.WithField("conversation", c).Debug("A human owns the conversation: staying silent")
+		return nil, nil
+	}
+
+	if code := parseLinkCommand(in.Text); code != "" {
+		return e.link(in, code)
+	}
+
+	parsedData, err := e.understand(in, c, user)
+
+	if err != nil {
+		// @todo: handle this case and return something to the user. Make sure the
+		// conversation is saved with the message. For example, we could think
+		// about adding a flag to the message, like:
+		// - could_not_parse_nlp
+		// - could_not_process
+		// - something_else
+		// - ...
+		// => gives more context and allows us to save data & understand it even
+		// though errors occur.
+		log.WithField("nlp", string(in.Nlp)).Errorf("Could not parse NLP data: %s", err)
+		return nil, err
+	}
+
+	e.review(in, c, parsedData)
+
+	if parsedData == nil {
+		// @todo: handle this case
+		log.Errorf("No data to parse")
+		return nil, nil
+	}
+
+	parsedData.TrimIntents(e.stepHandler.thresholds.Candidates)
+	invalid := e.validators.Validate(parsedData)
+	userMessage.ParsedData = parsedData
+
+	log.WithField("data", parsedData).Debug("Data parsed from message")
+
+	e.conversationRepository.SaveConversation(c)
+
+	messages, err := e.processData(parsedData, c)
+
+	if (err == ErrCannotStartStory || err == ErrCannotProgressStory) && len(invalid) > 0 {
+		log.WithField("errors", invalid).Debug("Invalid values: asking for other ones")
+		return reprompt(invalid), nil
+	}
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"data":         parsedData,
+			"conversation": c,
+		}).Errorf("Could not process the data: %s", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// SetStatus sets the status of the user's current conversation and saves it.
+// For example, StatusHumanIntervention makes the bot stay silent until
+// the status is set back to StatusOngoing.
+func (e *Engine) SetStatus(platform, senderId string, status Status) error {
+	user, err := e.identities.GetOrCreateUser(platform, senderId)
+
+	if err != nil {
+		return err
+	}
+
+	c, err := e.getConversation(user)
+
+	if err != nil {
+		return err
+	}
+
+	c.Status = status
+
+	return e.conversationRepository.SaveConversation(c)
+}
+
+// LatestConversation returns the user's latest conversation, which can be over.
+// Returns ErrNotFound if the user or the conversation does not exist.
+func (e *Engine) LatestConversation(platform, senderId string) (*Conversation, error) {
+	user, err := e.identities.FindUser(platform, senderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.conversationRepository.FindLatestConversation(user)
+}
+
+// LastUserMessageAt returns the time of the latest message sent by the user from the identity,
+// ignoring the messages sent from the user's linked identities.
+// Returns a zero time if the user never sent any message, and ErrNotFound
+// if the user does not exist.
+func (e *Engine) LastUserMessageAt(platform, senderId string) (time.Time, error) {
+	user, err := e.identities.FindUser(platform, senderId)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c, err := e.conversationRepository.FindLatestConversation(user)
+
+	if err != nil {
+		if err == ErrNotFound {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return c.LastUserMessageAt(platform, senderId), nil
+}
+
+// Identities returns the IdentityManager used to find the users, so that the platforms
+// can link the users' accounts.
+func (e *Engine) Identities() *IdentityManager {
+	return e.identities
+}
+
+// link links the sender's identity to the user who created the code, and confirms it to the user
+func (e *Engine) link(in *InboundMessage, code string) ([]*OutboundMessage, error) {
+	_, err := e.identities.LinkWithCode(in.Platform, in.SenderId, code)
+
+	if err == ErrInvalidLinkCode {
+		return []*OutboundMessage{NewTextMessage(invalidLinkCodeText)}, nil
+	}
+
+	if err == ErrTooManyLinkAttempts {
+		return []*OutboundMessage{NewTextMessage(tooManyLinkAttemptsText)}, nil
+	}
+
+	if err != nil {
+		log.WithField("user", in.SenderId).Errorf("Could not link the user: %s", err)
+		return nil, err
+	}
+
+	return []*OutboundMessage{NewTextMessage(accountsLinkedText)}, nil
+}
+
+// understand returns the message's NLP data. When the user was asked to choose an intent,
+// the answer resolves the ambiguous data: quick replies and labels do not need to be parsed.
+func (e *Engine) understand(in *InboundMessage, c *Conversation, user *User) (*nlp.ParsedData, error) {
+	disambiguation := c.Disambiguation
+	c.Disambiguation = nil
+
+	if disambiguation != nil {
+		if resolved := disambiguation.Resolve(in, nil); resolved != nil {
+			log.WithField("data", resolved).Debug("Intent chosen by the user")
+			return resolved, nil
+		}
+	}
+
+	data, err := e.parse(in, c, user)
+
+	if err != nil || disambiguation == nil {
+		return data, err
+	}
+
+	return disambiguation.Resolve(in, data), nil
+}
+
+// parse returns the message's NLP data: the intent of the postback, the data provided by the
+// platform when there is some, or the data parsed from the message's text otherwise.
+// The conversation's id is used as the NLP session id, and the dates and times are understood
+// in the user's timezone, or in the bot's timezone when the user's one is unknown.
+// Returns nil if there is no data to parse.
+func (e *Engine) parse(in *InboundMessage, c *Conversation, user *User) (*nlp.ParsedData, error) {
+	if in.Postback != "" {
+		intent := nlp.NewParsedIntent(strings.TrimPrefix(in.Postback, intentPayloadPrefix), 1)
+		data := nlp.NewParsedData(intent, nil)
+		data.Intents = []*nlp.ParsedIntent{intent}
+
+		return data, nil
+	}
+
+	if in.Nlp != nil {
+		return e.nlpParser.ParseNlpData(in.Nlp)
+	}
+
+	if e.textParser == nil || in.Text == "" {
+		return nil, nil
+	}
+
+	context := nlp.NewContext(in.SentAt)
+	context.SessionId = c.Id.Hex()
+	context.Timezone = user.Timezone
+
+	if context.Timezone == "" {
+		context.Timezone = e.timezone
+	}
+
+	return e.textParser.Parse(in.Text, context)
+}
+
+// updateTimezone stores the user's timezone, when sent by the platform and valid
+func (e *Engine) updateTimezone(user *User, timezone string) {
+	if timezone == "" || timezone == user.Timezone {
+		return
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		log.WithField("timezone", timezone).Infof("Ignoring the user's timezone: %s", err)
+		return
+	}
+
+	user.Timezone = timezone
+
+	if err := e.identities.repository.UpdateUser(user); err != nil {
+		log.WithField("user", user.Id).Errorf("Could not update the user's timezone: %s", err)
+	}
+}
+
+// processData is the method responsible for taking actions on a conversation using the provided NLP data.
+// It returns the messages to send back to the user.
+func (e *Engine) processData(data *nlp.ParsedData, c *Conversation) ([]*OutboundMessage, error) {
+	var messages []*OutboundMessage
+	var err error
+
+	if c.CurrentStep == "" {
+		log.WithField("c", c).Info("Try starting a new story")
+		messages, err = e.tryStartStory(data, c)
+	} else {
+		log.WithField("c", c).Info("Try progressing in the current story")
+		messages, err = e.tryProgressInStory(data, c)
+	}
+
+	if err != nil {
+		// @todo: handle: save the user message here?
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// tryStartStory will try to start a new story using the provided NLP data.
+// It will go through the available stories and see if any step can be initiated.
+func (e *Engine) tryStartStory(data *nlp.ParsedData, c *Conversation) ([]*OutboundMessage, error) {
+	stories, err := e.storyRepository.FindAll()
+
+	if err != nil {
+		log.Error("Could not load stories")
+		return nil, err
+	}
+
+	var startingSteps []*Step
+
+	for _, story := range stories {
+		startingSteps = append(startingSteps, story.StartingSteps...)
+	}
+
+	if messages := e.disambiguate(startingSteps, data, c); messages != nil {
+		return messages, nil
+	}
+
+	startingStep := e.findStep(startingSteps, data)
+
+	if startingStep == nil {
+		log.WithFields(log.Fields{
+			"data":         data,
+			"conversation": c,
+		}).Info("Cannot start a story")
+
+		// @todo: handle this. Don't forget to save the conversation with the message
+		return nil, ErrCannotStartStory
+	}
+
+	return e.processStep(c, startingStep, data)
+}
+
+// tryProgressInStory is the method being called when a conversation is ongoing and we try to progress
+// within the current story.
+func (e *Engine) tryProgressInStory(data *nlp.ParsedData, c *Conversation) ([]*OutboundMessage, error) {
+	stories, err := e.storyRepository.FindAll()
+
+	if err != nil {
+		// @todo: log, and save conversation
+		return nil, ErrCannotLoadStories
+	}
+
+	var currentStep *Step
+
+	// Find the current step of the conversation
+	for _, story := range stories {
+		step := story.FindStep(c.CurrentStep)
+
+		if step != nil {
+			currentStep = step
+			break
+		}
+	}
+
+	if currentStep == nil {
+		log.WithFields(log.Fields{
+			"data":         data,
+			"conversation": c,
+		}).Error("The conversation's current step does not exist in the stories")
+
+		// @todo: handle this case and see how we can prevent
+		// a conversation from being blocked.
+		return nil, ErrStepNotFound
+	}
+
+	if messages := e.disambiguate(currentStep.NextSteps, data, c); messages != nil {
+		return messages, nil
+	}
+
+	nextStep := e.findStep(currentStep.NextSteps, data)
+
+	if nextStep == nil {
+		log.WithFields(log.Fields{
+			"data":         data,
+			"conversation": c,
+		}).Info("Cannot progress in story")
+
+		// @todo: handle this. Don't forget to save the conversation with the message
+		return nil, ErrCannotProgressStory
+	}
+
+	return e.processStep(c, nextStep, data)
+}
+
+// review puts the message in the review queue when it was not understood well enough.
+// The quick replies and postbacks are not reviewed, as their text is not typed by the user.
+func (e *Engine) review(in *InboundMessage, c *Conversation, data *nlp.ParsedData) {
+	if e.reviewQueue == nil || in.QuickReplyPayload != "" || in.Postback != "" {
+		return
+	}
+
+	item, err := e.reviewQueue.Capture(in.Text, c.Id, data, e.stepHandler.thresholds.Intent)
+
+	if err != nil {
+		log.WithField("conversation", c.Id).Errorf("Could not put the message in the review queue: %s", err)
+		return
+	}
+
+	if item != nil {
+		log.WithField("reason", item.Reason).Debug("Message put in the review queue")
+	}
+}
+
+// reprompt returns the messages asking the user for other values, given the validation errors.
+// The valid values are suggested as quick replies when there are only a few of them.
+func reprompt(errs []*nlp.ValidationError) []*OutboundMessage {
+	var messages []*OutboundMessage
+
+	for _, err := range errs {
+		message := NewTextMessage(err.Message)
+
+		for _, option := range err.Options {
+			message.QuickReplies = append(message.QuickReplies, &QuickReply{
+				Title:   option,
+				Payload: option,
+			})
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// findStep returns the first of the steps the data allows to step in.
+// Returns nil if there is none.
+func (e *Engine) findStep(steps []*Step, data *nlp.ParsedData) *Step {
+	for _, step := range steps {
+		if e.stepHandler.CanStepIn(step, data) {
+			log.WithField("step", step).Debugf("Stepping in")
+			return step
+		}
+	}
+
+	return nil
+}
+
+// disambiguate asks the user to choose an intent when the two most confident candidates are too
+// close to tell, and each of them is confident enough to step in one of the steps expecting it.
+// The question is kept within the conversation until the user answers it.
+// Returns nil if the intent is not ambiguous.
+func (e *Engine) disambiguate(steps []*Step, data *nlp.ParsedData, c *Conversation) []*OutboundMessage {
+	if len(data.Intents) < 2 {
+		return nil
+	}
+
+	first, second := data.Intents[0], data.Intents[1]
+
+	if first.Intent.Name == second.Intent.Name {
+		return nil
+	}
+
+	gap := first.Confidence - second.Confidence
+
+	for _, candidate := range []*nlp.ParsedIntent{first, second} {
+		if !e.isAmbiguousCandidate(steps, candidate, data.Entities, gap) {
+			return nil
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"data":         data,
+		"conversation": c,
+	}).Info("Ambiguous intent: asking the user")
+
+	c.Disambiguation = NewDisambiguation(data, first.Intent.Name, second.Intent.Name)
+	e.conversationRepository.SaveConversation(c)
+
+	return []*OutboundMessage{c.Disambiguation.Question()}
+}
+
+// isAmbiguousCandidate returns whether the candidate intent allows to step in one of the steps
+// expecting it, and the confidence gap with the other candidate is under the step's ambiguity threshold.
+// The steps expecting no intent are not considered, as the candidate does not tell them apart.
+func (e *Engine) isAmbiguousCandidate(steps []*Step, candidate *nlp.ParsedIntent, entities []*nlp.ParsedEntity, gap float32) bool {
+	candidateData := nlp.NewParsedData(candidate, entities)
+
+	for _, step := range steps {
+		if step.ExpectedIntent != candidate.Intent.Name {
+			continue
+		}
+
+		thresholds := e.stepHandler.Thresholds(step)
+
+		if thresholds.Ambiguity <= 0 || gap >= thresholds.Ambiguity || candidate.Confidence < thresholds.Intent {
+			continue
+		}
+
+		if e.stepHandler.CanStepIn(step, candidateData) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// processStep processes a single step, according to the fact that we should
+// be able, at that stage, to step in the step.
+//
+// So make sure to call step.CanStepIn and that the result is true
+// before calling this method.
+func (e *Engine) processStep(c *Conversation, s *Step, data *nlp.ParsedData) ([]*OutboundMessage, error) {
+	// Process the step
+	log.WithFields(log.Fields{
+		"step": s,
+		"data": data,
+	}).Info("Processing step")
+
+	messages, err := e.stepHandler.Process(s, data)
+
+	if err != nil {
+		log.Errorf("Could not process the step: %s", err)
+		// @todo: handle this, and save the conversation's message otherwise it's lost
+		return nil, ErrCannotProcessStep
+	}
+
+	// Update the conversation's state
+	c.CurrentStep = s.Name
+
+	if s.IsLastStep() {
+		c.Status = StatusOver
+		log.WithField("conversation", c).Info("Terminating conversation")
+	}
+
+	e.conversationRepository.SaveConversation(c)
+
+	return messages, nil
+}
+
+// getConversation tries to return a conversation between a given user and the bot.
+// If there is an ongoing conversation, then it will return it.
+// If this is the first conversation or the previous one is marked as done, then it will create a new one.
+func (e *Engine) getConversation(user *User) (*Conversation, error) {
+	c, err := e.conversationRepository.FindLatestConversation(user)
+
+	if err != nil {
+		if err != ErrNotFound {
+			return nil, err
+		}
+
+		log.WithField("user", user).Info("Starting a first conversation")
+
+		// The conversation was not found: start a new one
+		c = CreateNewConversation(user.Id)
+	}
+
+	// Start a new conversation if the previous one is over
+	if c.Status == StatusOver {
+		log.WithField("user", user).Info("Starting a new conversation")
+
+		c = CreateNewConversation(user.Id)
+	}
+
+	return c, nil
+}
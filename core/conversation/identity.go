@@ -0,0 +1,398 @@
+package conversation
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// linkCodeTtl is the time during which a link code can be used
+	linkCodeTtl = 10 * time.Minute
+	// linkCodeLength is the number of characters of a link code
+	linkCodeLength = 8
+	// linkCodeAlphabet are the characters of the link codes, without the ones that look alike
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// maxLinkAttempts is the number of invalid codes an identity can send within linkCodeTtl
+	maxLinkAttempts = 5
+	// maxMergeDepth is the maximum number of merged users followed when resolving a user
+	maxMergeDepth = 10
+)
+
+var (
+	ErrInvalidLinkCode     = errors.New("Invalid or expired link code")
+	ErrTooManyLinkAttempts = errors.New("Too many invalid link codes")
+	ErrAlreadyMerged       = errors.New("The user is already merged into another user")
+	ErrNotMerged           = errors.New("The user is not merged into another user")
+	ErrSameUser            = errors.New("Cannot merge a user into itself")
+
+	// linkCommandPattern matches the messages sent by the users to link their accounts, such as "/link K7MQ2XPA".
+	// The codes are matched whatever their case.
+	linkCommandPattern = regexp.MustCompile(`^\s*/link\s+([A-Za-z0-9]{8})\s*$`)
+)
+
+// LinkCode is a one-time code used to link a user's identities. The code is created for the user
+// on one platform, and sent by the user on another platform.
+type LinkCode struct {
+	Code      string        `json:"code" bson:"_id"`
+	UserId    bson.ObjectId `json:"user_id" bson:"user_id"`
+	ExpiresAt time.Time     `json:"expires_at" bson:"expires_at"`
+}
+
+// linkAttempts are the invalid codes sent by an identity, since the first one
+type linkAttempts struct {
+	count int
+	since time.Time
+}
+
+// IdentityManager is responsible for finding the users from their identities, and for
+// linking, merging and unmerging users.
+type IdentityManager struct {
+	repository Repository
+	// attempts are the invalid codes sent by the identities, by identity, so that the codes
+	// cannot be guessed by trying them all
+	mutex    sync.Mutex
+	attempts map[string]*linkAttempts
+}
+
+// NewIdentityManager is the constructor method for IdentityManager
+func NewIdentityManager(r Repository) *IdentityManager {
+	return &IdentityManager{
+		repository: r,
+		attempts:   make(map[string]*linkAttempts),
+	}
+}
+
+// FindUser finds the user owning the identity. If the user was merged into another
+// user, then the user it was merged into is returned.
+// Returns ErrNotFound if no user owns the identity.
+func (m *IdentityManager) FindUser(platform, externalId string) (*User, error) {
+	user, err := m.repository.FindUserByIdentity(platform, externalId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m.resolve(user)
+}
+
+// GetOrCreateUser finds the user owning the identity, creating a new user if needed
+func (m *IdentityManager) GetOrCreateUser(platform, externalId string) (*User, error) {
+	user, err := m.FindUser(platform, externalId)
+
+	if err == nil {
+		return user, nil
+	}
+
+	if err != ErrNotFound {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"platform": platform,
+		"id":       externalId,
+	}).Infof("Inserting a new user")
+
+	user = NewUser(platform, externalId)
+
+	err = m.repository.InsertUser(user)
+
+	if err != nil {
+		log.WithField("id", externalId).Info("Could not insert the user")
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateLinkCode creates a one-time code for the user owning the identity. The code can
+// then be sent by the user on another platform in order to link both identities.
+func (m *IdentityManager) CreateLinkCode(platform, externalId string) (*LinkCode, error) {
+	user, err := m.GetOrCreateUser(platform, externalId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := randomCode(linkCodeLength)
+
+	if err != nil {
+		return nil, err
+	}
+
+	linkCode := &LinkCode{
+		Code:      code,
+		UserId:    user.Id,
+		ExpiresAt: time.Now().Add(linkCodeTtl),
+	}
+
+	err = m.repository.InsertLinkCode(linkCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return linkCode, nil
+}
+
+// LinkWithCode links the identity to the user who created the code: the user owning
+// the identity is merged into the code's user. The code can only be used once.
+// Returns ErrInvalidLinkCode if the code does not exist or has expired, and ErrTooManyLinkAttempts
+// if the identity sent maxLinkAttempts invalid codes within linkCodeTtl.
+func (m *IdentityManager) LinkWithCode(platform, externalId, code string) (*User, error) {
+	identity := platform + "/" + externalId
+
+	if !m.canAttemptLink(identity) {
+		log.WithFields(log.Fields{
+			"platform": platform,
+			"id":       externalId,
+		}).Info("Too many invalid link codes")
+		return nil, ErrTooManyLinkAttempts
+	}
+
+	linkCode, err := m.repository.ConsumeLinkCode(strings.ToUpper(code))
+
+	if err == ErrNotFound {
+		m.addLinkAttempt(identity)
+		return nil, ErrInvalidLinkCode
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(linkCode.ExpiresAt) {
+		m.addLinkAttempt(identity)
+		return nil, ErrInvalidLinkCode
+	}
+
+	target, err := m.repository.FindUserById(linkCode.UserId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	target, err = m.resolve(target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := m.GetOrCreateUser(platform, externalId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The identities are already linked
+	if source.Id == target.Id {
+		return target, nil
+	}
+
+	return m.Merge(target.Id, source.Id)
+}
+
+// canAttemptLink returns whether the identity can send another link code
+func (m *IdentityManager) canAttemptLink(identity string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	attempts, ok := m.attempts[identity]
+
+	return !ok || attempts.count < maxLinkAttempts || time.Since(attempts.since) > linkCodeTtl
+}
+
+// addLinkAttempt counts an invalid code sent by the identity. The attempts older than
+// linkCodeTtl are forgotten.
+func (m *IdentityManager) addLinkAttempt(identity string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+
+	for key, attempts := range m.attempts {
+		if now.Sub(attempts.since) > linkCodeTtl {
+			delete(m.attempts, key)
+		}
+	}
+
+	if attempts, ok := m.attempts[identity]; ok {
+		attempts.count++
+		return
+	}
+
+	m.attempts[identity] = &linkAttempts{
+		count: 1,
+		since: now,
+	}
+}
+
+// Merge merges the source user into the target user. The source user's conversations
+// and identities then belong to the target user.
+// Returns ErrAlreadyMerged if any of the users was already merged into another user.
+func (m *IdentityManager) Merge(targetId, sourceId bson.ObjectId) (*User, error) {
+	if targetId == sourceId {
+		return nil, ErrSameUser
+	}
+
+	target, err := m.repository.FindUserById(targetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := m.repository.FindUserById(sourceId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if target.IsMerged() || source.IsMerged() {
+		return nil, ErrAlreadyMerged
+	}
+
+	// The target cannot be one of the users merged into the source
+	if containsId(source.MergedUserIds, target.Id) {
+		return nil, ErrAlreadyMerged
+	}
+
+	source.MergedInto = target.Id
+	target.MergedUserIds = append(target.MergedUserIds, source.Ids()...)
+
+	err = m.repository.UpdateUser(source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.repository.UpdateUser(target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"target": target.Id,
+		"source": source.Id,
+	}).Info("Users merged")
+
+	return target, nil
+}
+
+// Unmerge undoes the merge of the user: the user, along with the users that were merged
+// into it, gets its identities and conversations back.
+// Returns ErrNotMerged if the user was not merged into another user.
+func (m *IdentityManager) Unmerge(userId bson.ObjectId) (*User, error) {
+	user, err := m.repository.FindUserById(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsMerged() {
+		return nil, ErrNotMerged
+	}
+
+	ids := user.Ids()
+
+	// Every user the user was merged into, directly or not, holds its ids
+	parentId := user.MergedInto
+
+	for depth := 0; parentId != "" && depth < maxMergeDepth; depth++ {
+		parent, err := m.repository.FindUserById(parentId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		parent.removeMergedUserIds(ids)
+
+		err = m.repository.UpdateUser(parent)
+
+		if err != nil {
+			return nil, err
+		}
+
+		parentId = parent.MergedInto
+	}
+
+	user.MergedInto = ""
+
+	err = m.repository.UpdateUser(user)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("user", user.Id).Info("User unmerged")
+
+	return user, nil
+}
+
+// UnlinkIdentity undoes the merge of the user owning the identity, when it was linked to another user
+func (m *IdentityManager) UnlinkIdentity(platform, externalId string) (*User, error) {
+	user, err := m.repository.FindUserByIdentity(platform, externalId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Unmerge(user.Id)
+}
+
+// resolve follows the merges of the user and returns the user it was eventually merged into
+func (m *IdentityManager) resolve(user *User) (*User, error) {
+	for depth := 0; user.IsMerged(); depth++ {
+		if depth >= maxMergeDepth {
+			log.WithField("user", user.Id).Error("Too many merged users to follow")
+			return user, nil
+		}
+
+		parent, err := m.repository.FindUserById(user.MergedInto)
+
+		if err != nil {
+			return nil, err
+		}
+
+		user = parent
+	}
+
+	return user, nil
+}
+
+// parseLinkCommand returns the code of a link command, such as "/link K7MQ2XPA".
+// Returns an empty string if the text is not a link command.
+func parseLinkCommand(text string) string {
+	matches := linkCommandPattern.FindStringSubmatch(text)
+
+	if matches == nil {
+		return ""
+	}
+
+	return matches[1]
+}
+
+// randomCode returns a random code made of n characters of the linkCodeAlphabet
+func randomCode(n int) (string, error) {
+	code := make([]byte, n)
+	max := big.NewInt(int64(len(linkCodeAlphabet)))
+
+	for i := range code {
+		index, err := rand.Int(rand.Reader, max)
+
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = linkCodeAlphabet[index.Int64()]
+	}
+
+	return string(code), nil
+}
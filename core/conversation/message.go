@@ -41,17 +41,32 @@ type UserMessage struct {
 	*message
 	Sender     bson.ObjectId   `bson:"sender_id"`
 	ParsedData *nlp.ParsedData `bson:"parsed_data"`
+	Location   *Location       `bson:"location,omitempty"`
+	// Platform and ExternalId are the identity the message was sent from, as the user's identities
+	// can be linked. They are empty for the messages received before the identities were introduced.
+	Platform   string `bson:"platform,omitempty"`
+	ExternalId string `bson:"external_id,omitempty"`
 }
 
 // NewUserMessage is the constructor method for UserMessage
 func NewUserMessage(text string, sentAt time.Time, sender *User, parsedData *nlp.ParsedData) *UserMessage {
 	return &UserMessage{
-		newMessage(text, MessageFromUser, sentAt),
-		sender.Id,
-		parsedData,
+		message:    newMessage(text, MessageFromUser, sentAt),
+		Sender:     sender.Id,
+		ParsedData: parsedData,
 	}
 }
 
+// IsSentFrom returns whether the message was sent from the identity.
+// The messages received before the identities were introduced are considered sent from any identity.
+func (msg *UserMessage) IsSentFrom(platform, externalId string) bool {
+	if msg.Platform == "" {
+		return true
+	}
+
+	return msg.Platform == platform && msg.ExternalId == externalId
+}
+
 func (msg *UserMessage) Text() string {
 	return msg.message.Text
 }
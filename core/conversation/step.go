@@ -61,8 +61,9 @@ func (s *Step) findSubStep(name string) *Step {
 	return nil
 }
 
-// StepProcessFunc is a func responsible for handling a given step
-type StepProcessFunc func(step *Step, data *nlp.ParsedData) error
+// StepProcessFunc is a func responsible for handling a given step.
+// It returns the messages to send back to the user, in order.
+type StepProcessFunc func(step *Step, data *nlp.ParsedData) ([]*OutboundMessage, error)
 
 // StepsProcessMap maps steps names to their process func
 type StepsProcessMap map[string]StepProcessFunc
@@ -70,30 +71,57 @@ type StepsProcessMap map[string]StepProcessFunc
 // StepHandler is the struct responsible for handling steps for a bot
 type StepHandler struct {
 	processMap StepsProcessMap
+	thresholds *Thresholds
 }
 
-// NewStepHandler is the constructor method for StepHandler
+// NewStepHandler is the constructor method for StepHandler.
+// The default thresholds are used until others are set.
 func NewStepHandler(processMap StepsProcessMap) *StepHandler {
 	return &StepHandler{
 		processMap: processMap,
+		thresholds: DefaultThresholds(),
 	}
 }
 
+// SetThresholds sets the bot's thresholds
+func (h *StepHandler) SetThresholds(thresholds *Thresholds) {
+	h.thresholds = thresholds
+}
+
+// Thresholds returns the thresholds used to step in the given step:
+// the bot's thresholds, overridden by the ones the bot sets for the step
+func (h *StepHandler) Thresholds(step *Step) *Thresholds {
+	return h.thresholds.ForStep(step)
+}
+
 // CanStepIn tries to see if the NLP data meets the step's requirements
 // in order to process the step. It will check if the expected intent / entities
 // are present in the NLP data, and return true or false accordingly.
 //
+// The intent and entities that are less confident than the step's thresholds are ignored.
+//
 // However, this method does not check the data itself. It only checks
 // for its presence, not its validity.
 // @todo: needs testing
 func (h *StepHandler) CanStepIn(step *Step, data *nlp.ParsedData) bool {
+	thresholds := h.Thresholds(step)
+	intent := data.Intent
+
+	if intent != nil && intent.Confidence < thresholds.Intent {
+		log.WithFields(log.Fields{
+			"intent":     intent.Intent.Name,
+			"confidence": intent.Confidence,
+		}).Debug("Ignoring the intent: not confident enough")
+		intent = nil
+	}
+
 	// Case 1: NLP data provides an intent but it's not the same name
-	if data.Intent != nil && step.ExpectedIntent != data.Intent.Intent.Name {
+	if intent != nil && step.ExpectedIntent != intent.Intent.Name {
 		return false
 	}
 
 	// Case 2: NLP data does not provide an intent but we are expecting one
-	if data.Intent == nil && step.ExpectedIntent != "" {
+	if intent == nil && step.ExpectedIntent != "" {
 		return false
 	}
 
@@ -102,7 +130,7 @@ func (h *StepHandler) CanStepIn(step *Step, data *nlp.ParsedData) bool {
 			hasEntity := false
 
 			for _, providedEntity := range data.Entities {
-				if providedEntity.Entity.Name == expectedEntity {
+				if providedEntity.Entity.Name == expectedEntity && providedEntity.Confidence >= thresholds.Entity {
 					hasEntity = true
 					log.Debugf("Has entity: %s", expectedEntity)
 				}
@@ -118,15 +146,16 @@ func (h *StepHandler) CanStepIn(step *Step, data *nlp.ParsedData) bool {
 	return true
 }
 
-// Process will process the step using its associated StepProcessFunc.
+// Process will process the step using its associated StepProcessFunc
+// and return the messages to be sent to the user.
 // Returns an error if there is no associated StepProcessFunc or
 // for any other processing reason.
-func (h *StepHandler) Process(step *Step, data *nlp.ParsedData) error {
+func (h *StepHandler) Process(step *Step, data *nlp.ParsedData) ([]*OutboundMessage, error) {
 	fn, ok := h.processMap[step.Name]
 
 	if !ok {
 		// @todo: handle this case and log
-		return errors.New("Cannot handle")
+		return nil, errors.New("Cannot handle")
 	}
 
 	return fn(step, data)
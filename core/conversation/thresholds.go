@@ -0,0 +1,105 @@
+package conversation
+
+import (
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+// Thresholds are the minimum confidences the NLP data must reach for the bot to act on it.
+// They are set per bot, and can be overridden per step.
+type Thresholds struct {
+	// Intent is the minimum confidence of an intent. Less confident intents are ignored.
+	Intent float32
+	// Entity is the minimum confidence of an entity. Less confident entities are ignored.
+	Entity float32
+	// Ambiguity is the confidence gap under which the two most confident intents are
+	// considered ambiguous, in which case the user is asked to choose. 0 never asks.
+	Ambiguity float32
+	// Candidates is the number of candidate intents kept within the NLP data. 0 keeps all of them.
+	Candidates int
+	// Steps override the thresholds for the steps, by step name. Their zero values keep these ones.
+	Steps map[string]*Thresholds
+}
+
+// DefaultThresholds returns the thresholds used when the bot does not set any
+func DefaultThresholds() *Thresholds {
+	return &Thresholds{
+		Intent:     0.5,
+		Entity:     0.5,
+		Ambiguity:  0.1,
+		Candidates: 3,
+	}
+}
+
+// NewThresholds creates thresholds from a bot's params, such as {"intent": 0.7}, along with the
+// overrides of the steps, such as {"steps": {"book_table_get_nb_persons": {"entity": 0.8}}}.
+// The missing params keep their default value.
+func NewThresholds(params map[string]interface{}) *Thresholds {
+	thresholds := DefaultThresholds()
+	thresholds.set(params)
+
+	if steps, ok := params["steps"].(map[string]interface{}); ok {
+		thresholds.Steps = make(map[string]*Thresholds)
+
+		for name, stepParams := range steps {
+			if stepParams, ok := stepParams.(map[string]interface{}); ok {
+				overrides := &Thresholds{}
+				overrides.set(stepParams)
+				thresholds.Steps[name] = overrides
+			}
+		}
+	}
+
+	return thresholds
+}
+
+// set sets the thresholds given within the params
+func (thresholds *Thresholds) set(params map[string]interface{}) {
+	if value, ok := utils.ToFloat(params["intent"]); ok {
+		thresholds.Intent = float32(value)
+	}
+
+	if value, ok := utils.ToFloat(params["entity"]); ok {
+		thresholds.Entity = float32(value)
+	}
+
+	if value, ok := utils.ToFloat(params["ambiguity"]); ok {
+		thresholds.Ambiguity = float32(value)
+	}
+
+	if value, ok := utils.ToFloat(params["candidates"]); ok {
+		thresholds.Candidates = int(value)
+	}
+}
+
+// ForStep returns the thresholds used for the step: these ones, overridden by the step's ones
+func (thresholds *Thresholds) ForStep(step *Step) *Thresholds {
+	return thresholds.override(thresholds.Steps[step.Name])
+}
+
+// override returns the thresholds, overridden by the non-zero values of the given ones.
+// Returns the thresholds themselves if there is nothing to override.
+func (thresholds *Thresholds) override(overrides *Thresholds) *Thresholds {
+	if overrides == nil {
+		return thresholds
+	}
+
+	overridden := *thresholds
+
+	if overrides.Intent != 0 {
+		overridden.Intent = overrides.Intent
+	}
+
+	if overrides.Entity != 0 {
+		overridden.Entity = overrides.Entity
+	}
+
+	if overrides.Ambiguity != 0 {
+		overridden.Ambiguity = overrides.Ambiguity
+	}
+
+	if overrides.Candidates != 0 {
+		overridden.Candidates = overrides.Candidates
+	}
+
+	return &overridden
+}
@@ -1,9 +1,115 @@
 package conversation
 
-import "gopkg.in/mgo.v2/bson"
+import (
+	"time"
 
-// User is the main user model shared across the different platforms
+	"github.com/aziule/conversation-management/core/bot"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// User is the main user model shared across the different platforms.
+//
+// A single user can talk to the bots on several platforms: each platform account
+// is one of the user's identities. Users can be merged into another one, for example
+// when they link their accounts: the merged user is kept as is, so that the merge
+// can be undone, and points to the user it was merged into.
 type User struct {
-	Id   bson.ObjectId `bson:"_id"`
-	FbId string        `bson:"fbid"`
+	Id         bson.ObjectId `json:"id" bson:"_id"`
+	Identities []*Identity   `json:"identities" bson:"identities"`
+	// MergedInto is the id of the user this user was merged into, if any
+	MergedInto bson.ObjectId `json:"merged_into,omitempty" bson:"merged_into,omitempty"`
+	// MergedUserIds are the ids of the users merged into this user, directly or not,
+	// so that their conversations are found along with this user's.
+	MergedUserIds []bson.ObjectId `json:"merged_user_ids,omitempty" bson:"merged_user_ids,omitempty"`
+	// Timezone is the user's timezone, such as "Europe/Paris", when known
+	Timezone  string    `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// Platform, PlatformId and FbId are only set for the users created before the identities
+	// were introduced. Use Identities instead.
+	Platform   string `json:"-" bson:"platform,omitempty"`
+	PlatformId string `json:"-" bson:"platform_id,omitempty"`
+	FbId       string `json:"-" bson:"fbid,omitempty"`
+}
+
+// Identity is a user's account on a platform
+type Identity struct {
+	Platform string `json:"platform" bson:"platform"`
+	// ExternalId is the user's id on the platform
+	ExternalId string    `json:"external_id" bson:"external_id"`
+	LinkedAt   time.Time `json:"linked_at" bson:"linked_at"`
+}
+
+// NewUser is the constructor method for User, creating a user with a single identity
+func NewUser(platform, externalId string) *User {
+	now := time.Now()
+
+	return &User{
+		Id: bson.NewObjectId(),
+		Identities: []*Identity{
+			{
+				Platform:   platform,
+				ExternalId: externalId,
+				LinkedAt:   now,
+			},
+		},
+		CreatedAt: now,
+	}
+}
+
+// Ids returns the user's id, along with the ids of the users merged into it
+func (user *User) Ids() []bson.ObjectId {
+	return append([]bson.ObjectId{user.Id}, user.MergedUserIds...)
+}
+
+// IsMerged tells whether the user was merged into another user
+func (user *User) IsMerged() bool {
+	return user.MergedInto != ""
+}
+
+// MigrateLegacyFields converts the fields of the users created before the identities
+// were introduced to an identity. Returns true if the user was modified.
+func (user *User) MigrateLegacyFields() bool {
+	platform, externalId := user.Platform, user.PlatformId
+
+	if externalId == "" && user.FbId != "" {
+		platform, externalId = string(bot.PlatformFacebook), user.FbId
+	}
+
+	if externalId == "" {
+		return false
+	}
+
+	user.Identities = append(user.Identities, &Identity{
+		Platform:   platform,
+		ExternalId: externalId,
+		LinkedAt:   user.Id.Time(),
+	})
+	user.Platform, user.PlatformId, user.FbId = "", "", ""
+
+	return true
+}
+
+// removeMergedUserIds removes the given ids from the ids of the users merged into this user
+func (user *User) removeMergedUserIds(ids []bson.ObjectId) {
+	var kept []bson.ObjectId
+
+	for _, id := range user.MergedUserIds {
+		if !containsId(ids, id) {
+			kept = append(kept, id)
+		}
+	}
+
+	user.MergedUserIds = kept
+}
+
+// containsId tells whether the id is part of the ids
+func containsId(ids []bson.ObjectId, id bson.ObjectId) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
 }
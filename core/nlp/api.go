@@ -29,8 +29,11 @@ func NewApi(name string, conf utils.BuilderConf) (Api, error) {
 	return api.(Api), nil
 }
 
-// Api is the main interface used to get / store NLP data
+// Api is the main interface used to get / store NLP data.
+// As it can parse text, it also implements the TextParser interface.
 type Api interface {
 	GetIntents() ([]*Intent, error)
 	GetEntities() ([]*Entity, error)
+	// Parse sends the text to the NLP service and returns the data it understood
+	Parse(text string, context *Context) (*ParsedData, error)
 }
@@ -0,0 +1,285 @@
+package nlp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const nlpCacheStoreBuilderPrefix = "nlp_cache_store_"
+
+// RegisterCacheStoreBuilder registers a new service builder using a package-level prefix
+func RegisterCacheStoreBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(nlpCacheStoreBuilderPrefix+name, builder)
+}
+
+// NewCacheStore tries to create a CacheStore using the available builders.
+// Returns ErrServiceBuilderNotFound if the store builder isn't found.
+func NewCacheStore(name string, conf utils.BuilderConf) (CacheStore, error) {
+	storeBuilder, err := utils.GetServiceBuilder(nlpCacheStoreBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := storeBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return store.(CacheStore), nil
+}
+
+// CacheStore stores the cached values, grouped by namespace so that they can be invalidated together
+type CacheStore interface {
+	// Get returns the value of the key. Returns false if there is none, or if it expired.
+	Get(namespace, key string) ([]byte, bool, error)
+	Set(namespace, key string, value []byte, ttl time.Duration) error
+	// Clear removes all of the namespace's values
+	Clear(namespace string) error
+}
+
+// SessionScoped is implemented by the text parsers whose results depend on the conversation's
+// previous messages, such as Dialogflow with its contexts. Their results are cached per session.
+type SessionScoped interface {
+	SessionScoped() bool
+}
+
+// CacheConf gives how long the NLP responses are cached. A zero TTL disables the caching.
+type CacheConf struct {
+	// ParseTTL is how long the parsed texts and NLP data are cached
+	ParseTTL time.Duration
+	// ListTTL is how long the lists of intents and entities are cached
+	ListTTL time.Duration
+}
+
+// DefaultCacheConf returns the conf used when none is given
+func DefaultCacheConf() *CacheConf {
+	return &CacheConf{
+		ParseTTL: 10 * time.Minute,
+		ListTTL:  5 * time.Minute,
+	}
+}
+
+// NewCacheConf creates a cache conf from params, such as {"parse_ttl": "1h", "list_ttl": 60}.
+// The TTLs are given either as durations or as numbers of seconds. The missing params keep their
+// default value.
+func NewCacheConf(params map[string]interface{}) *CacheConf {
+	conf := DefaultCacheConf()
+
+	if ttl, ok := toTTL(params["parse_ttl"]); ok {
+		conf.ParseTTL = ttl
+	}
+
+	if ttl, ok := toTTL(params["list_ttl"]); ok {
+		conf.ListTTL = ttl
+	}
+
+	return conf
+}
+
+// toTTL converts a duration, such as "10m", or a number of seconds to a duration
+func toTTL(value interface{}) (time.Duration, bool) {
+	if text, ok := value.(string); ok {
+		ttl, err := time.ParseDuration(text)
+
+		return ttl, err == nil
+	}
+
+	seconds, ok := utils.ToFloat(value)
+
+	return time.Duration(seconds * float64(time.Second)), ok
+}
+
+// CacheStats are the counters of a cache
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Coalesced are the misses that waited for the same call to the NLP service as another miss
+	Coalesced uint64 `json:"coalesced"`
+}
+
+// Cache caches the NLP responses within a store. Concurrent misses of the same key are coalesced,
+// so that the NLP service is called only once for all of them.
+type Cache struct {
+	store     CacheStore
+	namespace string
+	conf      *CacheConf
+	flights   *flightGroup
+	hits      uint64
+	misses    uint64
+	coalesced uint64
+}
+
+// cacheEntry is the value stored within the store. The values are stored encoded, so that every
+// reader gets its own copy of them, and so that typed entity values are decoded back (see ParsedEntity.SetBSON).
+type cacheEntry struct {
+	Data     *ParsedData `bson:"data,omitempty"`
+	Intents  []*Intent   `bson:"intents,omitempty"`
+	Entities []*Entity   `bson:"entities,omitempty"`
+}
+
+// NewCache creates a cache whose values are stored within the namespace, such as the bot's slug
+func NewCache(store CacheStore, namespace string, conf *CacheConf) *Cache {
+	if conf == nil {
+		conf = DefaultCacheConf()
+	}
+
+	return &Cache{
+		store:     store,
+		namespace: namespace,
+		conf:      conf,
+		flights:   &flightGroup{calls: make(map[string]*flight)},
+	}
+}
+
+// Stats returns the cache's counters
+func (cache *Cache) Stats() *CacheStats {
+	return &CacheStats{
+		Hits:      atomic.LoadUint64(&cache.hits),
+		Misses:    atomic.LoadUint64(&cache.misses),
+		Coalesced: atomic.LoadUint64(&cache.coalesced),
+	}
+}
+
+// Invalidate removes all of the cached values, such as when the NLP service was trained
+func (cache *Cache) Invalidate() error {
+	if err := cache.store.Clear(cache.namespace); err != nil {
+		log.WithField("namespace", cache.namespace).Infof("Could not invalidate the cache: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// fetch returns the cached entry of the key, or loads it and caches it for the TTL when it is
+// cacheable. Nothing is cached when the TTL is zero.
+func (cache *Cache) fetch(key string, ttl time.Duration, load func() (*cacheEntry, bool, error)) (*cacheEntry, error) {
+	if ttl <= 0 {
+		entry, _, err := load()
+		return entry, err
+	}
+
+	if raw, ok, err := cache.store.Get(cache.namespace, key); err == nil && ok {
+		entry := &cacheEntry{}
+
+		if err := bson.Unmarshal(raw, entry); err == nil {
+			atomic.AddUint64(&cache.hits, 1)
+			return entry, nil
+		}
+
+		log.WithField("key", key).Info("Could not decode the cached value: reloading it")
+	} else if err != nil {
+		log.WithField("key", key).Infof("Could not read the cache: %s", err)
+	}
+
+	atomic.AddUint64(&cache.misses, 1)
+
+	raw, err, shared := cache.flights.do(key, func() ([]byte, error) {
+		entry, cacheable, err := load()
+
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := bson.Marshal(entry)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if cacheable {
+			if err := cache.store.Set(cache.namespace, key, raw, ttl); err != nil {
+				log.WithField("key", key).Infof("Could not write the cache: %s", err)
+			}
+		}
+
+		return raw, nil
+	})
+
+	if shared {
+		atomic.AddUint64(&cache.coalesced, 1)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cacheEntry{}
+
+	return entry, bson.Unmarshal(raw, entry)
+}
+
+// cacheKey returns the key of the given parts, hashed so that keys have a bounded length
+func cacheKey(kind string, parts ...string) string {
+	hash := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+
+	return kind + ":" + hex.EncodeToString(hash[:])
+}
+
+// isCacheable returns whether the parsed data can be cached: datetimes are not, as they
+// are resolved relative to the time the text was written at
+func isCacheable(data *ParsedData) bool {
+	if data == nil {
+		return true
+	}
+
+	for _, entity := range data.Entities {
+		switch entity.Entity.Type {
+		case DateTimeEntity, SingleDateTimeEntity, DateTimeIntervalEntity:
+			return false
+		}
+	}
+
+	return true
+}
+
+// flight is a call in progress, shared by the callers asking for the same key
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// flightGroup coalesces the concurrent calls of the same key
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flight
+}
+
+// do calls fn, unless a call of the key is in progress, in which case it waits for its result.
+// Returns whether the result was shared with another caller.
+func (group *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error, bool) {
+	group.mutex.Lock()
+
+	if call, ok := group.calls[key]; ok {
+		group.mutex.Unlock()
+		<-call.done
+
+		return call.value, call.err, true
+	}
+
+	call := &flight{done: make(chan struct{})}
+	group.calls[key] = call
+	group.mutex.Unlock()
+
+	// The waiting callers are released even if fn panics
+	defer func() {
+		group.mutex.Lock()
+		delete(group.calls, key)
+		group.mutex.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+
+	return call.value, call.err, false
+}
@@ -0,0 +1,149 @@
+package nlp
+
+import (
+	"strings"
+)
+
+// cachedParser is the Parser decorator caching the parsed data, by raw data
+type cachedParser struct {
+	parser Parser
+	cache  *Cache
+}
+
+// NewCachedParser returns the parser, caching its parsed data within the cache
+func NewCachedParser(parser Parser, cache *Cache) Parser {
+	return &cachedParser{
+		parser: parser,
+		cache:  cache,
+	}
+}
+
+// ParseNlpData returns the cached data of the raw data, or parses it
+func (parser *cachedParser) ParseNlpData(rawData []byte) (*ParsedData, error) {
+	entry, err := parser.cache.fetch(cacheKey("nlp", string(rawData)), parser.cache.conf.ParseTTL, func() (*cacheEntry, bool, error) {
+		data, err := parser.parser.ParseNlpData(rawData)
+
+		// The datetimes are already resolved within the raw data, so all of the data is cacheable
+		return &cacheEntry{Data: data}, true, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Data, nil
+}
+
+// cachedApi is the Api decorator caching the parsed texts and the lists of intents and entities
+type cachedApi struct {
+	api   Api
+	cache *Cache
+}
+
+// NewCachedApi returns the API, caching its responses within the cache.
+// The texts whose data has datetimes are not cached, as they are resolved relative to the
+// time the texts were written at.
+func NewCachedApi(api Api, cache *Cache) Api {
+	return &cachedApi{
+		api:   api,
+		cache: cache,
+	}
+}
+
+// GetIntents returns the cached intents, or gets them from the API
+func (api *cachedApi) GetIntents() ([]*Intent, error) {
+	return cachedIntents(api.cache, api.api.GetIntents)
+}
+
+// GetEntities returns the cached entities, or gets them from the API
+func (api *cachedApi) GetEntities() ([]*Entity, error) {
+	return cachedEntities(api.cache, api.api.GetEntities)
+}
+
+// Parse returns the cached data of the text, or sends the text to the API.
+// The texts are cached per locale and timezone, and per session for the session scoped APIs.
+func (api *cachedApi) Parse(text string, context *Context) (*ParsedData, error) {
+	parts := []string{strings.TrimSpace(text)}
+
+	if context != nil {
+		parts = append(parts, context.Locale, context.Timezone)
+
+		if scoped, ok := api.api.(SessionScoped); ok && scoped.SessionScoped() {
+			parts = append(parts, context.SessionId)
+		}
+	}
+
+	entry, err := api.cache.fetch(cacheKey("text", parts...), api.cache.conf.ParseTTL, func() (*cacheEntry, bool, error) {
+		data, err := api.api.Parse(text, context)
+
+		return &cacheEntry{Data: data}, isCacheable(data), err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Data, nil
+}
+
+// cachedRepository is the Repository decorator caching the lists of intents and entities
+type cachedRepository struct {
+	repository Repository
+	cache      *Cache
+}
+
+// NewCachedRepository returns the repository, caching its lists of intents and entities within the cache
+func NewCachedRepository(repository Repository, cache *Cache) Repository {
+	return &cachedRepository{
+		repository: repository,
+		cache:      cache,
+	}
+}
+
+// GetIntents returns the cached intents, or gets them from the repository
+func (repository *cachedRepository) GetIntents() ([]*Intent, error) {
+	return cachedIntents(repository.cache, repository.repository.GetIntents)
+}
+
+// GetEntities returns the cached entities, or gets them from the repository
+func (repository *cachedRepository) GetEntities() ([]*Entity, error) {
+	return cachedEntities(repository.cache, repository.repository.GetEntities)
+}
+
+// cachedIntents returns the cached intents, or gets them
+func cachedIntents(cache *Cache, getIntents func() ([]*Intent, error)) ([]*Intent, error) {
+	entry, err := cache.fetch(cacheKey("intents"), cache.conf.ListTTL, func() (*cacheEntry, bool, error) {
+		intents, err := getIntents()
+
+		return &cacheEntry{Intents: intents}, true, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Intents == nil {
+		return []*Intent{}, nil
+	}
+
+	return entry.Intents, nil
+}
+
+// cachedEntities returns the cached entities, or gets them
+func cachedEntities(cache *Cache, getEntities func() ([]*Entity, error)) ([]*Entity, error) {
+	entry, err := cache.fetch(cacheKey("entities"), cache.conf.ListTTL, func() (*cacheEntry, bool, error) {
+		entities, err := getEntities()
+
+		return &cacheEntry{Entities: entities}, true, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Entities == nil {
+		return []*Entity{}, nil
+	}
+
+	return entry.Entities, nil
+}
@@ -0,0 +1,264 @@
+package nlp
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const nlpDataTypeRepositoryBuilderPrefix = "nlp_data_type_repository_"
+
+var ErrInvalidDataType = errors.New("Invalid data type")
+
+// entityTypes are the types that can be used within a data type map
+var entityTypes = map[EntityType]bool{
+	IntentEntity:   true,
+	IntEntity:      true,
+	NumberEntity:   true,
+	StringEntity:   true,
+	EnumEntity:     true,
+	MoneyEntity:    true,
+	DurationEntity: true,
+	EmailEntity:    true,
+	PhoneEntity:    true,
+	UrlEntity:      true,
+	LocationEntity: true,
+	DateTimeEntity: true,
+}
+
+// RegisterDataTypeRepositoryBuilder registers a new service builder using a package-level prefix
+func RegisterDataTypeRepositoryBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(nlpDataTypeRepositoryBuilderPrefix+name, builder)
+}
+
+// NewDataTypeRepository tries to create a DataTypeRepository using the available builders.
+// Returns ErrServiceBuilderNotFound if the repository builder isn't found.
+func NewDataTypeRepository(name string, conf utils.BuilderConf) (DataTypeRepository, error) {
+	repositoryBuilder, err := utils.GetServiceBuilder(nlpDataTypeRepositoryBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := repositoryBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.(DataTypeRepository), nil
+}
+
+// DataTypeRepository is the interface responsible for fetching / saving the bots' data type maps
+type DataTypeRepository interface {
+	// FindByBot returns the data type map of the bot, given its slug. Returns nil if there is none.
+	FindByBot(bot string) (DataTypeMap, error)
+	SaveForBot(bot string, dataTypeMap DataTypeMap) error
+}
+
+// InvalidKeys returns the entities' names whose type is not a known type, sorted
+func (dataTypeMap DataTypeMap) InvalidKeys() []string {
+	var keys []string
+
+	for name, dataType := range dataTypeMap {
+		if !entityTypes[dataType] {
+			keys = append(keys, name)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// DataTypes is the data type map of a bot, shared by the bot's parsers. It can be updated
+// while the parsers use it, and it records the entities the parsers could not find a type for.
+type DataTypes struct {
+	mutex sync.RWMutex
+	// defaults are the types of the entities expected by the bot's stories
+	defaults DataTypeMap
+	// custom are the types set for the bot, overriding the defaults
+	custom  DataTypeMap
+	unknown map[string]int
+}
+
+// NewDataTypes is the constructor method for DataTypes
+func NewDataTypes(defaults DataTypeMap) *DataTypes {
+	return &DataTypes{
+		defaults: defaults,
+		custom:   DataTypeMap{},
+		unknown:  make(map[string]int),
+	}
+}
+
+// Lookup returns the type of the entity: the bot's type if any, or else the type found within
+// the given map, usually the NLP provider's built-in entities. It can be called on nil DataTypes.
+func (d *DataTypes) Lookup(name string, providerDefaults DataTypeMap) (EntityType, bool) {
+	if d != nil {
+		d.mutex.RLock()
+		dataType, ok := d.custom[name]
+
+		if !ok {
+			dataType, ok = d.defaults[name]
+		}
+
+		d.mutex.RUnlock()
+
+		if ok {
+			return dataType, true
+		}
+	}
+
+	dataType, ok := providerDefaults[name]
+
+	return dataType, ok
+}
+
+// Set replaces the bot's custom types.
+// Returns ErrInvalidDataType if any of the types is not a known type.
+func (d *DataTypes) Set(custom DataTypeMap) error {
+	if len(custom.InvalidKeys()) > 0 {
+		return ErrInvalidDataType
+	}
+
+	copied := DataTypeMap{}
+
+	for name, dataType := range custom {
+		copied[name] = dataType
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.custom = copied
+
+	// The entities that are now mapped are no longer unknown
+	for name := range copied {
+		delete(d.unknown, name)
+	}
+
+	return nil
+}
+
+// Custom returns a copy of the bot's custom types
+func (d *DataTypes) Custom() DataTypeMap {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	copied := DataTypeMap{}
+
+	for name, dataType := range d.custom {
+		copied[name] = dataType
+	}
+
+	return copied
+}
+
+// Map returns a copy of the bot's types: the defaults, overridden by the custom types
+func (d *DataTypes) Map() DataTypeMap {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	merged := DataTypeMap{}
+
+	for name, dataType := range d.defaults {
+		merged[name] = dataType
+	}
+
+	for name, dataType := range d.custom {
+		merged[name] = dataType
+	}
+
+	return merged
+}
+
+// ReportUnknown records an entity the parsers could not find a type for.
+// It can be called on nil DataTypes.
+func (d *DataTypes) ReportUnknown(name string) {
+	if d == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.unknown[name]++
+}
+
+// Unknown returns the entities the parsers could not find a type for, along with the number of times
+// they were received
+func (d *DataTypes) Unknown() map[string]int {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	copied := make(map[string]int)
+
+	for name, count := range d.unknown {
+		copied[name] = count
+	}
+
+	return copied
+}
+
+// Discovery is the result of the discovery of the types of the entities listed by the NLP provider
+type Discovery struct {
+	// Discovered are the entities whose type was added to the custom types
+	Discovered []string `json:"discovered"`
+	// Untyped are the listed entities whose type the provider does not give, and which are not mapped
+	Untyped []string `json:"untyped"`
+	// Unlisted are the mapped entities the provider does not list
+	Unlisted []string `json:"unlisted"`
+}
+
+// Discover adds the types of the given entities, as listed by the NLP provider, to the custom types.
+// The types that are already mapped are kept, so that the types set by hand are not overridden.
+func (d *DataTypes) Discover(entities []*Entity) *Discovery {
+	discovery := &Discovery{
+		Discovered: []string{},
+		Untyped:    []string{},
+		Unlisted:   []string{},
+	}
+	listed := make(map[string]bool)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, entity := range entities {
+		listed[entity.Name] = true
+
+		if _, ok := d.custom[entity.Name]; ok {
+			continue
+		}
+
+		if _, ok := d.defaults[entity.Name]; ok {
+			continue
+		}
+
+		if !entityTypes[entity.Type] {
+			discovery.Untyped = append(discovery.Untyped, entity.Name)
+			continue
+		}
+
+		d.custom[entity.Name] = entity.Type
+		delete(d.unknown, entity.Name)
+		discovery.Discovered = append(discovery.Discovered, entity.Name)
+	}
+
+	for _, dataTypeMap := range []DataTypeMap{d.defaults, d.custom} {
+		for name, dataType := range dataTypeMap {
+			// Intents are not listed as entities
+			if !listed[name] && dataType != IntentEntity {
+				discovery.Unlisted = append(discovery.Unlisted, name)
+				listed[name] = true
+			}
+		}
+	}
+
+	sort.Strings(discovery.Discovered)
+	sort.Strings(discovery.Untyped)
+	sort.Strings(discovery.Unlisted)
+
+	return discovery
+}
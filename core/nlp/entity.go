@@ -13,17 +13,37 @@ const (
 	GranularityDay  DateTimeGranularity = "day"
 
 	// @todo: add an UnknownEntity type?
-	IntentEntity           EntityType = "intent"
-	IntEntity              EntityType = "int"
+	IntentEntity EntityType = "intent"
+	IntEntity    EntityType = "int"
+	NumberEntity EntityType = "number"
+	StringEntity EntityType = "string"
+	// EnumEntity is a string among a list of values, each value having its synonyms
+	EnumEntity     EntityType = "enum"
+	MoneyEntity    EntityType = "money"
+	DurationEntity EntityType = "duration"
+	EmailEntity    EntityType = "email"
+	PhoneEntity    EntityType = "phone"
+	UrlEntity      EntityType = "url"
+	LocationEntity EntityType = "location"
+	// DateTimeEntity is used within the data type maps, as the NLP services can give either
+	// a single datetime or an interval: the parsed entities are given either of the two types.
 	DateTimeEntity         EntityType = "datetime"
-	SingleDateTimeEntity   EntityType = "datetime"
-	DateTimeIntervalEntity EntityType = "datetime"
+	SingleDateTimeEntity   EntityType = "datetime_single"
+	DateTimeIntervalEntity EntityType = "datetime_interval"
 )
 
 // Entity is the struct that represents a base entity
 type Entity struct {
-	Name string     `json:"name", bson:"name"`
-	Type EntityType `json:"-", bson:"type"`
+	Name string     `json:"name" bson:"name"`
+	Type EntityType `json:"-" bson:"type"`
+}
+
+// NewEntity creates a new entity of the given type
+func NewEntity(name string, entityType EntityType) *Entity {
+	return &Entity{
+		Name: name,
+		Type: entityType,
+	}
 }
 
 // NewIntEntity creates a new entity of type Int
@@ -50,6 +70,22 @@ func NewSingleDateTimeEntity(name string) *Entity {
 	}
 }
 
+// NewEmailEntity creates a new entity of type Email
+func NewEmailEntity(name string) *Entity {
+	return &Entity{
+		Name: name,
+		Type: EmailEntity,
+	}
+}
+
+// NewPhoneEntity creates a new entity of type Phone
+func NewPhoneEntity(name string) *Entity {
+	return &Entity{
+		Name: name,
+		Type: PhoneEntity,
+	}
+}
+
 //// SetBSON converts the EntityWithType's entity, of type interface, to the corresponding Entity struct.
 //// We have moved the SetBSON method here for now, as it was faster to develop. In the future,
 //// we may think about extracting it completely to the "conversation/mongo" package.
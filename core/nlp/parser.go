@@ -1,8 +1,10 @@
 package nlp
 
 import (
-	"github.com/aziule/conversation-management/core/utils"
+	"sort"
 	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
 )
 
 const nlpParserBuilderPrefix = "nlp_parser_"
@@ -21,22 +23,15 @@ type ParsedIntent struct {
 // As there may be any kind of entities, we use an interface to store the
 // entities' formatted data.
 type ParsedEntity struct {
-	Entity     *Entity     `json:",inline"`
-	Role       string      `json:"role"`
+	Entity *Entity `json:",inline"`
+	Role   string  `json:"role"`
+	// Group groups the entities that belong together, such as the size
+	// and the topping of a same pizza. It is optional.
+	Group      string      `json:"group,omitempty" bson:"group,omitempty"`
 	Confidence float32     `json:"confidence"`
 	Data       interface{} `json:"data"`
 }
 
-type parsedSingleDateTime struct {
-	Date        time.Time
-	Granularity DateTimeGranularity
-}
-
-type parsedDateTimeInterval struct {
-	From *parsedSingleDateTime
-	To   *parsedSingleDateTime
-}
-
 // RegisterRepositoryBuilder registers a new service builder using a package-level prefix
 func RegisterParserBuilder(name string, builder utils.ServiceBuilder) {
 	utils.RegisterServiceBuilder(nlpParserBuilderPrefix+name, builder)
@@ -67,16 +62,22 @@ type Parser interface {
 
 // ParsedData represents intents and entities as understood after using NLP services
 type ParsedData struct {
-	Intent   *ParsedIntent   `bson:"intent"`
+	Intent *ParsedIntent `bson:"intent"`
+	// Intents are the candidate intents, ranked by decreasing confidence, when the
+	// NLP service gives a ranking. The first one is the Intent.
+	Intents  []*ParsedIntent `bson:"intents,omitempty"`
 	Entities []*ParsedEntity `bson:"entities"`
 }
 
-func NewParsedIntent(name string) *ParsedIntent {
+// NewParsedIntent is the constructor method for ParsedIntent
+func NewParsedIntent(name string, confidence float32) *ParsedIntent {
 	return &ParsedIntent{
-		Intent: NewIntent(name),
+		Intent:     NewIntent(name),
+		Confidence: confidence,
 	}
 }
 
+// NewParsedIntEntity is the constructor method for a ParsedEntity of type Int
 func NewParsedIntEntity(name string, confidence float32, value int, role string) *ParsedEntity {
 	return &ParsedEntity{
 		Entity:     NewIntEntity(name),
@@ -86,11 +87,124 @@ func NewParsedIntEntity(name string, confidence float32, value int, role string)
 	}
 }
 
+// NewParsedNumberEntity is the constructor method for a ParsedEntity of type Number
+func NewParsedNumberEntity(name string, confidence float32, value float64, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, NumberEntity),
+		Confidence: confidence,
+		Data:       value,
+		Role:       role,
+	}
+}
+
+// NewParsedStringEntity is the constructor method for a ParsedEntity of type String
+func NewParsedStringEntity(name string, confidence float32, value string, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, StringEntity),
+		Confidence: confidence,
+		Data:       value,
+		Role:       role,
+	}
+}
+
+// NewParsedEnumEntity is the constructor method for a ParsedEntity of type Enum.
+// The value is the canonical value, not the synonym used by the user.
+func NewParsedEnumEntity(name string, confidence float32, value string, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, EnumEntity),
+		Confidence: confidence,
+		Data:       value,
+		Role:       role,
+	}
+}
+
+// NewParsedTextEntity is the constructor method for a ParsedEntity whose value is a text:
+// a String, Enum, Email, Phone or Url. Other types give a String.
+func NewParsedTextEntity(name string, confidence float32, value string, entityType EntityType, role string) *ParsedEntity {
+	switch entityType {
+	case EnumEntity, EmailEntity, PhoneEntity, UrlEntity:
+	default:
+		entityType = StringEntity
+	}
+
+	return &ParsedEntity{
+		Entity:     NewEntity(name, entityType),
+		Confidence: confidence,
+		Data:       value,
+		Role:       role,
+	}
+}
+
+// NewParsedMoneyEntity is the constructor method for a ParsedEntity of type Money
+func NewParsedMoneyEntity(name string, confidence float32, amount float64, currency string, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, MoneyEntity),
+		Confidence: confidence,
+		Data: &Money{
+			Amount:   amount,
+			Currency: currency,
+		},
+		Role: role,
+	}
+}
+
+// NewParsedDurationEntity is the constructor method for a ParsedEntity of type Duration
+func NewParsedDurationEntity(name string, confidence float32, duration time.Duration, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, DurationEntity),
+		Confidence: confidence,
+		Data:       duration,
+		Role:       role,
+	}
+}
+
+// NewParsedUrlEntity is the constructor method for a ParsedEntity of type Url
+func NewParsedUrlEntity(name string, confidence float32, url string, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, UrlEntity),
+		Confidence: confidence,
+		Data:       url,
+		Role:       role,
+	}
+}
+
+// NewParsedLocationEntity is the constructor method for a ParsedEntity of type Location
+func NewParsedLocationEntity(name string, confidence float32, location *Location, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEntity(name, LocationEntity),
+		Confidence: confidence,
+		Data:       location,
+		Role:       role,
+	}
+}
+
+// NewParsedEmailEntity is the constructor method for a ParsedEntity of type Email
+func NewParsedEmailEntity(name string, confidence float32, email string, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewEmailEntity(name),
+		Confidence: confidence,
+		Data:       email,
+		Role:       role,
+	}
+}
+
+// NewParsedPhoneEntity is the constructor method for a ParsedEntity of type Phone.
+// The phone number only contains digits, and the leading "+" of international numbers.
+func NewParsedPhoneEntity(name string, confidence float32, phone string, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewPhoneEntity(name),
+		Confidence: confidence,
+		Data:       phone,
+		Role:       role,
+	}
+}
+
+// NewParsedSingleDateTimeEntity is the constructor method for a ParsedEntity of type SingleDateTime
 func NewParsedSingleDateTimeEntity(name string, confidence float32, date time.Time, granularity DateTimeGranularity, role string) *ParsedEntity {
 	return &ParsedEntity{
 		Entity:     NewSingleDateTimeEntity(name),
 		Confidence: confidence,
-		Data: &parsedSingleDateTime{
+		Data: &SingleDateTime{
 			Date:        date,
 			Granularity: granularity,
 		},
@@ -98,16 +212,17 @@ func NewParsedSingleDateTimeEntity(name string, confidence float32, date time.Ti
 	}
 }
 
+// NewParsedDateTimeIntervalEntity is the constructor method for a ParsedEntity of type DateTimeInterval
 func NewParsedDateTimeIntervalEntity(name string, confidence float32, fromDate, toDate time.Time, fromGran, toGran DateTimeGranularity, role string) *ParsedEntity {
 	return &ParsedEntity{
 		Entity:     NewDateTimeIntervalEntity(name),
 		Confidence: confidence,
-		Data: &parsedDateTimeInterval{
-			From: &parsedSingleDateTime{
+		Data: &DateTimeInterval{
+			From: &SingleDateTime{
 				Date:        fromDate,
 				Granularity: fromGran,
 			},
-			To: &parsedSingleDateTime{
+			To: &SingleDateTime{
 				Date:        toDate,
 				Granularity: toGran,
 			},
@@ -116,20 +231,53 @@ func NewParsedDateTimeIntervalEntity(name string, confidence float32, fromDate,
 	}
 }
 
-// NewParsedData is the constructor method for ParsedData
+// NewParsedOpenDateTimeIntervalEntity is the constructor method for a ParsedEntity of type DateTimeInterval
+// that may be open, such as "after 8pm": the missing bound is nil.
+func NewParsedOpenDateTimeIntervalEntity(name string, confidence float32, from, to *SingleDateTime, role string) *ParsedEntity {
+	return &ParsedEntity{
+		Entity:     NewDateTimeIntervalEntity(name),
+		Confidence: confidence,
+		Data: &DateTimeInterval{
+			From: from,
+			To:   to,
+		},
+		Role: role,
+	}
+}
+
+// NewParsedData is the constructor method for ParsedData.
+// A nil intent means that no intent was understood.
 func NewParsedData(intent *ParsedIntent, entities []*ParsedEntity) *ParsedData {
+	return &ParsedData{
+		Intent:   intent,
+		Entities: entities,
+	}
+}
+
+// Entity returns the first entity with the given name. Returns nil if there is none.
+func (data *ParsedData) Entity(name string) *ParsedEntity {
+	for _, entity := range data.Entities {
+		if entity.Entity.Name == name {
+			return entity
+		}
+	}
+
 	return nil
-	//var entitiesWithType []*EntityWithType
-	//
-	//for _, e := range entities {
-	//	entitiesWithType = append(entitiesWithType, &EntityWithType{
-	//		Type:   e.Type(),
-	//		Entity: e,
-	//	})
-	//}
-	//
-	//return &ParsedData{
-	//	Intent:   intent,
-	//	Entities: entitiesWithType,
-	//}
+}
+
+// TrimIntents keeps the n most confident candidate intents, ranked by decreasing confidence.
+// All of the candidates are kept when n <= 0.
+func (data *ParsedData) TrimIntents(n int) {
+	RankIntents(data.Intents)
+
+	if n > 0 && len(data.Intents) > n {
+		data.Intents = data.Intents[:n]
+	}
+}
+
+// RankIntents sorts the intents by decreasing confidence
+func RankIntents(intents []*ParsedIntent) {
+	sort.SliceStable(intents, func(i, j int) bool {
+		return intents[i].Confidence > intents[j].Confidence
+	})
 }
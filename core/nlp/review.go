@@ -0,0 +1,251 @@
+package nlp
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const nlpReviewRepositoryBuilderPrefix = "nlp_review_repository_"
+
+var (
+	ErrReviewItemNotFound = errors.New("Review item not found")
+	ErrAlreadySynced      = errors.New("The review item is already synced")
+)
+
+// ReviewStatus is the status of a review item
+type ReviewStatus string
+
+// ReviewReason is the reason why a message was put in the review queue
+type ReviewReason string
+
+const (
+	// ReviewPending items are waiting for an operator to label them
+	ReviewPending ReviewStatus = "pending"
+	// ReviewLabeled items are labeled, and waiting to be synced to the NLP service
+	ReviewLabeled   ReviewStatus = "labeled"
+	ReviewSynced    ReviewStatus = "synced"
+	ReviewDismissed ReviewStatus = "dismissed"
+
+	// ReviewUnparsed is the reason of the messages where neither an intent nor an entity was found
+	ReviewUnparsed ReviewReason = "unparsed"
+	// ReviewLowConfidence is the reason of the messages whose intent is not confident enough
+	ReviewLowConfidence ReviewReason = "low_confidence"
+)
+
+// RegisterReviewRepositoryBuilder registers a new service builder using a package-level prefix
+func RegisterReviewRepositoryBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(nlpReviewRepositoryBuilderPrefix+name, builder)
+}
+
+// NewReviewRepository tries to create a ReviewRepository using the available builders.
+// Returns ErrServiceBuilderNotFound if the repository builder isn't found.
+func NewReviewRepository(name string, conf utils.BuilderConf) (ReviewRepository, error) {
+	repositoryBuilder, err := utils.GetServiceBuilder(nlpReviewRepositoryBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := repositoryBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.(ReviewRepository), nil
+}
+
+// ReviewRepository is the interface responsible for fetching / saving the review items
+type ReviewRepository interface {
+	Insert(item *ReviewItem) error
+	Update(item *ReviewItem) error
+	// FindById returns the review item. Returns ErrReviewItemNotFound if it is not found.
+	FindById(id bson.ObjectId) (*ReviewItem, error)
+	// FindByBot returns the bot's review items having the status, from the oldest to the newest.
+	// All of the bot's items are returned when the status is empty.
+	FindByBot(bot string, status ReviewStatus) ([]*ReviewItem, error)
+}
+
+// ReviewItem is a user's message the NLP service did not understand well enough. An operator labels it,
+// and the label is synced to the NLP service so that it understands such messages next time.
+type ReviewItem struct {
+	Id             bson.ObjectId `json:"id" bson:"_id"`
+	Bot            string        `json:"bot" bson:"bot"`
+	ConversationId bson.ObjectId `json:"conversation_id,omitempty" bson:"conversation_id,omitempty"`
+	Text           string        `json:"text" bson:"text"`
+	Reason         ReviewReason  `json:"reason" bson:"reason"`
+	// Intent is the intent the NLP service found, if any, along with its confidence
+	Intent     string       `json:"intent,omitempty" bson:"intent,omitempty"`
+	Confidence float32      `json:"confidence" bson:"confidence"`
+	Status     ReviewStatus `json:"status" bson:"status"`
+	Label      *Utterance   `json:"label,omitempty" bson:"label,omitempty"`
+	CreatedAt  time.Time    `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" bson:"updated_at"`
+}
+
+// ReviewQueue is the review queue of a bot
+type ReviewQueue struct {
+	repository ReviewRepository
+	bot        string
+}
+
+// NewReviewQueue is the constructor method for ReviewQueue
+func NewReviewQueue(repository ReviewRepository, bot string) *ReviewQueue {
+	return &ReviewQueue{
+		repository: repository,
+		bot:        bot,
+	}
+}
+
+// Capture puts the message in the queue when its data has neither an intent nor an entity, or when
+// its intent's confidence is lower than the threshold. Empty messages, such as attachments, are ignored.
+// Returns the item, or nil if the message was understood well enough.
+func (queue *ReviewQueue) Capture(text string, conversationId bson.ObjectId, data *ParsedData, threshold float32) (*ReviewItem, error) {
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return nil, nil
+	}
+
+	item := &ReviewItem{
+		Id:             bson.NewObjectId(),
+		Bot:            queue.bot,
+		ConversationId: conversationId,
+		Text:           text,
+		Status:         ReviewPending,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	switch {
+	case data == nil || (data.Intent == nil && len(data.Entities) == 0):
+		item.Reason = ReviewUnparsed
+	case data.Intent != nil && data.Intent.Confidence < threshold:
+		item.Reason = ReviewLowConfidence
+		item.Intent = data.Intent.Intent.Name
+		item.Confidence = data.Intent.Confidence
+	default:
+		return nil, nil
+	}
+
+	if err := queue.repository.Insert(item); err != nil {
+		log.WithField("text", text).Infof("Could not insert the review item: %s", err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Items returns the queue's items having the status, or all of them if the status is empty
+func (queue *ReviewQueue) Items(status ReviewStatus) ([]*ReviewItem, error) {
+	return queue.repository.FindByBot(queue.bot, status)
+}
+
+// Label labels an item with its intent and entities. The label's text is the item's text.
+// Returns ErrInvalidUtterance if the label is not valid.
+func (queue *ReviewQueue) Label(id bson.ObjectId, label *Utterance) (*ReviewItem, error) {
+	item, err := queue.find(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Status == ReviewSynced {
+		return nil, ErrAlreadySynced
+	}
+
+	label.Text = item.Text
+
+	if err := label.Validate(); err != nil {
+		return nil, err
+	}
+
+	item.Label = label
+	item.Status = ReviewLabeled
+
+	return item, queue.update(item)
+}
+
+// Dismiss removes an item from the items to label, such as a message that does not make sense
+func (queue *ReviewQueue) Dismiss(id bson.ObjectId) (*ReviewItem, error) {
+	item, err := queue.find(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Status == ReviewSynced {
+		return nil, ErrAlreadySynced
+	}
+
+	item.Status = ReviewDismissed
+
+	return item, queue.update(item)
+}
+
+// Sync sends the labeled items to the NLP service, and returns them once synced.
+// The intents are expected to exist within the NLP service.
+func (queue *ReviewQueue) Sync(trainer Trainer) ([]*ReviewItem, error) {
+	items, err := queue.Items(ReviewLabeled)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	var utterances []*Utterance
+
+	for _, item := range items {
+		utterances = append(utterances, item.Label)
+	}
+
+	if err := trainer.AddUtterances(utterances); err != nil {
+		log.WithField("bot", queue.bot).Infof("Could not sync the review items: %s", err)
+		return nil, err
+	}
+
+	for _, item := range items {
+		item.Status = ReviewSynced
+
+		if err := queue.update(item); err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// find returns the queue's item. Returns ErrReviewItemNotFound if the item belongs to another bot.
+func (queue *ReviewQueue) find(id bson.ObjectId) (*ReviewItem, error) {
+	item, err := queue.repository.FindById(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Bot != queue.bot {
+		return nil, ErrReviewItemNotFound
+	}
+
+	return item, nil
+}
+
+// update saves the item
+func (queue *ReviewQueue) update(item *ReviewItem) error {
+	item.UpdatedAt = time.Now()
+
+	if err := queue.repository.Update(item); err != nil {
+		log.WithField("item", item.Id).Infof("Could not update the review item: %s", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package nlp
+
+import (
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+const nlpTextParserBuilderPrefix = "nlp_text_parser_"
+
+// RegisterTextParserBuilder registers a new service builder using a package-level prefix
+func RegisterTextParserBuilder(name string, builder utils.ServiceBuilder) {
+	utils.RegisterServiceBuilder(nlpTextParserBuilderPrefix+name, builder)
+}
+
+// NewTextParser tries to create a TextParser using the available builders.
+// Returns ErrServiceBuilderNotFound if the text parser builder isn't found.
+func NewTextParser(name string, conf utils.BuilderConf) (TextParser, error) {
+	textParserBuilder, err := utils.GetServiceBuilder(nlpTextParserBuilderPrefix + name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	textParser, err := textParserBuilder(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return textParser.(TextParser), nil
+}
+
+// TextParser is the interface for parsing raw text, when the platform does not
+// provide already parsed NLP data.
+type TextParser interface {
+	Parse(text string, context *Context) (*ParsedData, error)
+}
+
+// Context gives the context in which a text was written, used to understand it.
+// For example, "tomorrow" is resolved using the reference time.
+type Context struct {
+	// SessionId identifies the conversation the text belongs to, for the services keeping
+	// track of the conversations' state. It is optional.
+	SessionId string
+	// ReferenceTime is the time the text was written at. The current time is used when it is zero.
+	ReferenceTime time.Time
+	// Timezone is the user's timezone, such as "Europe/Paris". It is optional.
+	Timezone string
+	// Locale is the user's locale, such as "en_US". It is optional.
+	Locale string
+}
+
+// NewContext is the constructor method for Context
+func NewContext(referenceTime time.Time) *Context {
+	return &Context{
+		ReferenceTime: referenceTime,
+	}
+}
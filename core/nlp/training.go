@@ -0,0 +1,81 @@
+package nlp
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidUtterance = errors.New("Invalid utterance")
+
+// Trainer is implemented by the NLP APIs that can be trained from our side
+type Trainer interface {
+	CreateIntent(name string) error
+	// CreateEntity creates an entity, along with its roles. The entity's name is used as its only role
+	// when there is none.
+	CreateEntity(name string, roles []string) error
+	// AddEntityValue adds a value, along with its synonyms, to an entity
+	AddEntityValue(entity string, value *EntityValue) error
+	// AddUtterances adds samples to the NLP service, so that it learns from them.
+	// Any number of utterances can be given: they are sent in batches.
+	AddUtterances(utterances []*Utterance) error
+}
+
+// EntityValue is a value of an entity, along with its synonyms
+type EntityValue struct {
+	Value    string   `json:"value" bson:"value"`
+	Synonyms []string `json:"synonyms" bson:"synonyms"`
+}
+
+// Utterance is a sample text labeled with its intent and entities, used to train the NLP service
+type Utterance struct {
+	Text     string             `json:"text" bson:"text"`
+	Intent   string             `json:"intent,omitempty" bson:"intent,omitempty"`
+	Entities []*UtteranceEntity `json:"entities,omitempty" bson:"entities,omitempty"`
+}
+
+// UtteranceEntity is an entity labeled within an utterance. Body is the part of the text standing for
+// the entity, from the Start to the End byte offset.
+type UtteranceEntity struct {
+	Entity string `json:"entity" bson:"entity"`
+	Role   string `json:"role,omitempty" bson:"role,omitempty"`
+	Body   string `json:"body" bson:"body"`
+	Start  int    `json:"start" bson:"start"`
+	End    int    `json:"end" bson:"end"`
+}
+
+// Validate checks that the utterance is labeled, and that its entities are within its text.
+// The entities given by their body only have their offsets set to the first occurrence of the body,
+// and the entities given by their offsets only have their body set.
+// Returns ErrInvalidUtterance if the utterance is not valid.
+func (u *Utterance) Validate() error {
+	u.Text = strings.TrimSpace(u.Text)
+
+	if u.Text == "" || (u.Intent == "" && len(u.Entities) == 0) {
+		return ErrInvalidUtterance
+	}
+
+	for _, entity := range u.Entities {
+		if entity == nil || entity.Entity == "" {
+			return ErrInvalidUtterance
+		}
+
+		if entity.Start == 0 && entity.End == 0 && entity.Body != "" {
+			entity.Start = strings.Index(u.Text, entity.Body)
+			entity.End = entity.Start + len(entity.Body)
+		}
+
+		if entity.Start < 0 || entity.End <= entity.Start || entity.End > len(u.Text) {
+			return ErrInvalidUtterance
+		}
+
+		if entity.Body == "" {
+			entity.Body = u.Text[entity.Start:entity.End]
+		}
+
+		if u.Text[entity.Start:entity.End] != entity.Body {
+			return ErrInvalidUtterance
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package nlp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrInvalidValidator = errors.New("Invalid validator")
+
+// ValidationError is the error returned when an entity's value is not valid.
+// Its message is meant to be shown to the user, so that the user can give another value.
+type ValidationError struct {
+	Entity  string
+	Message string
+	// Options are the valid values, when there are only a few of them
+	Options []string
+}
+
+// Error returns the message of the error
+func (err *ValidationError) Error() string {
+	return err.Message
+}
+
+// Validator checks the value of an entity. Returns a ValidationError if it is not valid.
+type Validator func(entity *ParsedEntity) *ValidationError
+
+// Validators maps the entities' names to their validators
+type Validators map[string][]Validator
+
+// Between validates numbers within the given bounds, bounds included
+func Between(min, max float64) Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		if value, ok := entity.Number(); ok && value >= min && value <= max {
+			return nil
+		}
+
+		return &ValidationError{
+			Entity:  entity.Entity.Name,
+			Message: fmt.Sprintf("Please give a number between %v and %v.", min, max),
+		}
+	}
+}
+
+// AtLeast validates numbers greater than or equal to the given minimum
+func AtLeast(min float64) Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		if value, ok := entity.Number(); ok && value >= min {
+			return nil
+		}
+
+		return &ValidationError{
+			Entity:  entity.Entity.Name,
+			Message: fmt.Sprintf("Please give a number of at least %v.", min),
+		}
+	}
+}
+
+// AtMost validates numbers lower than or equal to the given maximum
+func AtMost(max float64) Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		if value, ok := entity.Number(); ok && value <= max {
+			return nil
+		}
+
+		return &ValidationError{
+			Entity:  entity.Entity.Name,
+			Message: fmt.Sprintf("Please give a number of at most %v.", max),
+		}
+	}
+}
+
+// OneOf validates texts that are one of the given values, case-insensitively
+func OneOf(values ...string) Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		if value, ok := entity.Text(); ok {
+			for _, v := range values {
+				if strings.EqualFold(value, v) {
+					return nil
+				}
+			}
+		}
+
+		return &ValidationError{
+			Entity:  entity.Entity.Name,
+			Message: fmt.Sprintf("Please choose one of: %s.", strings.Join(values, ", ")),
+			Options: values,
+		}
+	}
+}
+
+// Matches validates texts matching the given pattern
+func Matches(pattern *regexp.Regexp) Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		if value, ok := entity.Text(); ok && pattern.MatchString(value) {
+			return nil
+		}
+
+		return &ValidationError{
+			Entity:  entity.Entity.Name,
+			Message: fmt.Sprintf("This does not look like a valid %s.", strings.Replace(entity.Entity.Name, "_", " ", -1)),
+		}
+	}
+}
+
+// InFuture validates datetimes that are not over yet: a single datetime must end after now,
+// and an interval must end after now. Intervals without an end, such as "after 8pm", never end.
+func InFuture() Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		var end time.Time
+
+		if value, ok := entity.SingleDateTime(); ok {
+			end = value.Date
+
+			// A day is not over until its very end
+			if value.Granularity == GranularityDay {
+				end = end.AddDate(0, 0, 1)
+			}
+		} else if value, ok := entity.DateTimeInterval(); ok {
+			if value.To == nil {
+				return nil
+			}
+
+			end = value.To.Date
+		}
+
+		if end.After(time.Now()) {
+			return nil
+		}
+
+		return &ValidationError{
+			Entity:  entity.Entity.Name,
+			Message: "Please give a date in the future.",
+		}
+	}
+}
+
+// WithMessage returns the validator, with the given message shown to the user when it fails
+func WithMessage(validator Validator, message string) Validator {
+	return func(entity *ParsedEntity) *ValidationError {
+		err := validator(entity)
+
+		if err != nil {
+			err.Message = message
+		}
+
+		return err
+	}
+}
+
+// NewValidators creates validators from a bot's params, mapping the entities' names to their rules.
+// The rules are "min" and "max" for numbers, "values" and "pattern" for texts, and "future" for
+// datetimes. The optional "message" replaces the default messages shown to the user. For example:
+//
+//	{"nb_persons": {"min": 1, "max": 20, "message": "We can host 1 to 20 persons."}}
+func NewValidators(params map[string]interface{}) (Validators, error) {
+	validators := Validators{}
+
+	for name, value := range params {
+		rules := utils.ToMap(value)
+
+		if rules == nil {
+			log.WithField("entity", name).Info("The validation rules must be an object")
+			return nil, ErrInvalidValidator
+		}
+
+		var entityValidators []Validator
+
+		min, hasMin := utils.ToFloat(rules["min"])
+		max, hasMax := utils.ToFloat(rules["max"])
+
+		if hasMin && hasMax {
+			entityValidators = append(entityValidators, Between(min, max))
+		} else if hasMin {
+			entityValidators = append(entityValidators, AtLeast(min))
+		} else if hasMax {
+			entityValidators = append(entityValidators, AtMost(max))
+		}
+
+		if values, ok := rules["values"].([]interface{}); ok {
+			var options []string
+
+			for _, v := range values {
+				if option, ok := v.(string); ok {
+					options = append(options, option)
+				}
+			}
+
+			entityValidators = append(entityValidators, OneOf(options...))
+		}
+
+		if pattern, ok := rules["pattern"].(string); ok {
+			compiled, err := regexp.Compile(pattern)
+
+			if err != nil {
+				log.WithFields(log.Fields{
+					"entity":  name,
+					"pattern": pattern,
+				}).Infof("Invalid pattern: %s", err)
+				return nil, ErrInvalidValidator
+			}
+
+			entityValidators = append(entityValidators, Matches(compiled))
+		}
+
+		if future, ok := rules["future"].(bool); ok && future {
+			entityValidators = append(entityValidators, InFuture())
+		}
+
+		if message, ok := rules["message"].(string); ok && message != "" {
+			for i, validator := range entityValidators {
+				entityValidators[i] = WithMessage(validator, message)
+			}
+		}
+
+		validators[name] = entityValidators
+	}
+
+	return validators, nil
+}
+
+// Validate validates the data's entities, and removes the invalid ones from the data so that
+// they are not used. Returns the validation errors, in the order of the entities.
+func (validators Validators) Validate(data *ParsedData) []*ValidationError {
+	var errs []*ValidationError
+	var valid []*ParsedEntity
+
+	for _, entity := range data.Entities {
+		err := validators.validateEntity(entity)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"entity": entity.Entity.Name,
+				"value":  entity.Data,
+			}).Debugf("Invalid entity: %s", err)
+			errs = append(errs, err)
+			continue
+		}
+
+		valid = append(valid, entity)
+	}
+
+	data.Entities = valid
+
+	return errs
+}
+
+// validateEntity runs the entity's validators, and returns the first error
+func (validators Validators) validateEntity(entity *ParsedEntity) *ValidationError {
+	for _, validator := range validators[entity.Entity.Name] {
+		if err := validator(entity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
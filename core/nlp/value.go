@@ -0,0 +1,233 @@
+package nlp
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var ErrCouldNotDecodeValue = errors.New("Could not decode the entity's value")
+
+// SingleDateTime is the value of a SingleDateTime entity
+type SingleDateTime struct {
+	Date        time.Time           `json:"date" bson:"date"`
+	Granularity DateTimeGranularity `json:"granularity" bson:"granularity"`
+}
+
+// DateTimeInterval is the value of a DateTimeInterval entity
+type DateTimeInterval struct {
+	From *SingleDateTime `json:"from" bson:"from"`
+	To   *SingleDateTime `json:"to" bson:"to"`
+}
+
+// Money is the value of a Money entity. The currency is the one given by the NLP service,
+// usually an ISO 4217 code such as "EUR". It is empty when unknown.
+type Money struct {
+	Amount   float64 `json:"amount" bson:"amount"`
+	Currency string  `json:"currency" bson:"currency"`
+}
+
+// Location is the value of a Location entity. The name is the place's name, as given by
+// the user, and the coordinates are zero when the NLP service did not resolve them.
+type Location struct {
+	Name      string  `json:"name" bson:"name"`
+	Latitude  float64 `json:"latitude" bson:"latitude"`
+	Longitude float64 `json:"longitude" bson:"longitude"`
+}
+
+// Enum maps the values of an enumeration to their synonyms, such as {"large": ["big", "xl"]}
+type Enum map[string][]string
+
+// Resolve returns the value the text stands for, being either the value itself or one of its synonyms.
+// The comparison is case-insensitive. Returns false if the text does not stand for any value.
+func (enum Enum) Resolve(text string) (string, bool) {
+	var values []string
+
+	for value := range enum {
+		values = append(values, value)
+	}
+
+	// Sorted so that a synonym shared by several values always gives the same one
+	sort.Strings(values)
+	text = strings.TrimSpace(text)
+
+	for _, value := range values {
+		if strings.EqualFold(text, value) {
+			return value, true
+		}
+
+		for _, synonym := range enum[value] {
+			if strings.EqualFold(text, synonym) {
+				return value, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Int returns the value of an Int entity
+func (e *ParsedEntity) Int() (int, bool) {
+	value, ok := e.Data.(int)
+
+	return value, ok
+}
+
+// Number returns the value of a Number or Int entity
+func (e *ParsedEntity) Number() (float64, bool) {
+	switch value := e.Data.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	}
+
+	return 0, false
+}
+
+// Text returns the value of a String, Enum, Email, Phone or Url entity
+func (e *ParsedEntity) Text() (string, bool) {
+	value, ok := e.Data.(string)
+
+	return value, ok
+}
+
+// Money returns the value of a Money entity
+func (e *ParsedEntity) Money() (*Money, bool) {
+	value, ok := e.Data.(*Money)
+
+	return value, ok
+}
+
+// Duration returns the value of a Duration entity
+func (e *ParsedEntity) Duration() (time.Duration, bool) {
+	value, ok := e.Data.(time.Duration)
+
+	return value, ok
+}
+
+// Location returns the value of a Location entity
+func (e *ParsedEntity) Location() (*Location, bool) {
+	value, ok := e.Data.(*Location)
+
+	return value, ok
+}
+
+// SingleDateTime returns the value of a SingleDateTime entity
+func (e *ParsedEntity) SingleDateTime() (*SingleDateTime, bool) {
+	value, ok := e.Data.(*SingleDateTime)
+
+	return value, ok
+}
+
+// DateTimeInterval returns the value of a DateTimeInterval entity
+func (e *ParsedEntity) DateTimeInterval() (*DateTimeInterval, bool) {
+	value, ok := e.Data.(*DateTimeInterval)
+
+	return value, ok
+}
+
+// SetBSON decodes the entity's value, of type interface, to the value of the entity's type,
+// so that the entities loaded from the db can be read like the parsed ones.
+func (e *ParsedEntity) SetBSON(raw bson.Raw) error {
+	decoded := struct {
+		Entity     *Entity  `bson:"entity"`
+		Role       string   `bson:"role"`
+		Group      string   `bson:"group"`
+		Confidence float32  `bson:"confidence"`
+		Data       bson.Raw `bson:"data"`
+	}{}
+
+	if err := raw.Unmarshal(&decoded); err != nil {
+		log.Infof("Could not unmarshal BSON: %s", err)
+		return ErrCouldNotDecodeValue
+	}
+
+	if decoded.Entity == nil {
+		decoded.Entity = &Entity{}
+	}
+
+	data, err := decodeValue(decoded.Entity, decoded.Data)
+
+	if err != nil {
+		log.WithField("entity", decoded.Entity.Name).Infof("Could not decode the value: %s", err)
+		return ErrCouldNotDecodeValue
+	}
+
+	e.Entity = decoded.Entity
+	e.Role = decoded.Role
+	e.Group = decoded.Group
+	e.Confidence = decoded.Confidence
+	e.Data = data
+
+	return nil
+}
+
+// decodeValue decodes the raw value according to the entity's type
+func decodeValue(entity *Entity, raw bson.Raw) (interface{}, error) {
+	// Missing or null value
+	if raw.Kind == 0x00 || raw.Kind == 0x0A {
+		return nil, nil
+	}
+
+	switch entity.Type {
+	case IntEntity:
+		var value int
+		err := raw.Unmarshal(&value)
+
+		return value, err
+	case NumberEntity:
+		var value float64
+		err := raw.Unmarshal(&value)
+
+		return value, err
+	case StringEntity, EnumEntity, EmailEntity, PhoneEntity, UrlEntity:
+		var value string
+		err := raw.Unmarshal(&value)
+
+		return value, err
+	case DurationEntity:
+		var value time.Duration
+		err := raw.Unmarshal(&value)
+
+		return value, err
+	case MoneyEntity:
+		value := &Money{}
+
+		return value, raw.Unmarshal(value)
+	case LocationEntity:
+		value := &Location{}
+
+		return value, raw.Unmarshal(value)
+	case SingleDateTimeEntity:
+		value := &SingleDateTime{}
+
+		return value, raw.Unmarshal(value)
+	case DateTimeIntervalEntity:
+		value := &DateTimeInterval{}
+
+		return value, raw.Unmarshal(value)
+	case DateTimeEntity:
+		// The datetimes stored before they got distinct types can be either one of them
+		interval := &DateTimeInterval{}
+
+		if err := raw.Unmarshal(interval); err == nil && interval.From != nil {
+			entity.Type = DateTimeIntervalEntity
+			return interval, nil
+		}
+
+		entity.Type = SingleDateTimeEntity
+		value := &SingleDateTime{}
+
+		return value, raw.Unmarshal(value)
+	}
+
+	var value interface{}
+	err := raw.Unmarshal(&value)
+
+	return value, err
+}
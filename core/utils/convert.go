@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ToFloat converts a number, as decoded from JSON or BSON, to a float.
+// Returns false if the value is not a number.
+func ToFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+// ToMap converts an object, as decoded from JSON or BSON, to a map.
+// Returns nil if the value is not an object.
+func ToMap(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case bson.M:
+		return map[string]interface{}(v)
+	}
+
+	return nil
+}
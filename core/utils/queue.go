@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"sync"
+)
+
+// SerialQueue runs funcs in the background, one at a time per key and in the order they were pushed,
+// while the funcs of different keys run concurrently. For example, the events of a same user must be
+// handled in order, without blocking the other users.
+type SerialQueue struct {
+	mutex sync.Mutex
+	// pending are the funcs waiting for the running one, by key. A key is only present
+	// while one of its funcs is running.
+	pending map[string][]func()
+}
+
+// NewSerialQueue is the constructor method for SerialQueue
+func NewSerialQueue() *SerialQueue {
+	return &SerialQueue{
+		pending: make(map[string][]func()),
+	}
+}
+
+// Push queues the func, to be run once the key's previous funcs are done. It returns right away.
+func (q *SerialQueue) Push(key string, fn func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if pending, running := q.pending[key]; running {
+		q.pending[key] = append(pending, fn)
+		return
+	}
+
+	q.pending[key] = nil
+
+	go q.run(key, fn)
+}
+
+// run runs the func, then the key's pending funcs until there are none left
+func (q *SerialQueue) run(key string, fn func()) {
+	for fn != nil {
+		fn()
+
+		q.mutex.Lock()
+
+		if pending := q.pending[key]; len(pending) > 0 {
+			fn = pending[0]
+			q.pending[key] = pending[1:]
+		} else {
+			fn = nil
+			delete(q.pending, key)
+		}
+
+		q.mutex.Unlock()
+	}
+}
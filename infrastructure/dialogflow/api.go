@@ -0,0 +1,386 @@
+// Package dialogflow implements NLP objects using Dialogflow (v2 API) as the datasource.
+package dialogflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	defaultBaseUrl      = "https://dialogflow.googleapis.com"
+	defaultLanguageCode = "en"
+	// defaultSessionId is used when the text is parsed outside of any conversation
+	defaultSessionId = "default"
+	// scope is the OAuth2 scope required to use the agent
+	scope = "https://www.googleapis.com/auth/dialogflow"
+)
+
+var (
+	ErrCouldNotMarshalJson = errors.New("Could not marshal JSON object")
+	ErrInvalidStatusCode   = errors.New("Invalid status code returned")
+	ErrInvalidCredentials  = errors.New("Invalid service account credentials")
+)
+
+// dialogflowApi is the struct used to make calls to Dialogflow
+type dialogflowApi struct {
+	client       *http.Client
+	baseUrl      *url.URL
+	tokenSource  oauth2.TokenSource
+	languageCode string
+	parser       *dialogflowParser
+}
+
+// newDialogflowApi creates a new dialogflowApi using the given conf.
+// The API is authenticated using the credentials of a service account allowed to use the agent,
+// and its access tokens are refreshed before they expire (see newTokenSource).
+// The base url can be changed, for example to use a local stand-in.
+func newDialogflowApi(conf utils.BuilderConf) (interface{}, error) {
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	projectId, ok := utils.GetParam(conf, "project_id").(string)
+
+	if !ok || projectId == "" {
+		return nil, utils.ErrInvalidOrMissingParam("project_id")
+	}
+
+	tokenSource, err := newTokenSource(conf, client)
+
+	if err != nil {
+		return nil, err
+	}
+
+	languageCode, ok := utils.GetParam(conf, "language_code").(string)
+
+	if !ok || languageCode == "" {
+		languageCode = defaultLanguageCode
+	}
+
+	rawBaseUrl, ok := utils.GetParam(conf, "base_url").(string)
+
+	if !ok || rawBaseUrl == "" {
+		rawBaseUrl = defaultBaseUrl
+	}
+
+	baseUrl, err := url.Parse(strings.TrimRight(rawBaseUrl, "/") + "/v2/projects/" + projectId + "/agent")
+
+	if err != nil {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	// The bot's data types are optional
+	dataTypes, _ := utils.GetParam(conf, "data_types").(*nlp.DataTypes)
+
+	return &dialogflowApi{
+		client:       client,
+		baseUrl:      baseUrl,
+		tokenSource:  tokenSource,
+		languageCode: languageCode,
+		parser:       newDialogflowParser(dataTypes),
+	}, nil
+}
+
+// GetIntents gets the list of intents from Dialogflow, going through all of the pages
+func (api *dialogflowApi) GetIntents() ([]*nlp.Intent, error) {
+	intents := []*nlp.Intent{}
+	pageToken := ""
+
+	for {
+		envelope := &struct {
+			Intents []struct {
+				DisplayName string `json:"displayName"`
+			} `json:"intents"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+
+		err := api.callApi("GET", api.getListUrl("/intents", pageToken), nil, envelope)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, intent := range envelope.Intents {
+			intents = append(intents, nlp.NewIntent(intent.DisplayName))
+		}
+
+		if envelope.NextPageToken == "" {
+			return intents, nil
+		}
+
+		pageToken = envelope.NextPageToken
+	}
+}
+
+// GetEntities gets the list of entity types from Dialogflow, going through all of the pages.
+// The entities' types are found using the parser's data types, when known.
+func (api *dialogflowApi) GetEntities() ([]*nlp.Entity, error) {
+	entities := []*nlp.Entity{}
+	pageToken := ""
+
+	for {
+		envelope := &struct {
+			EntityTypes []struct {
+				DisplayName string `json:"displayName"`
+			} `json:"entityTypes"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+
+		err := api.callApi("GET", api.getListUrl("/entityTypes", pageToken), nil, envelope)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entityType := range envelope.EntityTypes {
+			dataType, _ := api.parser.dataType(entityType.DisplayName)
+			entities = append(entities, nlp.NewEntity(entityType.DisplayName, dataType))
+		}
+
+		if envelope.NextPageToken == "" {
+			return entities, nil
+		}
+
+		pageToken = envelope.NextPageToken
+	}
+}
+
+// Parse sends the text to Dialogflow's detectIntent endpoint, within the session given by the context,
+// and parses the intent and parameters it returned.
+// This method is required in order to implement the nlp.Api interface.
+func (api *dialogflowApi) Parse(text string, context *nlp.Context) (*nlp.ParsedData, error) {
+	sessionId := defaultSessionId
+	languageCode := api.languageCode
+	queryParams := map[string]interface{}{}
+
+	if context != nil {
+		if context.SessionId != "" {
+			sessionId = context.SessionId
+		}
+
+		if context.Locale != "" {
+			// Dialogflow expects language tags, such as "en-US"
+			languageCode = strings.Replace(context.Locale, "_", "-", -1)
+		}
+
+		if context.Timezone != "" {
+			queryParams["timeZone"] = context.Timezone
+		}
+	}
+
+	payload := map[string]interface{}{
+		"queryInput": map[string]interface{}{
+			"text": map[string]interface{}{
+				"text":         text,
+				"languageCode": languageCode,
+			},
+		},
+		"queryParams": queryParams,
+	}
+
+	var envelope json.RawMessage
+
+	err := api.callApi("POST", api.getDetectIntentUrl(sessionId), payload, &envelope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.parser.ParseNlpData(envelope)
+}
+
+// SessionScoped returns true, as Dialogflow's results depend on the contexts of the session.
+// This method is required in order to implement the nlp.SessionScoped interface.
+func (api *dialogflowApi) SessionScoped() bool {
+	return true
+}
+
+// callApi calls the API given a method, an URL, an optional payload and an envelope. If it is a success,
+// then the data is parsed and stored inside the envelope (using JSON).
+// Returns an error if anything happens or if the status code != 200.
+func (api *dialogflowApi) callApi(method string, u *url.URL, payload interface{}, envelope interface{}) error {
+	var body []byte
+
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+
+		if err != nil {
+			log.WithField("payload", payload).Infof("Could not marshal the payload: %s", err)
+			return ErrCouldNotMarshalJson
+		}
+	}
+
+	request, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+
+	if err != nil {
+		log.WithField("url", u.Path).Infof("Could not create a new request: %s", err)
+		return err
+	}
+
+	token, err := api.tokenSource.Token()
+
+	if err != nil {
+		log.Infof("Could not get an access token: %s", err)
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	token.SetAuthHeader(request)
+
+	response, err := api.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return err
+	}
+
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		log.Infof("Failed to read the response body: %s", err)
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		log.WithFields(log.Fields{
+			"code": response.StatusCode,
+			"body": string(responseBody),
+		}).Info("API returned a non-200 code")
+		return ErrInvalidStatusCode
+	}
+
+	err = json.Unmarshal(responseBody, envelope)
+
+	if err != nil {
+		log.Infof("Failed to unmarshal the response body: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// getDetectIntentUrl returns the url to ping to detect the intent of a text, within the given session
+func (api *dialogflowApi) getDetectIntentUrl(sessionId string) *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + "/sessions/" + url.PathEscape(sessionId) + ":detectIntent")
+
+	return u
+}
+
+// getListUrl returns the url to ping to list the agent's resources, such as "/intents",
+// starting at the given page
+func (api *dialogflowApi) getListUrl(path, pageToken string) *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + path)
+
+	q := u.Query()
+	q.Set("pageSize", "1000")
+
+	if pageToken != "" {
+		q.Set("pageToken", pageToken)
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u
+}
+
+// newTokenSource returns the source of the access tokens used to call the API, using the first of:
+//
+// - "credentials": the JSON key of the service account, as an object or a string
+// - "credentials_file": the path to the JSON key of the service account
+// - "access_token": a static access token, which is not refreshed, for example to use a local stand-in
+// - the Application Default Credentials, such as the service account of the Google Cloud instance
+//
+// The tokens are cached, and refreshed using the given client when they expire.
+func newTokenSource(conf utils.BuilderConf, client *http.Client) (oauth2.TokenSource, error) {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+
+	credentialsJson, err := getCredentialsJson(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if credentialsJson != nil {
+		credentials, err := google.CredentialsFromJSON(ctx, credentialsJson, scope)
+
+		if err != nil {
+			log.Infof("Could not read the service account credentials: %s", err)
+			return nil, ErrInvalidCredentials
+		}
+
+		return credentials.TokenSource, nil
+	}
+
+	if accessToken, ok := utils.GetParam(conf, "access_token").(string); ok && accessToken != "" {
+		log.Warning("Using a static Dialogflow access token: it will not be refreshed once expired")
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken}), nil
+	}
+
+	credentials, err := google.FindDefaultCredentials(ctx, scope)
+
+	if err != nil {
+		log.Infof("Could not find the default credentials: %s", err)
+		return nil, utils.ErrInvalidOrMissingParam("credentials")
+	}
+
+	return credentials.TokenSource, nil
+}
+
+// getCredentialsJson returns the JSON key of the service account, given either as the "credentials"
+// param or as the file located at the "credentials_file" path.
+// Returns nil if there is none.
+func getCredentialsJson(conf utils.BuilderConf) ([]byte, error) {
+	switch credentials := utils.GetParam(conf, "credentials").(type) {
+	case string:
+		if credentials != "" {
+			return []byte(credentials), nil
+		}
+	case nil:
+	default:
+		// The key was decoded as an object, from JSON or BSON
+		data, err := json.Marshal(utils.ToMap(credentials))
+
+		if err != nil {
+			return nil, ErrInvalidCredentials
+		}
+
+		return data, nil
+	}
+
+	path, ok := utils.GetParam(conf, "credentials_file").(string)
+
+	if !ok || path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		log.WithField("path", path).Infof("Could not read the credentials file: %s", err)
+		return nil, ErrInvalidCredentials
+	}
+
+	return data, nil
+}
+
+func init() {
+	nlp.RegisterApiBuilder("dialogflow", newDialogflowApi)
+	nlp.RegisterTextParserBuilder("dialogflow", newDialogflowApi)
+}
@@ -0,0 +1,308 @@
+package dialogflow
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aziule/conversation-management/core/nlp"
+)
+
+// fakeDialogflow is a local stand-in for Dialogflow and for Google's token endpoint. It answers
+// the detectIntent calls using the recorded responses, and issues numbered access tokens.
+type fakeDialogflow struct {
+	server *httptest.Server
+	// tokenTtl is the lifetime of the issued access tokens, in seconds
+	tokenTtl int
+	mutex    sync.Mutex
+	// tokens is the number of access tokens issued so far
+	tokens int
+	// requests are the detectIntent requests received so far
+	requests []*detectIntentRequest
+}
+
+// detectIntentRequest is a detectIntent request received by the fake Dialogflow
+type detectIntentRequest struct {
+	Path          string
+	Authorization string
+	Payload       struct {
+		QueryInput struct {
+			Text struct {
+				Text         string `json:"text"`
+				LanguageCode string `json:"languageCode"`
+			} `json:"text"`
+		} `json:"queryInput"`
+		QueryParams struct {
+			TimeZone string `json:"timeZone"`
+		} `json:"queryParams"`
+	}
+}
+
+// newFakeDialogflow starts a fake Dialogflow, answering the detectIntent calls with the recorded response
+func newFakeDialogflow(t *testing.T, response []byte, tokenTtl int) *fakeDialogflow {
+	fake := &fakeDialogflow{tokenTtl: tokenTtl}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+			t.Errorf("Unexpected token request: %v", r.Form)
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		fake.mutex.Lock()
+		fake.tokens++
+		token := fmt.Sprintf("token-%d", fake.tokens)
+		fake.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":%q,"token_type":"Bearer","expires_in":%d}`, token, fake.tokenTtl)
+	})
+
+	mux.HandleFunc("/v2/projects/booking-agent/agent/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		request := &detectIntentRequest{
+			Path:          r.URL.Path,
+			Authorization: r.Header.Get("Authorization"),
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&request.Payload); err != nil {
+			t.Errorf("Could not decode the detectIntent payload: %s", err)
+		}
+
+		fake.mutex.Lock()
+		fake.requests = append(fake.requests, request)
+		fake.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	})
+
+	fake.server = httptest.NewServer(mux)
+
+	return fake
+}
+
+// issued returns the number of access tokens issued so far
+func (fake *fakeDialogflow) issued() int {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	return fake.tokens
+}
+
+// received returns the detectIntent requests received so far
+func (fake *fakeDialogflow) received() []*detectIntentRequest {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	return fake.requests
+}
+
+// newServiceAccountKey returns the JSON key of a service account, whose tokens are
+// issued by the fake Dialogflow's token endpoint
+func newServiceAccountKey(t *testing.T, fake *fakeDialogflow) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatalf("Could not generate the service account key: %s", err)
+	}
+
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	j, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "booking-agent",
+		"private_key_id": "0123456789abcdef",
+		"private_key":    string(privateKey),
+		"client_email":   "bot@booking-agent.iam.gserviceaccount.com",
+		"client_id":      "123456789",
+		"token_uri":      fake.server.URL + "/token",
+	})
+
+	return string(j)
+}
+
+// newTestApi creates a Dialogflow API calling the fake Dialogflow, authenticated using a service account
+func newTestApi(t *testing.T, fake *fakeDialogflow) *dialogflowApi {
+	built, err := newDialogflowApi(map[string]interface{}{
+		"client":      fake.server.Client(),
+		"project_id":  "booking-agent",
+		"base_url":    fake.server.URL,
+		"credentials": newServiceAccountKey(t, fake),
+		"data_types":  testDataTypes(),
+	})
+
+	if err != nil {
+		t.Fatalf("Could not create the Dialogflow API: %s", err)
+	}
+
+	return built.(*dialogflowApi)
+}
+
+// TestParse checks that the text is sent to the session's detectIntent endpoint, along with the
+// user's locale and timezone, and that the recorded response is parsed
+func TestParse(t *testing.T) {
+	fake := newFakeDialogflow(t, readFile(t, filepath.Join("testdata", "detect_intent_booking.json")), 3600)
+	defer fake.server.Close()
+
+	data, err := newTestApi(t, fake).Parse("A table for 4 people tomorrow at 8pm at Chez Paul", &nlp.Context{
+		SessionId: "5a0b7d0c1f2e",
+		Timezone:  "America/Los_Angeles",
+		Locale:    "en_US",
+	})
+
+	if err != nil {
+		t.Fatalf("Could not parse the text: %s", err)
+	}
+
+	requests := fake.received()
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected a single detectIntent request, got %d", len(requests))
+	}
+
+	request := requests[0]
+
+	if request.Path != "/v2/projects/booking-agent/agent/sessions/5a0b7d0c1f2e:detectIntent" {
+		t.Errorf("Unexpected path: %s", request.Path)
+	}
+
+	if request.Authorization != "Bearer token-1" {
+		t.Errorf("Unexpected authorization: %s", request.Authorization)
+	}
+
+	if request.Payload.QueryInput.Text.Text != "A table for 4 people tomorrow at 8pm at Chez Paul" {
+		t.Errorf("Unexpected text: %s", request.Payload.QueryInput.Text.Text)
+	}
+
+	if request.Payload.QueryInput.Text.LanguageCode != "en-US" {
+		t.Errorf("Unexpected language code: %s", request.Payload.QueryInput.Text.LanguageCode)
+	}
+
+	if request.Payload.QueryParams.TimeZone != "America/Los_Angeles" {
+		t.Errorf("Unexpected timezone: %s", request.Payload.QueryParams.TimeZone)
+	}
+
+	if data.Intent == nil || data.Intent.Intent.Name != "book_table" {
+		t.Fatalf("Unexpected intent: %+v", data.Intent)
+	}
+
+	entities := make(map[string]*nlp.ParsedEntity)
+
+	for _, entity := range data.Entities {
+		entities[entity.Entity.Name+"/"+entity.Role] = entity
+	}
+
+	if entity, ok := entities["nb_persons/"]; !ok || entity.Data != 4 {
+		t.Errorf("Unexpected nb_persons entity: %+v", entity)
+	}
+
+	// The parameters of the contexts are entities as well, unless the query already gives them
+	if _, ok := entities["seating/booking"]; !ok {
+		t.Errorf("Expected the seating parameter of the booking context, got %v", entities)
+	}
+
+	if _, ok := entities["nb_persons/booking"]; ok {
+		t.Errorf("The nb_persons parameter of the booking context should be skipped")
+	}
+}
+
+// TestParseWithoutContext checks that the default session and language code are used
+// when the text is parsed outside of any conversation
+func TestParseWithoutContext(t *testing.T) {
+	fake := newFakeDialogflow(t, readFile(t, filepath.Join("testdata", "detect_intent_fallback.json")), 3600)
+	defer fake.server.Close()
+
+	data, err := newTestApi(t, fake).Parse("blah blah", nil)
+
+	if err != nil {
+		t.Fatalf("Could not parse the text: %s", err)
+	}
+
+	if data.Intent != nil {
+		t.Errorf("The fallback intent should be ignored, got %+v", data.Intent)
+	}
+
+	request := fake.received()[0]
+
+	if request.Path != "/v2/projects/booking-agent/agent/sessions/"+defaultSessionId+":detectIntent" {
+		t.Errorf("Unexpected path: %s", request.Path)
+	}
+
+	if request.Payload.QueryInput.Text.LanguageCode != defaultLanguageCode {
+		t.Errorf("Unexpected language code: %s", request.Payload.QueryInput.Text.LanguageCode)
+	}
+}
+
+// TestTokenCached checks that the access token is reused until it expires
+func TestTokenCached(t *testing.T) {
+	fake := newFakeDialogflow(t, readFile(t, filepath.Join("testdata", "detect_intent_fallback.json")), 3600)
+	defer fake.server.Close()
+
+	dialogflowApi := newTestApi(t, fake)
+
+	for i := 0; i < 3; i++ {
+		if _, err := dialogflowApi.Parse("blah blah", nil); err != nil {
+			t.Fatalf("Could not parse the text: %s", err)
+		}
+	}
+
+	if issued := fake.issued(); issued != 1 {
+		t.Errorf("Expected a single access token, got %d", issued)
+	}
+
+	for _, request := range fake.received() {
+		if request.Authorization != "Bearer token-1" {
+			t.Errorf("Unexpected authorization: %s", request.Authorization)
+		}
+	}
+}
+
+// TestTokenRefresh checks that a new access token is requested once the previous one expired.
+// The tokens are refreshed shortly before they expire: tokens living for a second are already expired.
+func TestTokenRefresh(t *testing.T) {
+	fake := newFakeDialogflow(t, readFile(t, filepath.Join("testdata", "detect_intent_fallback.json")), 1)
+	defer fake.server.Close()
+
+	dialogflowApi := newTestApi(t, fake)
+
+	for i := 0; i < 2; i++ {
+		if _, err := dialogflowApi.Parse("blah blah", nil); err != nil {
+			t.Fatalf("Could not parse the text: %s", err)
+		}
+	}
+
+	if issued := fake.issued(); issued != 2 {
+		t.Fatalf("Expected 2 access tokens, got %d", issued)
+	}
+
+	requests := fake.received()
+
+	if requests[0].Authorization != "Bearer token-1" || requests[1].Authorization != "Bearer token-2" {
+		t.Errorf("Unexpected authorizations: %s, %s", requests[0].Authorization, requests[1].Authorization)
+	}
+}
+
+// TestInvalidCredentials checks that malformed service account keys are rejected
+func TestInvalidCredentials(t *testing.T) {
+	_, err := newDialogflowApi(map[string]interface{}{
+		"client":      http.DefaultClient,
+		"project_id":  "booking-agent",
+		"credentials": `{"type":"service_account"`,
+	})
+
+	if err != ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package dialogflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCouldNotParseJson = errors.New("Could not parse JSON")
+	ErrUnhandledValue    = errors.New("Unhandled parameter value")
+
+	// durationUnits are the units of the @sys.duration parameters. Months and years are approximated.
+	durationUnits = map[string]time.Duration{
+		"s":   time.Second,
+		"min": time.Minute,
+		"h":   time.Hour,
+		"day": 24 * time.Hour,
+		"wk":  7 * 24 * time.Hour,
+		"mo":  30 * 24 * time.Hour,
+		"yr":  365 * 24 * time.Hour,
+	}
+
+	// defaultDataTypeMap is the default data type map to be used with Dialogflow. It is empty, as
+	// the bots' entities are given by the bots' data types (see nlp.DataTypes).
+	// Parameters that are not mapped have their type guessed from their value.
+	defaultDataTypeMap = nlp.DataTypeMap{}
+)
+
+// detectIntentEnvelope is the response of the detectIntent endpoint.
+// More information here: https://cloud.google.com/dialogflow/es/docs/reference/rest/v2/DetectIntentResponse
+type detectIntentEnvelope struct {
+	QueryResult *queryResult `json:"queryResult"`
+}
+
+// queryResult is the result of the detection, also sent to the fulfillment webhooks
+type queryResult struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Intent     *struct {
+		DisplayName string `json:"displayName"`
+		IsFallback  bool   `json:"isFallback"`
+	} `json:"intent"`
+	IntentDetectionConfidence float32 `json:"intentDetectionConfidence"`
+	OutputContexts            []struct {
+		Name       string                 `json:"name"`
+		Parameters map[string]interface{} `json:"parameters"`
+	} `json:"outputContexts"`
+}
+
+// dialogflowParser is the NLP parser for Dialogflow.
+// It implements the nlp.Parser interface.
+type dialogflowParser struct {
+	// dataTypes are the bot's data types, if any
+	dataTypes *nlp.DataTypes
+}
+
+// newParser is the builder of dialogflowParser
+// The bot's data types are given using the optional "data_types" param.
+func newParser(conf utils.BuilderConf) (interface{}, error) {
+	dataTypes, _ := utils.GetParam(conf, "data_types").(*nlp.DataTypes)
+
+	return newDialogflowParser(dataTypes), nil
+}
+
+// newDialogflowParser is the constructor method for dialogflowParser
+func newDialogflowParser(dataTypes *nlp.DataTypes) *dialogflowParser {
+	return &dialogflowParser{
+		dataTypes: dataTypes,
+	}
+}
+
+// dataType returns the type of the entity, using the bot's data types and the default data type map
+func (parser *dialogflowParser) dataType(name string) (nlp.EntityType, bool) {
+	return parser.dataTypes.Lookup(name, defaultDataTypeMap)
+}
+
+// ParseNlpData parses a detectIntent response, or a bare query result, and returns parsed data.
+//
+// The intent is ignored when Dialogflow fell back to its fallback intent. The query's parameters
+// are converted to entities, and so are the parameters of the output contexts that are not
+// already given by the query: their role is the context's name.
+func (parser *dialogflowParser) ParseNlpData(rawData []byte) (*nlp.ParsedData, error) {
+	envelope := &detectIntentEnvelope{}
+	err := json.Unmarshal(rawData, envelope)
+
+	if err != nil {
+		log.WithField("rawData", string(rawData)).Infof("Could not parse JSON: %s", err)
+		return nil, ErrCouldNotParseJson
+	}
+
+	result := envelope.QueryResult
+
+	if result == nil {
+		result = &queryResult{}
+
+		if err := json.Unmarshal(rawData, result); err != nil {
+			return nil, ErrCouldNotParseJson
+		}
+	}
+
+	var intent *nlp.ParsedIntent
+	var entities []*nlp.ParsedEntity
+
+	if result.Intent != nil && !result.Intent.IsFallback && result.Intent.DisplayName != "" {
+		intent = nlp.NewParsedIntent(result.Intent.DisplayName, result.IntentDetectionConfidence)
+	}
+
+	for name, value := range result.Parameters {
+		entities = append(entities, parser.toEntities(name, value, "", result.IntentDetectionConfidence)...)
+	}
+
+	for _, context := range result.OutputContexts {
+		role := contextName(context.Name)
+
+		for name, value := range context.Parameters {
+			// Skip the original texts, and the parameters already given by the query
+			if strings.HasSuffix(name, ".original") {
+				continue
+			}
+
+			if _, ok := result.Parameters[name]; ok {
+				continue
+			}
+
+			entities = append(entities, parser.toEntities(name, value, role, result.IntentDetectionConfidence)...)
+		}
+	}
+
+	return nlp.NewParsedData(intent, entities), nil
+}
+
+// toEntities converts a parameter to built-in NLP representations of entities.
+// Lists give an entity for each of their values, and empty values give no entity.
+func (parser *dialogflowParser) toEntities(name string, value interface{}, role string, confidence float32) []*nlp.ParsedEntity {
+	var entities []*nlp.ParsedEntity
+
+	values, ok := value.([]interface{})
+
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	for _, v := range values {
+		if v == nil || v == "" {
+			continue
+		}
+
+		entity, err := parser.toEntity(name, v, role, confidence)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"name":  name,
+				"value": v,
+			}).Warnf("Could not convert the parameter to an entity: %s", err)
+			continue
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities
+}
+
+// toEntity converts a single parameter value to a built-in NLP representation of an entity.
+// The type of the entity is found using the data type map, or guessed from the value.
+func (parser *dialogflowParser) toEntity(name string, value interface{}, role string, confidence float32) (*nlp.ParsedEntity, error) {
+	dataType, ok := parser.dataType(name)
+
+	if !ok {
+		dataType = guessDataType(value)
+	}
+
+	if dataType == "" {
+		parser.dataTypes.ReportUnknown(name)
+	}
+
+	switch dataType {
+	case nlp.IntEntity:
+		number, ok := value.(float64)
+
+		if !ok || number != math.Trunc(number) {
+			return nil, ErrUnhandledValue
+		}
+
+		return nlp.NewParsedIntEntity(name, confidence, int(number), role), nil
+	case nlp.NumberEntity:
+		number, ok := value.(float64)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		return nlp.NewParsedNumberEntity(name, confidence, number, role), nil
+	case nlp.StringEntity, nlp.EnumEntity, nlp.EmailEntity, nlp.PhoneEntity, nlp.UrlEntity:
+		text, ok := value.(string)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		return nlp.NewParsedTextEntity(name, confidence, text, dataType, role), nil
+	case nlp.MoneyEntity:
+		object, _ := value.(map[string]interface{})
+		amount, ok := object["amount"].(float64)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		currency, _ := object["currency"].(string)
+
+		return nlp.NewParsedMoneyEntity(name, confidence, amount, currency, role), nil
+	case nlp.DurationEntity:
+		object, _ := value.(map[string]interface{})
+		amount, amountOk := object["amount"].(float64)
+		unit, unitOk := durationUnits[fmt.Sprint(object["unit"])]
+
+		if !amountOk || !unitOk {
+			return nil, ErrUnhandledValue
+		}
+
+		return nlp.NewParsedDurationEntity(name, confidence, time.Duration(amount*float64(unit)), role), nil
+	case nlp.DateTimeEntity:
+		return toDateTimeEntity(name, value, role, confidence)
+	}
+
+	return nil, ErrUnhandledValue
+}
+
+// guessDataType guesses the type of the entity from its value: integral numbers are ints, other
+// numbers are numbers, dates, times and periods are datetimes, other texts are strings, and amounts
+// are either money or durations. Returns an empty type if it cannot be guessed.
+func guessDataType(value interface{}) nlp.EntityType {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) {
+			return nlp.IntEntity
+		}
+
+		return nlp.NumberEntity
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return nlp.DateTimeEntity
+		}
+
+		return nlp.StringEntity
+	case map[string]interface{}:
+		for _, key := range []string{"date_time", "startDateTime", "startDate", "startTime"} {
+			if _, ok := v[key]; ok {
+				return nlp.DateTimeEntity
+			}
+		}
+
+		if _, ok := v["amount"]; ok {
+			if _, ok := v["currency"]; ok {
+				return nlp.MoneyEntity
+			}
+
+			return nlp.DurationEntity
+		}
+	}
+
+	return ""
+}
+
+// toDateTimeEntity converts a date, time or period parameter to a datetime entity.
+// Dialogflow does not give any granularity: dates have a day granularity, and the others an hour one.
+func toDateTimeEntity(name string, value interface{}, role string, confidence float32) (*nlp.ParsedEntity, error) {
+	switch v := value.(type) {
+	case string:
+		t, err := utils.ParseTime(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nlp.NewParsedSingleDateTimeEntity(name, confidence, t, nlp.GranularityHour, role), nil
+	case map[string]interface{}:
+		if dateTime, ok := v["date_time"].(string); ok {
+			return toDateTimeEntity(name, dateTime, role, confidence)
+		}
+
+		for _, keys := range [][]string{
+			{"startDateTime", "endDateTime", string(nlp.GranularityHour)},
+			{"startTime", "endTime", string(nlp.GranularityHour)},
+			{"startDate", "endDate", string(nlp.GranularityDay)},
+		} {
+			from, fromOk := v[keys[0]].(string)
+			to, toOk := v[keys[1]].(string)
+
+			if !fromOk || !toOk {
+				continue
+			}
+
+			fromTime, err := utils.ParseTime(from)
+
+			if err != nil {
+				return nil, err
+			}
+
+			toTime, err := utils.ParseTime(to)
+
+			if err != nil {
+				return nil, err
+			}
+
+			granularity := nlp.DateTimeGranularity(keys[2])
+
+			return nlp.NewParsedDateTimeIntervalEntity(name, confidence, fromTime, toTime, granularity, granularity, role), nil
+		}
+	}
+
+	return nil, ErrUnhandledValue
+}
+
+// contextName returns the short name of a context, given its full resource name
+// such as "projects/<project>/agent/sessions/<session>/contexts/<name>"
+func contextName(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+func init() {
+	nlp.RegisterParserBuilder("dialogflow", newParser)
+}
@@ -0,0 +1,137 @@
+package dialogflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aziule/conversation-management/core/nlp"
+)
+
+// update rewrites the golden files using the parser's current output: go test ./infrastructure/dialogflow -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenEntity is the golden representation of a parsed entity, along with its type,
+// which is not serialized within parsed entities
+type goldenEntity struct {
+	Name       string         `json:"name"`
+	Type       nlp.EntityType `json:"type"`
+	Role       string         `json:"role,omitempty"`
+	Confidence float32        `json:"confidence"`
+	Data       interface{}    `json:"data"`
+}
+
+// golden is the golden representation of the parser's output, along with the entities
+// reported as unknown
+type golden struct {
+	Intent   *nlp.ParsedIntent `json:"intent"`
+	Entities []*goldenEntity   `json:"entities"`
+	Unknown  map[string]int    `json:"unknown"`
+}
+
+// testDataTypes returns the data types of the booking bot used by the test cases
+func testDataTypes() *nlp.DataTypes {
+	return nlp.NewDataTypes(nlp.DataTypeMap{
+		"nb_persons":   nlp.IntEntity,
+		"booking_date": nlp.DateTimeEntity,
+		"restaurant":   nlp.EnumEntity,
+	})
+}
+
+// TestParseNlpData parses the detectIntent responses recorded within testdata/,
+// and compares the results to the golden files
+func TestParseNlpData(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("Could not find the recorded responses: %v", err)
+	}
+
+	for _, path := range inputs {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		rawData := readFile(t, path)
+
+		t.Run(name, func(t *testing.T) {
+			dataTypes := testDataTypes()
+			parser, _ := newParser(map[string]interface{}{"data_types": dataTypes})
+
+			data, err := parser.(*dialogflowParser).ParseNlpData(rawData)
+
+			if err != nil {
+				t.Fatalf("Could not parse the NLP data: %s", err)
+			}
+
+			actual := toGolden(data, dataTypes)
+			path := filepath.Join("testdata", name+".golden")
+
+			if *update {
+				if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if expected := readFile(t, path); !bytes.Equal(expected, actual) {
+				t.Errorf("Unexpected parsed data for %s.\nExpected:\n%s\nActual:\n%s", name, expected, actual)
+			}
+		})
+	}
+}
+
+// TestParseNlpDataMalformed checks that malformed JSON is rejected
+func TestParseNlpDataMalformed(t *testing.T) {
+	parser, _ := newParser(map[string]interface{}{})
+
+	if _, err := parser.(*dialogflowParser).ParseNlpData([]byte(`{"queryResult":`)); err != ErrCouldNotParseJson {
+		t.Errorf("Expected ErrCouldNotParseJson, got %v", err)
+	}
+}
+
+// toGolden returns the golden representation of the parsed data. The parameters are not
+// ordered by Dialogflow, so the entities are sorted by name and role.
+func toGolden(data *nlp.ParsedData, dataTypes *nlp.DataTypes) []byte {
+	g := &golden{
+		Intent:   data.Intent,
+		Entities: []*goldenEntity{},
+		Unknown:  dataTypes.Unknown(),
+	}
+
+	for _, entity := range data.Entities {
+		g.Entities = append(g.Entities, &goldenEntity{
+			Name:       entity.Entity.Name,
+			Type:       entity.Entity.Type,
+			Role:       entity.Role,
+			Confidence: entity.Confidence,
+			Data:       entity.Data,
+		})
+	}
+
+	sort.SliceStable(g.Entities, func(i, j int) bool {
+		if g.Entities[i].Name != g.Entities[j].Name {
+			return g.Entities[i].Name < g.Entities[j].Name
+		}
+
+		return g.Entities[i].Role < g.Entities[j].Role
+	})
+
+	j, _ := json.MarshalIndent(g, "", "  ")
+
+	return append(j, '\n')
+}
+
+// readFile returns the content of the file, failing the test if it cannot be read
+func readFile(t *testing.T, path string) []byte {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Could not read %s: %s", path, err)
+	}
+
+	return content
+}
@@ -0,0 +1,40 @@
+package dialogflow
+
+import (
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+// dialogflowRepository is the struct used to access data from Dialogflow
+type dialogflowRepository struct {
+	api nlp.Api
+}
+
+// newDialogflowRepository instantiates a new dialogflowRepository using the given conf
+func newDialogflowRepository(conf utils.BuilderConf) (interface{}, error) {
+	api, ok := utils.GetParam(conf, "api").(nlp.Api)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("api")
+	}
+
+	return &dialogflowRepository{
+		api: api,
+	}, nil
+}
+
+// GetIntents is the method returning all of the available intents.
+// It is required in order to implement the nlp.Repository interface.
+func (repository *dialogflowRepository) GetIntents() ([]*nlp.Intent, error) {
+	return repository.api.GetIntents()
+}
+
+// GetEntities is the method returning all of the available entities.
+// It is required in order to implement the nlp.Repository interface.
+func (repository *dialogflowRepository) GetEntities() ([]*nlp.Entity, error) {
+	return repository.api.GetEntities()
+}
+
+func init() {
+	nlp.RegisterRepositoryBuilder("dialogflow", newDialogflowRepository)
+}
@@ -2,18 +2,46 @@
 package facebook
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/aziule/conversation-management/core/api"
 	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRateLimit is the default number of requests per second sent for a single page
+	defaultRateLimit = 10.0
+	// defaultRateLimitBurst is the default number of requests that can be sent at once for a single page
+	defaultRateLimitBurst = 20
+	// defaultMaxRetries is the default number of retries on transient failures
+	defaultMaxRetries = 3
+	// initialBackoff is the delay before the first retry. It is doubled on every retry.
+	initialBackoff = 500 * time.Millisecond
+)
+
+var (
+	ErrInvalidRateLimit  = errors.New("Invalid rate limit: it must be a number of requests per second greater than 0")
+	ErrInvalidMaxRetries = errors.New("Invalid max retries: it must be a whole number greater than or equal to 0")
 )
 
 // facebookApi is the real-world implementation of the API
 type facebookApi struct {
+	// tokenMutex protects the page access token, which can be rotated at runtime
+	tokenMutex      sync.RWMutex
 	pageAccessToken string
 	client          *http.Client
 	baseUrl         *url.URL
+	limiter         *tokenBucket
+	maxRetries      int
 }
 
 // newFacebookApi is the constructor that creates a new Facebook API, using
@@ -37,6 +65,43 @@ func newFacebookApi(conf utils.BuilderConf) (interface{}, error) {
 		return nil, utils.ErrInvalidOrMissingParam("client")
 	}
 
+	// The rate limit and the number of retries are optional, and can be decoded from JSON or BSON
+	rateLimit := defaultRateLimit
+
+	if value := utils.GetParam(conf, "rate_limit"); value != nil {
+		rate, ok := utils.ToFloat(value)
+
+		if !ok || rate <= 0 {
+			return nil, ErrInvalidRateLimit
+		}
+
+		rateLimit = rate
+	}
+
+	maxRetries := defaultMaxRetries
+
+	if value := utils.GetParam(conf, "max_retries"); value != nil {
+		retries, ok := utils.ToFloat(value)
+
+		if !ok || retries < 0 || retries != float64(int(retries)) {
+			return nil, ErrInvalidMaxRetries
+		}
+
+		maxRetries = int(retries)
+	}
+
+	if client.Timeout == 0 {
+		log.Warning("The HTTP client used by the Facebook API has no timeout")
+	}
+
+	// The rate limiter is shared by the page, identified by its id when available
+	// so that rotating the token does not reset the limiter.
+	pageKey, ok := utils.GetParam(conf, "page_id").(string)
+
+	if !ok || pageKey == "" {
+		pageKey = pageAccessToken
+	}
+
 	rawBaseUrl := "https://graph.facebook.com/v" + version
 	baseUrl, _ := url.Parse(rawBaseUrl)
 
@@ -44,9 +109,28 @@ func newFacebookApi(conf utils.BuilderConf) (interface{}, error) {
 		pageAccessToken: pageAccessToken,
 		client:          client,
 		baseUrl:         baseUrl,
+		limiter:         getPageLimiter(pageKey, rateLimit, defaultRateLimitBurst),
+		maxRetries:      maxRetries,
 	}, nil
 }
 
+// SetPageAccessToken replaces the page access token used to call the Graph API,
+// for example when the token is rotated. It is safe to call it concurrently.
+func (api *facebookApi) SetPageAccessToken(pageAccessToken string) {
+	api.tokenMutex.Lock()
+	defer api.tokenMutex.Unlock()
+
+	api.pageAccessToken = pageAccessToken
+}
+
+// getPageAccessToken returns the current page access token
+func (api *facebookApi) getPageAccessToken() string {
+	api.tokenMutex.RLock()
+	defer api.tokenMutex.RUnlock()
+
+	return api.pageAccessToken
+}
+
 // @todo: store it and avoid recreating it every time
 // getSendTextUrl returns the url to ping to send text messages to a user
 func (api *facebookApi) getSendTextUrl() *url.URL {
@@ -55,13 +139,195 @@ func (api *facebookApi) getSendTextUrl() *url.URL {
 	u, _ := url.Parse(baseUrl.String() + "/me/messages")
 
 	q := u.Query()
-	q.Set("access_token", api.pageAccessToken)
+	q.Set("access_token", api.getPageAccessToken())
 
 	u.RawQuery = q.Encode()
 
 	return u
 }
 
+// @todo: store it and avoid recreating it every time
+// getMessengerProfileUrl returns the url to ping to manage the page's Messenger profile
+func (api *facebookApi) getMessengerProfileUrl() *url.URL {
+	baseUrl := api.baseUrl
+
+	u, _ := url.Parse(baseUrl.String() + "/me/messenger_profile")
+
+	q := u.Query()
+	q.Set("access_token", api.getPageAccessToken())
+
+	u.RawQuery = q.Encode()
+
+	return u
+}
+
+// @todo: store it and avoid recreating it every time
+// getThreadControlUrl returns the url to ping to pass or take the thread control
+func (api *facebookApi) getThreadControlUrl(action string) *url.URL {
+	baseUrl := api.baseUrl
+
+	u, _ := url.Parse(baseUrl.String() + "/me/" + action)
+
+	q := u.Query()
+	q.Set("access_token", api.getPageAccessToken())
+
+	u.RawQuery = q.Encode()
+
+	return u
+}
+
+// callApi calls the Graph API given a method, an URL and an optional payload, sent as JSON.
+// If it is a success and an envelope is provided, then the response is parsed and stored inside
+// the envelope (using JSON).
+//
+// Requests are rate limited per page, and retried using an exponential backoff when they
+// can safely be sent again (see isRetryable). Graph API errors are returned as *api.GraphError.
+func (fbApi *facebookApi) callApi(method string, u *url.URL, payload interface{}, envelope interface{}) error {
+	var body []byte
+
+	if payload != nil {
+		jsonObject, err := json.Marshal(payload)
+
+		if err != nil {
+			log.WithField("payload", payload).Infof("Could not marshal the payload: %s", err)
+			return ErrCouldNotMarshalJson
+		}
+
+		body = jsonObject
+	}
+
+	backoff := initialBackoff
+
+	for attempt := 0; ; attempt++ {
+		fbApi.limiter.Wait()
+
+		responseBody, err := fbApi.doRequest(method, u, body)
+
+		if err == nil {
+			if envelope == nil {
+				return nil
+			}
+
+			err = json.Unmarshal(responseBody, envelope)
+
+			if err != nil {
+				log.Infof("Failed to unmarshal the response body: %s", err)
+				return err
+			}
+
+			return nil
+		}
+
+		if !isRetryable(method, err) || attempt >= fbApi.maxRetries {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"attempt": attempt + 1,
+			"backoff": backoff,
+		}).Infof("Failure when calling the Graph API, retrying: %s", err)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// doRequest sends a single request to the Graph API and returns the response body.
+// Returns an *api.GraphError if the status code != 200.
+func (fbApi *facebookApi) doRequest(method string, u *url.URL, body []byte) ([]byte, error) {
+	request, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+
+	if err != nil {
+		log.WithField("url", u.Path).Infof("Could not create a new request: %s", err)
+		return nil, err
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := fbApi.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		log.Infof("Failed to read the response body: %s", err)
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		graphError := decodeGraphError(response.StatusCode, responseBody)
+
+		log.WithFields(log.Fields{
+			"code":      graphError.Code,
+			"subcode":   graphError.Subcode,
+			"fbTraceId": graphError.FbTraceId,
+		}).Infof("Graph API returned a non-200 code: %s", graphError.Message)
+
+		return nil, graphError
+	}
+
+	return responseBody, nil
+}
+
+// graphErrorEnvelope is the JSON envelope returned by the Graph API in case of error
+type graphErrorEnvelope struct {
+	Error struct {
+		Message     string `json:"message"`
+		Type        string `json:"type"`
+		Code        int    `json:"code"`
+		Subcode     int    `json:"error_subcode"`
+		FbTraceId   string `json:"fbtrace_id"`
+		IsTransient bool   `json:"is_transient"`
+	} `json:"error"`
+}
+
+// decodeGraphError decodes the error returned by the Graph API.
+// If the body cannot be decoded, a generic error is returned.
+func decodeGraphError(statusCode int, body []byte) *api.GraphError {
+	envelope := &graphErrorEnvelope{}
+
+	if err := json.Unmarshal(body, envelope); err != nil {
+		return api.NewGraphError(statusCode, 0, 0, "", string(body), "", false)
+	}
+
+	e := envelope.Error
+
+	return api.NewGraphError(statusCode, e.Code, e.Subcode, e.Type, e.Message, e.FbTraceId, e.IsTransient)
+}
+
+// isRetryable tells whether a request that failed with the given error can be sent again.
+// The requests rejected by the rate limits were not processed, so they can always be sent again.
+// Otherwise, only the GET and DELETE requests are sent again on transient failures, such as
+// timeouts: a message may have been delivered even though its request failed, and sending it
+// again would deliver it twice.
+func isRetryable(method string, err error) bool {
+	graphError, isGraphError := err.(*api.GraphError)
+
+	if isGraphError && graphError.IsRateLimited() {
+		return true
+	}
+
+	if method != http.MethodGet && method != http.MethodDelete {
+		return false
+	}
+
+	if isGraphError {
+		return graphError.Retryable()
+	}
+
+	_, isNetworkError := err.(net.Error)
+
+	return isNetworkError
+}
+
 func init() {
 	api.RegisterFacebookApiBuilder("facebook", newFacebookApi)
 }
@@ -0,0 +1,55 @@
+package facebook
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PassThreadControl is the FacebookApi's interface method responsible for passing the
+// thread control to another app, such as the Page Inbox.
+// More information here: https://developers.facebook.com/docs/messenger-platform/handover-protocol
+func (fbApi *facebookApi) PassThreadControl(recipientId, targetAppId, metadata string) error {
+	envelope := &threadControlEnvelope{
+		Recipient:   newRecipientEnvelope(recipientId),
+		TargetAppId: json.Number(targetAppId),
+		Metadata:    metadata,
+	}
+
+	err := fbApi.callApi("POST", fbApi.getThreadControlUrl("pass_thread_control"), envelope, nil)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"recipientId": recipientId,
+			"targetAppId": targetAppId,
+		}).Infof("Could not pass the thread control: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// TakeThreadControl is the FacebookApi's interface method responsible for taking the
+// thread control back from another app. The app must be the Primary Receiver.
+func (fbApi *facebookApi) TakeThreadControl(recipientId, metadata string) error {
+	envelope := &threadControlEnvelope{
+		Recipient: newRecipientEnvelope(recipientId),
+		Metadata:  metadata,
+	}
+
+	err := fbApi.callApi("POST", fbApi.getThreadControlUrl("take_thread_control"), envelope, nil)
+
+	if err != nil {
+		log.WithField("recipientId", recipientId).Infof("Could not take the thread control: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// threadControlEnvelope is the JSON envelope that needs to be sent to pass or take the thread control
+type threadControlEnvelope struct {
+	Recipient   *recipientEnvelope `json:"recipient"`
+	TargetAppId json.Number        `json:"target_app_id,omitempty"`
+	Metadata    string             `json:"metadata,omitempty"`
+}
@@ -0,0 +1,75 @@
+package facebook
+
+import (
+	"strings"
+
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetMessengerProfile is the FacebookApi's interface method responsible for reading
+// the page's Messenger profile
+func (fbApi *facebookApi) GetMessengerProfile() (*api.MessengerProfile, error) {
+	u := fbApi.getMessengerProfileUrl()
+
+	q := u.Query()
+	q.Set("fields", strings.Join([]string{
+		api.ProfileFieldGetStarted,
+		api.ProfileFieldPersistentMenu,
+		api.ProfileFieldGreeting,
+		api.ProfileFieldWhitelistedDomains,
+	}, ","))
+
+	u.RawQuery = q.Encode()
+
+	envelope := &messengerProfileEnvelope{}
+	err := fbApi.callApi("GET", u, nil, envelope)
+
+	if err != nil {
+		log.Infof("Could not get the Messenger profile: %s", err)
+		return nil, err
+	}
+
+	// The API returns an empty data array when no field is set
+	if len(envelope.Data) == 0 {
+		return &api.MessengerProfile{}, nil
+	}
+
+	return envelope.Data[0], nil
+}
+
+// SetMessengerProfile is the FacebookApi's interface method responsible for setting
+// the page's Messenger profile. Only the fields that are set are updated.
+func (fbApi *facebookApi) SetMessengerProfile(profile *api.MessengerProfile) error {
+	err := fbApi.callApi("POST", fbApi.getMessengerProfileUrl(), profile, nil)
+
+	if err != nil {
+		log.WithField("profile", profile).Infof("Could not set the Messenger profile: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteMessengerProfileFields is the FacebookApi's interface method responsible for
+// deleting some fields of the page's Messenger profile
+func (fbApi *facebookApi) DeleteMessengerProfileFields(fields []string) error {
+	err := fbApi.callApi("DELETE", fbApi.getMessengerProfileUrl(), &deleteProfileFieldsEnvelope{fields}, nil)
+
+	if err != nil {
+		log.WithField("fields", fields).Infof("Could not delete the Messenger profile fields: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// messengerProfileEnvelope is the JSON envelope returned when reading the Messenger profile
+type messengerProfileEnvelope struct {
+	Data []*api.MessengerProfile `json:"data"`
+}
+
+// deleteProfileFieldsEnvelope is the JSON envelope that needs to be sent to delete profile fields
+type deleteProfileFieldsEnvelope struct {
+	Fields []string `json:"fields"`
+}
@@ -0,0 +1,84 @@
+package facebook
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// pageLimiters stores the rate limiters of each page, identified by their id or access token,
+	// so that every API created for the same page shares the same limiter.
+	pageLimiters      = make(map[string]*tokenBucket)
+	pageLimitersMutex sync.Mutex
+)
+
+// tokenBucket is a token-bucket rate limiter. Tokens are added at a fixed rate,
+// up to the bucket's capacity, and every request consumes one token.
+type tokenBucket struct {
+	mutex    sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// newTokenBucket is the constructor method for tokenBucket.
+// The rate is expressed in tokens per second.
+func newTokenBucket(rate float64, capacity int) *tokenBucket {
+	return &tokenBucket{
+		rate:     rate,
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		last:     time.Now(),
+	}
+}
+
+// getPageLimiter returns the rate limiter of the page, creating it if needed
+func getPageLimiter(pageKey string, rate float64, capacity int) *tokenBucket {
+	pageLimitersMutex.Lock()
+	defer pageLimitersMutex.Unlock()
+
+	limiter, ok := pageLimiters[pageKey]
+
+	if !ok {
+		limiter = newTokenBucket(rate, capacity)
+		pageLimiters[pageKey] = limiter
+	}
+
+	return limiter
+}
+
+// Wait blocks until a token is available, and consumes it
+func (b *tokenBucket) Wait() {
+	for {
+		delay := b.reserve()
+
+		if delay == 0 {
+			return
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+// reserve tries to consume a token. Returns 0 if it succeeded, or the time
+// to wait before a token is available.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	b.last = now
+
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/antonholmquist/jason"
@@ -20,8 +21,10 @@ var (
 	ErrNoMessage               = errors.New("No message to parse")
 )
 
-// ParseJsonBody creates a Message from json bytes and returns an error if a parsing issue occurred
-func (fbApi *facebookApi) ParseRequestMessageReceived(r *http.Request) (*api.FacebookReceivedMessage, error) {
+// ParseRequestMessagesReceived parses the events of every entry of the request, in order. Facebook can
+// batch many events within a single request. The events that cannot be parsed are logged and skipped.
+// Returns an error if the request itself cannot be parsed, or if it does not contain any event.
+func (fbApi *facebookApi) ParseRequestMessagesReceived(r *http.Request) ([]*api.FacebookReceivedMessage, error) {
 	body, err := ioutil.ReadAll(r.Body)
 	defer r.Body.Close()
 
@@ -50,29 +53,46 @@ func (fbApi *facebookApi) ParseRequestMessageReceived(r *http.Request) (*api.Fac
 		return nil, ErrNoEntry
 	}
 
-	entry := entries[0]
+	var messages []*api.FacebookReceivedMessage
 
-	messaging, err := entry.GetObjectArray("messaging")
+	for _, entry := range entries {
+		// Messages received while another app owns the thread (Handover Protocol)
+		// are delivered within the "standby" array instead of the "messaging" one
+		standby := false
+		messaging, err := entry.GetObjectArray("messaging")
 
-	if err != nil {
-		log.WithField("key", "messaging").Info("Missing key")
-		return nil, ErrMissingKey("messaging")
+		if err != nil {
+			messaging, err = entry.GetObjectArray("standby")
+			standby = true
+		}
+
+		if err != nil {
+			log.WithField("key", "messaging").Info("Missing key: skipping the entry")
+			continue
+		}
+
+		for _, messageData := range messaging {
+			message, err := parseMessagingEvent(messageData, standby)
+
+			if err != nil {
+				log.Infof("Could not parse the event: %s", err)
+				continue
+			}
+
+			messages = append(messages, message)
+		}
 	}
 
-	if len(messaging) == 0 {
+	if len(messages) == 0 {
 		log.Info("No message to parse")
 		return nil, ErrNoMessage
 	}
 
-	messageData := messaging[0]
-
-	mid, err := messageData.GetString("message", "mid")
-
-	if err != nil {
-		log.WithField("key", "message.id").Info("Missing key")
-		return nil, ErrMissingKey("message.id")
-	}
+	return messages, nil
+}
 
+// parseMessagingEvent parses a single event of an entry's "messaging" or "standby" array
+func parseMessagingEvent(messageData *jason.Object, standby bool) (*api.FacebookReceivedMessage, error) {
 	senderId, err := messageData.GetString("sender", "id")
 
 	if err != nil {
@@ -97,8 +117,64 @@ func (fbApi *facebookApi) ParseRequestMessageReceived(r *http.Request) (*api.Fac
 	// Get the number of seconds
 	sentAt = sentAt / 1000
 
+	if threadControl, event := parseThreadControl(messageData); threadControl != nil {
+		return &api.FacebookReceivedMessage{
+			Event:         event,
+			SenderId:      senderId,
+			RecipientId:   recipientId,
+			SentAt:        time.Unix(sentAt, 0),
+			Standby:       standby,
+			ThreadControl: threadControl,
+		}, nil
+	}
+
+	if accountLinking, err := messageData.GetObject("account_linking"); err == nil {
+		status, _ := accountLinking.GetString("status")
+		authorizationCode, _ := accountLinking.GetString("authorization_code")
+
+		return &api.FacebookReceivedMessage{
+			Event:       api.FacebookEventAccountLinking,
+			SenderId:    senderId,
+			RecipientId: recipientId,
+			SentAt:      time.Unix(sentAt, 0),
+			AccountLinking: &api.FacebookAccountLinking{
+				Status:            status,
+				AuthorizationCode: authorizationCode,
+			},
+		}, nil
+	}
+
+	// Postbacks are sent when the user presses a button, such as the Get Started button.
+	// They have no message id.
+	if postback, err := messageData.GetObject("postback"); err == nil {
+		title, _ := postback.GetString("title")
+		payload, err := postback.GetString("payload")
+
+		if err != nil {
+			log.WithField("key", "postback.payload").Info("Missing key")
+			return nil, ErrMissingKey("postback.payload")
+		}
+
+		return &api.FacebookReceivedMessage{
+			Event:           api.FacebookEventPostback,
+			SenderId:        senderId,
+			RecipientId:     recipientId,
+			SentAt:          time.Unix(sentAt, 0),
+			Text:            title,
+			PostbackPayload: payload,
+			Standby:         standby,
+		}, nil
+	}
+
+	mid, err := messageData.GetString("message", "mid")
+
+	if err != nil {
+		log.WithField("key", "message.id").Info("Missing key")
+		return nil, ErrMissingKey("message.id")
+	}
+
 	text, _ := messageData.GetString("message", "text")
-	quickReplyPayload, _ := messageData.GetString("quick_reply", "payload")
+	quickReplyPayload, _ := messageData.GetString("message", "quick_reply", "payload")
 
 	nlp, err := messageData.GetObject("message", "nlp", "entities")
 	var nlpBytes []byte
@@ -114,6 +190,7 @@ func (fbApi *facebookApi) ParseRequestMessageReceived(r *http.Request) (*api.Fac
 	}
 
 	return &api.FacebookReceivedMessage{
+		Event:             api.FacebookEventMessage,
 		Mid:               mid,
 		SenderId:          senderId,
 		RecipientId:       recipientId,
@@ -121,5 +198,44 @@ func (fbApi *facebookApi) ParseRequestMessageReceived(r *http.Request) (*api.Fac
 		Text:              text,
 		QuickReplyPayload: quickReplyPayload,
 		Nlp:               nlpBytes,
+		Standby:           standby,
 	}, nil
 }
+
+// parseThreadControl parses the Handover Protocol events (pass_thread_control
+// and take_thread_control) from the messaging data.
+// Returns nil if the messaging data is not a thread control event.
+func parseThreadControl(messageData *jason.Object) (*api.FacebookThreadControl, api.FacebookEventType) {
+	if passed, err := messageData.GetObject("pass_thread_control"); err == nil {
+		metadata, _ := passed.GetString("metadata")
+
+		return &api.FacebookThreadControl{
+			NewOwnerAppId: getAppId(passed, "new_owner_app_id"),
+			Metadata:      metadata,
+		}, api.FacebookEventPassThreadControl
+	}
+
+	if taken, err := messageData.GetObject("take_thread_control"); err == nil {
+		metadata, _ := taken.GetString("metadata")
+
+		return &api.FacebookThreadControl{
+			PreviousOwnerAppId: getAppId(taken, "previous_owner_app_id"),
+			Metadata:           metadata,
+		}, api.FacebookEventTakeThreadControl
+	}
+
+	return nil, ""
+}
+
+// getAppId returns an app id, which can either be sent as a string or as a number
+func getAppId(object *jason.Object, key string) string {
+	if appId, err := object.GetString(key); err == nil {
+		return appId
+	}
+
+	if appId, err := object.GetInt64(key); err == nil {
+		return strconv.FormatInt(appId, 10)
+	}
+
+	return ""
+}
@@ -1,13 +1,9 @@
 package facebook
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io/ioutil"
-	"net/http"
 
+	"github.com/aziule/conversation-management/core/api"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -15,53 +11,75 @@ var (
 	ErrCouldNotMarshalJson = errors.New("Could not marshal JSON object")
 )
 
-// SendTextToUser is the FacebookApi's interface method responsible for sending a 1-to-1 message to a user
-func (api *facebookApi) SendTextToUser(recipientId, text string) error {
-	url := api.getSendTextUrl()
+// SendTextToUser is the FacebookApi's interface method responsible for sending a 1-to-1 message to a user.
+// The message is sent as a response when no options are given.
+func (fbApi *facebookApi) SendTextToUser(recipientId, text string, options *api.SendOptions) error {
+	if options == nil {
+		options = api.NewResponseSendOptions()
+	}
 
-	object := newTextToUserEnvelope(recipientId, text)
-	jsonObject, err := json.Marshal(object)
+	err := fbApi.callApi("POST", fbApi.getSendTextUrl(), newTextToUserEnvelope(recipientId, text, options), nil)
 
 	if err != nil {
 		log.WithFields(log.Fields{
 			"recipientId": recipientId,
 			"text":        text,
-		}).Infof("Could not send the message to the user due to a JSON marshal issue: %s", err)
-		return ErrCouldNotMarshalJson
+		}).Infof("Could not send the message to the user: %s", err)
+		return err
 	}
 
-	request, err := http.NewRequest("POST", url.String(), bytes.NewBuffer(jsonObject))
+	return nil
+}
 
-	if err != nil {
-		log.WithFields(log.Fields{
-			"url":        url.String(),
-			"jsonObject": string(jsonObject),
-		}).Infof("Could not create a new request: %s", err)
-		return err
+// SendQuickRepliesToUser is the FacebookApi's interface method responsible for sending a 1-to-1 message
+// to a user, along with quick replies. Only the first FacebookMaxQuickReplies quick replies are sent.
+// The message is sent as a response when no options are given.
+func (fbApi *facebookApi) SendQuickRepliesToUser(recipientId, text string, quickReplies []*api.FacebookQuickReply, options *api.SendOptions) error {
+	if options == nil {
+		options = api.NewResponseSendOptions()
 	}
 
-	request.Header.Set("Content-Type", "application/json")
+	if len(quickReplies) > api.FacebookMaxQuickReplies {
+		quickReplies = quickReplies[:api.FacebookMaxQuickReplies]
+	}
 
-	client := http.DefaultClient
-	response, err := client.Do(request)
+	envelope := newTextToUserEnvelope(recipientId, text, options)
+
+	for _, quickReply := range quickReplies {
+		envelope.Message.QuickReplies = append(envelope.Message.QuickReplies, &quickReplyEnvelope{
+			ContentType: "text",
+			Title:       truncate(quickReply.Title, api.FacebookMaxQuickReplyTitle),
+			Payload:     quickReply.Payload,
+		})
+	}
+
+	err := fbApi.callApi("POST", fbApi.getSendTextUrl(), envelope, nil)
 
 	if err != nil {
-		log.Infof("Failed to send the request: %s", err)
+		log.WithFields(log.Fields{
+			"recipientId":  recipientId,
+			"text":         text,
+			"quickReplies": len(quickReplies),
+		}).Infof("Could not send the message to the user: %s", err)
 		return err
 	}
 
-	defer response.Body.Close()
+	return nil
+}
 
-	body, err := ioutil.ReadAll(response.Body)
+// SendSenderAction is the FacebookApi's interface method responsible for sending a sender action
+// (mark_seen, typing_on, typing_off) to a user's thread
+func (fbApi *facebookApi) SendSenderAction(recipientId string, action api.SenderAction) error {
+	err := fbApi.callApi("POST", fbApi.getSendTextUrl(), newSenderActionEnvelope(recipientId, action), nil)
 
 	if err != nil {
-		log.Infof("Failed to read the response body: %s", err)
+		log.WithFields(log.Fields{
+			"recipientId": recipientId,
+			"action":      action,
+		}).Infof("Could not send the sender action: %s", err)
 		return err
 	}
 
-	// @todo: handle errors
-	fmt.Println(string(body))
-
 	return nil
 }
 
@@ -70,15 +88,25 @@ type recipientEnvelope struct {
 	Id string `json:"id"`
 }
 
-// messageEnvelope represents the envelope for a message with text
+// messageEnvelope represents the envelope for a message with text, and its quick replies if any
 type messageEnvelope struct {
-	Text string `json:"text"`
+	Text         string                `json:"text"`
+	QuickReplies []*quickReplyEnvelope `json:"quick_replies,omitempty"`
+}
+
+// quickReplyEnvelope represents the envelope for a quick reply
+type quickReplyEnvelope struct {
+	ContentType string `json:"content_type"`
+	Title       string `json:"title"`
+	Payload     string `json:"payload"`
 }
 
 // textToUserEnvelope is the JSON envelope that needs to be sent
 type textToUserEnvelope struct {
-	Recipient *recipientEnvelope `json:"recipient"`
-	Message   *messageEnvelope   `json:"message"`
+	MessagingType api.MessagingType  `json:"messaging_type"`
+	Tag           api.MessageTag     `json:"tag,omitempty"`
+	Recipient     *recipientEnvelope `json:"recipient"`
+	Message       *messageEnvelope   `json:"message"`
 }
 
 // newRecipientEnvelope is the constructor for a recipientEnvelope
@@ -96,8 +124,10 @@ func newMessageEnvelope(text string) *messageEnvelope {
 }
 
 // newTextToUserEnvelope is the constructor for a textToUserEnvelope
-func newTextToUserEnvelope(recipientId, text string) *textToUserEnvelope {
+func newTextToUserEnvelope(recipientId, text string, options *api.SendOptions) *textToUserEnvelope {
 	return &textToUserEnvelope{
+		MessagingType: options.MessagingType,
+		Tag:           options.Tag,
 		Recipient: &recipientEnvelope{
 			Id: recipientId,
 		},
@@ -106,3 +136,28 @@ func newTextToUserEnvelope(recipientId, text string) *textToUserEnvelope {
 		},
 	}
 }
+
+// senderActionEnvelope is the JSON envelope that needs to be sent for sender actions
+type senderActionEnvelope struct {
+	Recipient    *recipientEnvelope `json:"recipient"`
+	SenderAction api.SenderAction   `json:"sender_action"`
+}
+
+// newSenderActionEnvelope is the constructor for a senderActionEnvelope
+func newSenderActionEnvelope(recipientId string, action api.SenderAction) *senderActionEnvelope {
+	return &senderActionEnvelope{
+		Recipient:    newRecipientEnvelope(recipientId),
+		SenderAction: action,
+	}
+}
+
+// truncate truncates the text to the given number of characters
+func truncate(text string, max int) string {
+	runes := []rune(text)
+
+	if len(runes) <= max {
+		return text
+	}
+
+	return string(runes[:max])
+}
@@ -0,0 +1,120 @@
+// Package file provides repositories storing their data within JSON files.
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrInvalidBot = errors.New("Invalid bot slug")
+
+// dataTypeRepository is the unexported struct that implements the nlp.DataTypeRepository interface.
+// The data type map of each bot is stored within the directory, as "<bot slug>.json".
+type dataTypeRepository struct {
+	mutex sync.Mutex
+	dir   string
+}
+
+// newDataTypeRepository creates a new data type repository storing the data type maps
+// within the "dir" directory. The directory is created if it does not exist.
+func newDataTypeRepository(conf utils.BuilderConf) (interface{}, error) {
+	dir, ok := utils.GetParam(conf, "dir").(string)
+
+	if !ok || dir == "" {
+		return nil, utils.ErrInvalidOrMissingParam("dir")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.WithField("dir", dir).Infof("Could not create the directory: %s", err)
+		return nil, err
+	}
+
+	return &dataTypeRepository{
+		dir: dir,
+	}, nil
+}
+
+// FindByBot returns the data type map of the bot. Returns nil if the bot has none.
+func (repository *dataTypeRepository) FindByBot(bot string) (nlp.DataTypeMap, error) {
+	path, err := repository.path(bot)
+
+	if err != nil {
+		return nil, err
+	}
+
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.WithField("path", path).Infof("Could not read the data type map: %s", err)
+		return nil, err
+	}
+
+	var dataTypeMap nlp.DataTypeMap
+
+	if err := json.Unmarshal(data, &dataTypeMap); err != nil {
+		log.WithField("path", path).Infof("Could not decode the data type map: %s", err)
+		return nil, err
+	}
+
+	return dataTypeMap, nil
+}
+
+// SaveForBot writes the data type map of the bot. The file is replaced at once,
+// so that it is never read half-written.
+func (repository *dataTypeRepository) SaveForBot(bot string, dataTypeMap nlp.DataTypeMap) error {
+	path, err := repository.path(bot)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(dataTypeMap, "", "    ")
+
+	if err != nil {
+		return err
+	}
+
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		log.WithField("path", path).Infof("Could not write the data type map: %s", err)
+		return err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		log.WithField("path", path).Infof("Could not write the data type map: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// path returns the path of the bot's file.
+// Returns ErrInvalidBot if the slug cannot be used as a file name.
+func (repository *dataTypeRepository) path(bot string) (string, error) {
+	if bot == "" || bot == "." || bot == ".." || filepath.Base(bot) != bot {
+		return "", ErrInvalidBot
+	}
+
+	return filepath.Join(repository.dir, bot+".json"), nil
+}
+
+func init() {
+	nlp.RegisterDataTypeRepositoryBuilder("file", newDataTypeRepository)
+}
@@ -0,0 +1,333 @@
+package keyword
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+)
+
+const (
+	// minPhoneDigits is the minimum number of digits of a phone number, so that dates
+	// such as "2018-01-31" are not taken as phone numbers
+	minPhoneDigits = 9
+	maxPhoneDigits = 15
+)
+
+var (
+	emailRegexp = regexp.MustCompile(`[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+	// Urls are found within the original text, as their paths are case-sensitive
+	urlRegexp    = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+[^\s<>".,;:!?)]`)
+	numberRegexp = regexp.MustCompile(`-?\b\d+(?:[.,]\d+)?\b`)
+	phoneRegexp  = regexp.MustCompile(`\+?\d[\d\s().-]{6,}\d`)
+	dateRegexp   = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	timeRegexp   = regexp.MustCompile(`\b(\d{1,2})(?::(\d{2}))?\s*(am|pm)\b|\b(\d{1,2}):(\d{2})\b`)
+
+	unitWords = map[string]int{
+		"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4,
+		"five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+		"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14,
+		"fifteen": 15, "sixteen": 16, "seventeen": 17, "eighteen": 18, "nineteen": 19,
+	}
+	tensWords = map[string]int{
+		"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+		"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+	}
+
+	// dayKeywords are the days relative to the current day, from the longest to the shortest
+	// so that "day after tomorrow" is not taken as "tomorrow"
+	dayKeywords = []struct {
+		keyword string
+		offset  int
+	}{
+		{"day after tomorrow", 2},
+		{"tomorrow", 1},
+		{"tonight", 0},
+		{"today", 0},
+	}
+	weekdays = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+	timeKeywords = map[string]int{
+		"midnight": 0,
+		"noon":     12,
+	}
+)
+
+// findEmails returns the email addresses of the lowercased text
+func findEmails(lowered string) []string {
+	return emailRegexp.FindAllString(lowered, -1)
+}
+
+// findUrls returns the urls of the text
+func findUrls(text string) []string {
+	return urlRegexp.FindAllString(text, -1)
+}
+
+// lowercased returns the texts, lowercased
+func lowercased(texts []string) []string {
+	var lowered []string
+
+	for _, text := range texts {
+		lowered = append(lowered, strings.ToLower(text))
+	}
+
+	return lowered
+}
+
+// findPhones returns the phone numbers of the lowercased text, as written
+func findPhones(lowered string) []string {
+	var phones []string
+
+	for _, match := range phoneRegexp.FindAllString(lowered, -1) {
+		digits := len(strings.TrimPrefix(toPhoneNumber(match), "+"))
+
+		if digits >= minPhoneDigits && digits <= maxPhoneDigits {
+			phones = append(phones, match)
+		}
+	}
+
+	return phones
+}
+
+// toPhoneNumber keeps the digits of the phone number, and the leading "+" of international numbers
+func toPhoneNumber(phone string) string {
+	number := ""
+
+	if strings.HasPrefix(phone, "+") {
+		number = "+"
+	}
+
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			number += string(r)
+		}
+	}
+
+	return number
+}
+
+// removeAll removes the parts from the text
+func removeAll(text string, parts []string) string {
+	for _, part := range parts {
+		text = strings.Replace(text, part, " ", -1)
+	}
+
+	return text
+}
+
+// removeDatesAndTimes removes the explicit dates and the times from the text
+func removeDatesAndTimes(text string) string {
+	return timeRegexp.ReplaceAllString(dateRegexp.ReplaceAllString(text, " "), " ")
+}
+
+// parseInt returns an int entity from the first number of the lowercased text, written
+// in digits or in words such as "twenty two". Dates and times are ignored.
+// Returns nil if there is none.
+func parseInt(name, lowered string) *nlp.ParsedEntity {
+	words := wordRegexp.FindAllString(removeDatesAndTimes(lowered), -1)
+
+	for i, word := range words {
+		if value, err := strconv.Atoi(word); err == nil {
+			return nlp.NewParsedIntEntity(name, 1, value, "")
+		}
+
+		if value, ok := unitWords[word]; ok {
+			return nlp.NewParsedIntEntity(name, 1, value, "")
+		}
+
+		if value, ok := tensWords[word]; ok {
+			// Compound numbers, such as "twenty two"
+			if i+1 < len(words) {
+				if unit, ok := unitWords[words[i+1]]; ok && unit > 0 && unit < 10 {
+					value += unit
+				}
+			}
+
+			return nlp.NewParsedIntEntity(name, 1, value, "")
+		}
+	}
+
+	return nil
+}
+
+// parseNumber returns a number entity from the first number of the lowercased text, written
+// in digits, possibly with decimals such as "2.5" or "2,5", or in words. Dates and times are ignored.
+// Returns nil if there is none.
+func parseNumber(name, lowered string) *nlp.ParsedEntity {
+	withoutDates := removeDatesAndTimes(lowered)
+
+	if match := numberRegexp.FindStringIndex(withoutDates); match != nil {
+		// Compare the positions, as the written numbers may come first
+		if entity := parseInt(name, withoutDates[:match[0]]); entity != nil {
+			value, _ := entity.Number()
+			return nlp.NewParsedNumberEntity(name, 1, value, "")
+		}
+
+		value, err := strconv.ParseFloat(strings.Replace(withoutDates[match[0]:match[1]], ",", ".", 1), 64)
+
+		if err == nil {
+			return nlp.NewParsedNumberEntity(name, 1, value, "")
+		}
+	}
+
+	if entity := parseInt(name, withoutDates); entity != nil {
+		value, _ := entity.Number()
+		return nlp.NewParsedNumberEntity(name, 1, value, "")
+	}
+
+	return nil
+}
+
+// parseEnum returns an enum entity from the first value, or synonym of a value, found within
+// the normalized text. Returns nil if there is none.
+func parseEnum(name, normalized string, values nlp.Enum) *nlp.ParsedEntity {
+	first, found := len(normalized), ""
+
+	for value, synonyms := range values {
+		for _, text := range append([]string{value}, synonyms...) {
+			words := wordRegexp.FindAllString(strings.ToLower(text), -1)
+
+			if len(words) == 0 {
+				continue
+			}
+
+			index := strings.Index(normalized, " "+strings.Join(words, " ")+" ")
+
+			// Break ties using the values, as the maps are not ordered
+			if index >= 0 && (index < first || (index == first && value < found)) {
+				first, found = index, value
+			}
+		}
+	}
+
+	if found == "" {
+		return nil
+	}
+
+	return nlp.NewParsedEnumEntity(name, 1, found, "")
+}
+
+// parseDateTime returns a datetime entity from a date and / or a time of the lowercased text.
+// Dates are either relative to now ("tomorrow", "next friday") or explicit ("2018-01-31"),
+// and default to today. The granularity is the hour when a time is given.
+// Returns nil if there is neither a date nor a time.
+func parseDateTime(name, lowered string, now time.Time) *nlp.ParsedEntity {
+	normalized := " " + strings.Join(wordRegexp.FindAllString(lowered, -1), " ") + " "
+	date, found := parseDate(lowered, normalized, now)
+	granularity := nlp.GranularityDay
+
+	if hour, minute, ok := parseTime(lowered, normalized); ok {
+		// Not adding a duration, as days are not always 24 hours long within the timezone
+		date = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, date.Location())
+		granularity = nlp.GranularityHour
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+
+	return nlp.NewParsedSingleDateTimeEntity(name, 1, date, granularity, "")
+}
+
+// parseDate returns the date of the text, at midnight, and whether there is one.
+// Defaults to today.
+func parseDate(lowered, normalized string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if match := dateRegexp.FindStringSubmatch(lowered); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		day, _ := strconv.Atoi(match[3])
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+
+		// Invalid dates, such as "2018-02-31", are normalized by time.Date
+		if date.Month() == time.Month(month) && date.Day() == day {
+			return date, true
+		}
+	}
+
+	for _, day := range dayKeywords {
+		if strings.Contains(normalized, " "+day.keyword+" ") {
+			return today.AddDate(0, 0, day.offset), true
+		}
+	}
+
+	words := strings.Fields(normalized)
+
+	for i, word := range words {
+		weekday, ok := weekdays[word]
+
+		if !ok {
+			continue
+		}
+
+		// The next occurrence of the weekday, today included unless asking for the next one
+		offset := (int(weekday) - int(today.Weekday()) + 7) % 7
+
+		if offset == 0 && i > 0 && words[i-1] == "next" {
+			offset = 7
+		}
+
+		return today.AddDate(0, 0, offset), true
+	}
+
+	return today, false
+}
+
+// parseTime returns the 24-hour time of the text, and whether there is one
+func parseTime(lowered, normalized string) (int, int, bool) {
+	if match := timeRegexp.FindStringSubmatch(lowered); match != nil {
+		if hour, minute, ok := toHourMinute(match); ok {
+			return hour, minute, true
+		}
+	}
+
+	for keyword, hour := range timeKeywords {
+		if strings.Contains(normalized, " "+keyword+" ") {
+			return hour, 0, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// toHourMinute converts a time matched by timeRegexp to a 24-hour time.
+// Returns false if the time is not valid.
+func toHourMinute(match []string) (int, int, bool) {
+	hourText, minuteText, period := match[1], match[2], match[3]
+
+	if hourText == "" {
+		hourText, minuteText = match[4], match[5]
+	}
+
+	hour, _ := strconv.Atoi(hourText)
+	minute, _ := strconv.Atoi(minuteText)
+
+	if period != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+
+		hour = hour % 12
+
+		if period == "pm" {
+			hour += 12
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+
+	return hour, minute, true
+}
@@ -0,0 +1,381 @@
+// Package keyword implements an offline NLP engine, recognising intents and entities
+// using the keywords, patterns and synonyms of its training data. It does not need any
+// network access, which makes it handy for tests, demos and simple bots.
+package keyword
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// fuzzyWeight is the weight of a keyword matched despite a typo, an exact match weighing 1
+	fuzzyWeight = 0.8
+	// expectedKeywords is the number of keywords needed to get a full confidence
+	expectedKeywords = 2
+)
+
+var wordRegexp = regexp.MustCompile(`[\p{L}\p{N}:]+`)
+
+// keywordParser is the offline NLP engine.
+// It implements the nlp.TextParser, nlp.Parser and nlp.Api interfaces.
+type keywordParser struct {
+	training *TrainingData
+}
+
+// newParser is the builder of keywordParser.
+// The training data is given inline using "training", or as a JSON file using "training_file".
+func newParser(conf utils.BuilderConf) (interface{}, error) {
+	training, err := loadTrainingData(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &keywordParser{
+		training: training,
+	}, nil
+}
+
+// ParseNlpData parses the raw data as a text, using the current time as the reference time.
+// This method is required in order to implement the nlp.Parser interface.
+func (parser *keywordParser) ParseNlpData(rawData []byte) (*nlp.ParsedData, error) {
+	return parser.Parse(string(rawData), nil)
+}
+
+// Parse parses the text and returns the matching intents, from the most to the least
+// confident, along with the recognised entities. Dates are resolved using the context's
+// reference time, in the user's timezone.
+//
+// An intent whose pattern matches the text is certain. Otherwise, its confidence depends on
+// the number of keywords found in the text, those found despite a typo weighing less.
+// This method is required in order to implement the nlp.TextParser interface.
+func (parser *keywordParser) Parse(text string, context *nlp.Context) (*nlp.ParsedData, error) {
+	lowered := strings.ToLower(text)
+
+	// Emails, urls and phones are removed so that their digits are not taken as numbers
+	emails := findEmails(lowered)
+	urls := findUrls(text)
+	withoutLinks := removeAll(removeAll(lowered, emails), lowercased(urls))
+	phones := findPhones(removeDatesAndTimes(withoutLinks))
+	cleaned := removeAll(withoutLinks, phones)
+
+	words := parser.applySynonyms(wordRegexp.FindAllString(cleaned, -1))
+	normalized := " " + strings.Join(words, " ") + " "
+
+	var intents []*nlp.ParsedIntent
+
+	for _, name := range sortedIntentNames(parser.training.Intents) {
+		confidence := parser.intentConfidence(parser.training.Intents[name], text, normalized, words)
+
+		if confidence > 0 {
+			intents = append(intents, nlp.NewParsedIntent(name, confidence))
+		}
+	}
+
+	// The names are sorted: ties are broken by name
+	nlp.RankIntents(intents)
+
+	var entities []*nlp.ParsedEntity
+
+	for _, name := range sortedEntityNames(parser.training.Entities) {
+		rule := parser.training.Entities[name]
+
+		if len(rule.Keywords) > 0 && parser.matchKeywords(rule.Keywords, normalized, words) == 0 {
+			continue
+		}
+
+		var entity *nlp.ParsedEntity
+
+		switch rule.Type {
+		case nlp.IntEntity:
+			entity = parseInt(name, cleaned)
+		case nlp.NumberEntity:
+			entity = parseNumber(name, cleaned)
+		case nlp.EnumEntity:
+			entity = parseEnum(name, normalized, rule.Values)
+		case nlp.DateTimeEntity:
+			entity = parseDateTime(name, cleaned, referenceTime(context))
+		case nlp.EmailEntity:
+			if len(emails) > 0 {
+				entity = nlp.NewParsedEmailEntity(name, 1, emails[0], "")
+			}
+		case nlp.PhoneEntity:
+			if len(phones) > 0 {
+				entity = nlp.NewParsedPhoneEntity(name, 1, toPhoneNumber(phones[0]), "")
+			}
+		case nlp.UrlEntity:
+			if len(urls) > 0 {
+				entity = nlp.NewParsedUrlEntity(name, 1, urls[0], "")
+			}
+		}
+
+		if entity != nil {
+			entities = append(entities, entity)
+		}
+	}
+
+	var intent *nlp.ParsedIntent
+
+	if len(intents) > 0 {
+		intent = intents[0]
+	}
+
+	data := nlp.NewParsedData(intent, entities)
+	data.Intents = intents
+
+	log.WithFields(log.Fields{
+		"text": text,
+		"data": data,
+	}).Debug("Text parsed offline")
+
+	return data, nil
+}
+
+// GetIntents returns the intents of the training data.
+// This method is required in order to implement the nlp.Api interface.
+func (parser *keywordParser) GetIntents() ([]*nlp.Intent, error) {
+	intents := []*nlp.Intent{}
+
+	for _, name := range sortedIntentNames(parser.training.Intents) {
+		intents = append(intents, nlp.NewIntent(name))
+	}
+
+	return intents, nil
+}
+
+// GetEntities returns the entities of the training data, along with their type.
+// This method is required in order to implement the nlp.Api interface.
+func (parser *keywordParser) GetEntities() ([]*nlp.Entity, error) {
+	entities := []*nlp.Entity{}
+
+	for _, name := range sortedEntityNames(parser.training.Entities) {
+		entities = append(entities, &nlp.Entity{
+			Name: name,
+			Type: parser.training.Entities[name].Type,
+		})
+	}
+
+	return entities, nil
+}
+
+// intentConfidence returns the confidence of the intent given the text, or 0 if it does not match
+func (parser *keywordParser) intentConfidence(rule *IntentRule, text, normalized string, words []string) float32 {
+	for _, pattern := range rule.patterns {
+		if pattern.MatchString(text) {
+			return 1
+		}
+	}
+
+	if len(rule.Keywords) == 0 {
+		return 0
+	}
+
+	expected := expectedKeywords
+
+	if len(rule.Keywords) < expected {
+		expected = len(rule.Keywords)
+	}
+
+	confidence := parser.matchKeywords(rule.Keywords, normalized, words) / float32(expected)
+
+	if confidence > 1 {
+		return 1
+	}
+
+	return confidence
+}
+
+// matchKeywords returns the weighted number of keywords found in the text.
+// Keywords of several words are only matched exactly.
+func (parser *keywordParser) matchKeywords(keywords []string, normalized string, words []string) float32 {
+	var score float32
+
+	for _, keyword := range keywords {
+		keywordWords := wordRegexp.FindAllString(strings.ToLower(keyword), -1)
+
+		if len(keywordWords) == 0 {
+			continue
+		}
+
+		if strings.Contains(normalized, " "+strings.Join(keywordWords, " ")+" ") {
+			score++
+			continue
+		}
+
+		if len(keywordWords) == 1 && parser.training.isFuzzy() && fuzzyContains(words, keywordWords[0]) {
+			score += fuzzyWeight
+		}
+	}
+
+	return score
+}
+
+// applySynonyms replaces the synonyms found within the words by the word they stand for
+func (parser *keywordParser) applySynonyms(words []string) []string {
+	if len(parser.training.Synonyms) == 0 {
+		return words
+	}
+
+	var synonymsOf []string
+
+	for word := range parser.training.Synonyms {
+		synonymsOf = append(synonymsOf, word)
+	}
+
+	sort.Strings(synonymsOf)
+
+	text := " " + strings.Join(words, " ") + " "
+
+	for _, word := range synonymsOf {
+		replacement := " " + strings.Join(wordRegexp.FindAllString(strings.ToLower(word), -1), " ") + " "
+
+		for _, synonym := range parser.training.Synonyms[word] {
+			synonymWords := wordRegexp.FindAllString(strings.ToLower(synonym), -1)
+
+			if len(synonymWords) == 0 {
+				continue
+			}
+
+			pattern := " " + strings.Join(synonymWords, " ") + " "
+
+			// Adjacent synonyms share their separating space: replace them until there is none left
+			for i := 0; i < len(words) && strings.Contains(text, pattern); i++ {
+				text = strings.Replace(text, pattern, replacement, -1)
+			}
+		}
+	}
+
+	return strings.Fields(text)
+}
+
+// fuzzyContains tells whether one of the words matches the keyword despite a typo.
+// Short words must be matched exactly, as a single typo would change them too much.
+func fuzzyContains(words []string, keyword string) bool {
+	maxDistance := 1
+
+	if len([]rune(keyword)) < 5 {
+		return false
+	} else if len([]rune(keyword)) >= 8 {
+		maxDistance = 2
+	}
+
+	for _, word := range words {
+		if editDistance(word, keyword) <= maxDistance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and transpositions
+// of adjacent letters needed to turn a word into the other one
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	distances := make([][]int, len(ra)+1)
+
+	for i := range distances {
+		distances[i] = make([]int, len(rb)+1)
+		distances[i][0] = i
+	}
+
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			distance := distances[i-1][j] + 1
+
+			if d := distances[i][j-1] + 1; d < distance {
+				distance = d
+			}
+
+			if d := distances[i-1][j-1] + cost; d < distance {
+				distance = d
+			}
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if d := distances[i-2][j-2] + 1; d < distance {
+					distance = d
+				}
+			}
+
+			distances[i][j] = distance
+		}
+	}
+
+	return distances[len(ra)][len(rb)]
+}
+
+// referenceTime returns the time the text was written at, in the user's timezone when known
+func referenceTime(context *nlp.Context) time.Time {
+	now := time.Now()
+
+	if context == nil {
+		return now
+	}
+
+	if !context.ReferenceTime.IsZero() {
+		now = context.ReferenceTime
+	}
+
+	if context.Timezone != "" {
+		location, err := time.LoadLocation(context.Timezone)
+
+		if err != nil {
+			log.WithField("timezone", context.Timezone).Infof("Unknown timezone: %s", err)
+			return now
+		}
+
+		now = now.In(location)
+	}
+
+	return now
+}
+
+// sortedIntentNames returns the names of the intents, sorted, so that the results
+// do not depend on the maps' order
+func sortedIntentNames(intents map[string]*IntentRule) []string {
+	var names []string
+
+	for name := range intents {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// sortedEntityNames returns the names of the entities, sorted
+func sortedEntityNames(entities map[string]*EntityRule) []string {
+	var names []string
+
+	for name := range entities {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	nlp.RegisterParserBuilder("keyword", newParser)
+	nlp.RegisterTextParserBuilder("keyword", newParser)
+	nlp.RegisterApiBuilder("keyword", newParser)
+}
@@ -0,0 +1,40 @@
+package keyword
+
+import (
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+// keywordRepository is the struct used to access the intents and entities of the training data
+type keywordRepository struct {
+	api nlp.Api
+}
+
+// newKeywordRepository instantiates a new keywordRepository using the given conf
+func newKeywordRepository(conf utils.BuilderConf) (interface{}, error) {
+	api, ok := utils.GetParam(conf, "api").(nlp.Api)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("api")
+	}
+
+	return &keywordRepository{
+		api: api,
+	}, nil
+}
+
+// GetIntents is the method returning all of the available intents.
+// It is required in order to implement the nlp.Repository interface.
+func (repository *keywordRepository) GetIntents() ([]*nlp.Intent, error) {
+	return repository.api.GetIntents()
+}
+
+// GetEntities is the method returning all of the available entities.
+// It is required in order to implement the nlp.Repository interface.
+func (repository *keywordRepository) GetEntities() ([]*nlp.Entity, error) {
+	return repository.api.GetEntities()
+}
+
+func init() {
+	nlp.RegisterRepositoryBuilder("keyword", newKeywordRepository)
+}
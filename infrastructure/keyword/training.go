@@ -0,0 +1,201 @@
+package keyword
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCouldNotReadTrainingData = errors.New("Could not read the training data")
+	ErrInvalidTrainingData      = errors.New("Invalid training data")
+
+	// handledEntityTypes are the entity types the parser can extract
+	handledEntityTypes = map[nlp.EntityType]bool{
+		nlp.IntEntity:      true,
+		nlp.NumberEntity:   true,
+		nlp.EnumEntity:     true,
+		nlp.DateTimeEntity: true,
+		nlp.EmailEntity:    true,
+		nlp.PhoneEntity:    true,
+		nlp.UrlEntity:      true,
+	}
+)
+
+// TrainingData is the data the parser is driven by. It is usually given per bot.
+//
+// For example:
+//
+//	{
+//	    "intents": {
+//	        "book_table": {"keywords": ["book", "table"], "patterns": ["\\bfor \\d+\\b"]},
+//	        "greet": ["hello", "hi"]
+//	    },
+//	    "synonyms": {"book": ["reserve", "booking"]},
+//	    "entities": {
+//	        "nb_persons": {"type": "int", "keywords": ["people", "persons"]},
+//	        "booking_date": {"type": "datetime"},
+//	        "area": {"type": "enum", "values": {"terrace": ["outside"], "inside": ["indoors"]}}
+//	    }
+//	}
+type TrainingData struct {
+	// Intents maps the intents' names to their rule
+	Intents map[string]*IntentRule `json:"intents"`
+	// Entities maps the entities' names to their rule
+	Entities map[string]*EntityRule `json:"entities"`
+	// Synonyms maps words to their synonyms: the synonyms are replaced by the word before
+	// looking for keywords, so that the keywords do not need to list them.
+	Synonyms map[string][]string `json:"synonyms"`
+	// Fuzzy tells whether the keywords can be matched despite typos. It is enabled by default.
+	Fuzzy *bool `json:"fuzzy"`
+}
+
+// IntentRule describes how to recognise an intent: using its keywords, or using its regex patterns.
+// A matching pattern gives a full confidence. It can also be given as a list of keywords only.
+type IntentRule struct {
+	Keywords []string `json:"keywords"`
+	Patterns []string `json:"patterns"`
+	patterns []*regexp.Regexp
+}
+
+// EntityRule describes how to recognise an entity. Ints and numbers are found from numbers, in digits
+// or words, datetimes from dates ("tomorrow", "monday", "2018-01-31") and times ("5pm", "17:30"),
+// enums from their values and synonyms, and emails, phones and urls from their usual formats.
+// When keywords are given, the entity is only recognised if one of them is in the text.
+type EntityRule struct {
+	Type     nlp.EntityType `json:"type"`
+	Keywords []string       `json:"keywords"`
+	// Values are the values of the enums, along with their synonyms
+	Values nlp.Enum `json:"values"`
+}
+
+// UnmarshalJSON unmarshals the rule, given either as an object or as a list of keywords
+func (rule *IntentRule) UnmarshalJSON(data []byte) error {
+	var keywords []string
+
+	if err := json.Unmarshal(data, &keywords); err == nil {
+		rule.Keywords = keywords
+		return nil
+	}
+
+	type intentRule IntentRule
+
+	return json.Unmarshal(data, (*intentRule)(rule))
+}
+
+// isFuzzy tells whether the keywords can be matched despite typos
+func (training *TrainingData) isFuzzy() bool {
+	return training.Fuzzy == nil || *training.Fuzzy
+}
+
+// defaultTrainingData returns the training data used when none is given.
+// It matches the default stories.
+func defaultTrainingData() *TrainingData {
+	return &TrainingData{
+		Intents: map[string]*IntentRule{
+			"book_table": {
+				Keywords: []string{"book", "table"},
+			},
+		},
+		Synonyms: map[string][]string{
+			"book": {"reserve", "reservation", "booking"},
+		},
+		Entities: map[string]*EntityRule{
+			"nb_persons": {
+				Type:     nlp.IntEntity,
+				Keywords: []string{"people", "persons", "person", "guests", "of us"},
+			},
+			"booking_date": {
+				Type: nlp.DateTimeEntity,
+			},
+		},
+	}
+}
+
+// loadTrainingData loads the training data given within the conf: inline, using
+// the "training" object, or from a JSON file, using the "training_file" path.
+// The default training data is used when there is none.
+func loadTrainingData(conf utils.BuilderConf) (*TrainingData, error) {
+	var data []byte
+
+	if training := utils.GetParam(conf, "training"); training != nil {
+		var err error
+		data, err = json.Marshal(training)
+
+		if err != nil {
+			log.Infof("Could not read the training data: %s", err)
+			return nil, ErrCouldNotReadTrainingData
+		}
+	} else if path, _ := utils.GetParam(conf, "training_file").(string); path != "" {
+		var err error
+		data, err = ioutil.ReadFile(path)
+
+		if err != nil {
+			log.WithField("path", path).Infof("Could not read the training file: %s", err)
+			return nil, ErrCouldNotReadTrainingData
+		}
+	}
+
+	training := defaultTrainingData()
+
+	if data != nil {
+		training = &TrainingData{}
+
+		if err := json.Unmarshal(data, training); err != nil {
+			log.Infof("Could not parse the training data: %s", err)
+			return nil, ErrInvalidTrainingData
+		}
+	}
+
+	if err := training.compile(); err != nil {
+		return nil, err
+	}
+
+	return training, nil
+}
+
+// compile validates the training data, and compiles the intents' patterns
+func (training *TrainingData) compile() error {
+	for name, rule := range training.Intents {
+		if rule == nil {
+			log.WithField("intent", name).Info("Missing intent rule")
+			return ErrInvalidTrainingData
+		}
+
+		rule.patterns = nil
+
+		for _, pattern := range rule.Patterns {
+			// Patterns are case-insensitive
+			compiled, err := regexp.Compile("(?i)" + pattern)
+
+			if err != nil {
+				log.WithFields(log.Fields{
+					"intent":  name,
+					"pattern": pattern,
+				}).Infof("Invalid pattern: %s", err)
+				return ErrInvalidTrainingData
+			}
+
+			rule.patterns = append(rule.patterns, compiled)
+		}
+	}
+
+	for name, rule := range training.Entities {
+		if rule == nil || !handledEntityTypes[rule.Type] {
+			log.WithField("entity", name).Info("Unhandled entity type")
+			return ErrInvalidTrainingData
+		}
+
+		if rule.Type == nlp.EnumEntity && len(rule.Values) == 0 {
+			log.WithField("entity", name).Info("Missing enum values")
+			return ErrInvalidTrainingData
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+// defaultMaxEntries is the maximum number of values kept by a cache store, unless given
+const defaultMaxEntries = 10000
+
+// cacheItem is a value of the store, along with its expiration time
+type cacheItem struct {
+	namespace string
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// inMemoryCacheStore is the in memory implementation of an nlp.CacheStore.
+// When full, the least recently used values are evicted.
+type inMemoryCacheStore struct {
+	mutex      sync.Mutex
+	maxEntries int
+	// items are ordered from the most to the least recently used
+	items *list.List
+	index map[string]*list.Element
+}
+
+// newCacheStore instanciates a new in memory cache store, keeping at most "max_entries" values
+func newCacheStore(conf utils.BuilderConf) (interface{}, error) {
+	maxEntries := defaultMaxEntries
+
+	if value, ok := utils.ToFloat(utils.GetParam(conf, "max_entries")); ok && value > 0 {
+		maxEntries = int(value)
+	}
+
+	return &inMemoryCacheStore{
+		maxEntries: maxEntries,
+		items:      list.New(),
+		index:      make(map[string]*list.Element),
+	}, nil
+}
+
+// Get returns the value of the key. Returns false if there is none, or if it expired.
+func (s *inMemoryCacheStore) Get(namespace, key string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.index[namespace+"/"+key]
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	item := element.Value.(*cacheItem)
+
+	if time.Now().After(item.expiresAt) {
+		s.remove(element)
+		return nil, false, nil
+	}
+
+	s.items.MoveToFront(element)
+
+	return item.value, true, nil
+}
+
+// Set sets the value of the key for the TTL, evicting the least recently used values if the store is full
+func (s *inMemoryCacheStore) Set(namespace, key string, value []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, ok := s.index[namespace+"/"+key]; ok {
+		s.remove(element)
+	}
+
+	s.index[namespace+"/"+key] = s.items.PushFront(&cacheItem{
+		namespace: namespace,
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	})
+
+	for s.items.Len() > s.maxEntries {
+		s.remove(s.items.Back())
+	}
+
+	return nil
+}
+
+// Clear removes all of the namespace's values
+func (s *inMemoryCacheStore) Clear(namespace string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for element := s.items.Front(); element != nil; {
+		next := element.Next()
+
+		if element.Value.(*cacheItem).namespace == namespace {
+			s.remove(element)
+		}
+
+		element = next
+	}
+
+	return nil
+}
+
+// remove removes the element from the store
+func (s *inMemoryCacheStore) remove(element *list.Element) {
+	item := s.items.Remove(element).(*cacheItem)
+	delete(s.index, item.namespace+"/"+item.key)
+}
+
+func init() {
+	nlp.RegisterCacheStoreBuilder("memory", newCacheStore)
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aziule/conversation-management/core/conversation"
+	"github.com/aziule/conversation-management/core/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// inMemoryConversationRepository is the in memory implementation of a conversation Repository.
+// Nothing is persisted: it is meant to run bots locally, without any database.
+type inMemoryConversationRepository struct {
+	mutex         sync.Mutex
+	conversations []*conversation.Conversation
+	users         map[bson.ObjectId]*conversation.User
+	linkCodes     map[string]*conversation.LinkCode
+}
+
+// newConversationRepository instanciates a new in memory conversation repository
+func newConversationRepository(conf utils.BuilderConf) (interface{}, error) {
+	return &inMemoryConversationRepository{
+		users:     make(map[bson.ObjectId]*conversation.User),
+		linkCodes: make(map[string]*conversation.LinkCode),
+	}, nil
+}
+
+// SaveConversation saves a conversation, which can be an existing one or a new one
+func (r *inMemoryConversationRepository) SaveConversation(c *conversation.Conversation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c.UpdatedAt = time.Now()
+
+	if c.Id == "" {
+		c.Id = bson.NewObjectId()
+		c.CreatedAt = time.Now()
+
+		r.conversations = append(r.conversations, c)
+	}
+
+	return nil
+}
+
+// FindLatestConversation finds the latest conversation that happened with a user,
+// or with any of the users merged into it.
+// Returns a conversation.ErrNotFound error when there is none.
+func (r *inMemoryConversationRepository) FindLatestConversation(user *conversation.User) (*conversation.Conversation, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var latest *conversation.Conversation
+	ids := user.Ids()
+
+	for _, c := range r.conversations {
+		if !isHeldWith(c, ids) {
+			continue
+		}
+
+		if latest == nil || !c.CreatedAt.Before(latest.CreatedAt) {
+			latest = c
+		}
+	}
+
+	if latest == nil {
+		return nil, conversation.ErrNotFound
+	}
+
+	return latest, nil
+}
+
+// FindUserById tries to find a user based on its id.
+// Returns a conversation.ErrNotFound error when the user is not found
+func (r *inMemoryConversationRepository) FindUserById(id bson.ObjectId) (*conversation.User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	user, ok := r.users[id]
+
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+
+	return user, nil
+}
+
+// FindUserByIdentity tries to find a user based on one of its identities.
+// Returns a conversation.ErrNotFound error when the user is not found
+func (r *inMemoryConversationRepository) FindUserByIdentity(platform, externalId string) (*conversation.User, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, user := range r.users {
+		for _, identity := range user.Identities {
+			if identity.Platform == platform && identity.ExternalId == externalId {
+				return user, nil
+			}
+		}
+	}
+
+	return nil, conversation.ErrNotFound
+}
+
+// InsertUser stores a new user
+func (r *inMemoryConversationRepository) InsertUser(user *conversation.User) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.users[user.Id] = user
+
+	return nil
+}
+
+// UpdateUser updates an existing user
+func (r *inMemoryConversationRepository) UpdateUser(user *conversation.User) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.users[user.Id]; !ok {
+		return conversation.ErrNotFound
+	}
+
+	r.users[user.Id] = user
+
+	return nil
+}
+
+// InsertLinkCode stores a new link code
+func (r *inMemoryConversationRepository) InsertLinkCode(linkCode *conversation.LinkCode) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.linkCodes[linkCode.Code] = linkCode
+
+	return nil
+}
+
+// ConsumeLinkCode finds and deletes the link code at once, so that a code cannot be used twice.
+// Returns a conversation.ErrNotFound error when the code is not found
+func (r *inMemoryConversationRepository) ConsumeLinkCode(code string) (*conversation.LinkCode, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	linkCode, ok := r.linkCodes[code]
+
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+
+	delete(r.linkCodes, code)
+
+	return linkCode, nil
+}
+
+// isHeldWith tells whether the conversation is held with any of the given users.
+// The conversations saved before the user id was stored are found from their messages.
+func isHeldWith(c *conversation.Conversation, ids []bson.ObjectId) bool {
+	for _, id := range ids {
+		if c.UserId == id {
+			return true
+		}
+	}
+
+	for _, m := range c.Messages {
+		userMessage, ok := m.Message.(*conversation.UserMessage)
+
+		if !ok {
+			continue
+		}
+
+		for _, id := range ids {
+			if userMessage.Sender == id {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func init() {
+	conversation.RegisterRepositoryBuilder("memory", newConversationRepository)
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// inMemoryReviewRepository is the in memory implementation of an nlp.ReviewRepository.
+// Nothing is persisted: it is meant to run bots locally, without any database.
+type inMemoryReviewRepository struct {
+	mutex sync.Mutex
+	items []*nlp.ReviewItem
+}
+
+// newReviewRepository instanciates a new in memory review repository
+func newReviewRepository(conf utils.BuilderConf) (interface{}, error) {
+	return &inMemoryReviewRepository{}, nil
+}
+
+// Insert inserts a new review item
+func (r *inMemoryReviewRepository) Insert(item *nlp.ReviewItem) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	copied := *item
+	r.items = append(r.items, &copied)
+
+	return nil
+}
+
+// Update updates an existing review item.
+// Returns an nlp.ErrReviewItemNotFound error when the item is not found
+func (r *inMemoryReviewRepository) Update(item *nlp.ReviewItem) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, existing := range r.items {
+		if existing.Id == item.Id {
+			copied := *item
+			r.items[i] = &copied
+			return nil
+		}
+	}
+
+	return nlp.ErrReviewItemNotFound
+}
+
+// FindById tries to find a review item based on its id.
+// Returns an nlp.ErrReviewItemNotFound error when the item is not found
+func (r *inMemoryReviewRepository) FindById(id bson.ObjectId) (*nlp.ReviewItem, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, item := range r.items {
+		if item.Id == id {
+			copied := *item
+			return &copied, nil
+		}
+	}
+
+	return nil, nlp.ErrReviewItemNotFound
+}
+
+// FindByBot returns the bot's review items having the status, from the oldest to the newest.
+// All of the bot's items are returned when the status is empty.
+func (r *inMemoryReviewRepository) FindByBot(bot string, status nlp.ReviewStatus) ([]*nlp.ReviewItem, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	items := []*nlp.ReviewItem{}
+
+	for _, item := range r.items {
+		if item.Bot == bot && (status == "" || item.Status == status) {
+			copied := *item
+			items = append(items, &copied)
+		}
+	}
+
+	return items, nil
+}
+
+func init() {
+	nlp.RegisterReviewRepositoryBuilder("memory", newReviewRepository)
+}
@@ -0,0 +1,175 @@
+package mongo
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const NlpCacheCollection = "nlp_cache"
+
+// defaultMaxEntries is the maximum number of values kept by a cache store, unless given
+const defaultMaxEntries = 10000
+
+// cacheDocument is a value of the cache store
+type cacheDocument struct {
+	Id        string    `bson:"_id"`
+	Namespace string    `bson:"namespace"`
+	Value     []byte    `bson:"value"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	UsedAt    time.Time `bson:"used_at"`
+}
+
+// cacheStore is the unexported struct that implements the nlp.CacheStore interface.
+// The expired values are removed by MongoDb, using a TTL index, and the least recently used
+// values are evicted when the store is full. The values are shared by all of the app's
+// instances, and outlive restarts.
+type cacheStore struct {
+	db         *Db
+	maxEntries int
+}
+
+// newCacheStore creates a new cache store using MongoDb as the data source, along with its indexes.
+// The store keeps at most "max_entries" values.
+func newCacheStore(conf utils.BuilderConf) (interface{}, error) {
+	db, ok := utils.GetParam(conf, "db").(*Db)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("db")
+	}
+
+	maxEntries := defaultMaxEntries
+
+	if value, ok := utils.ToFloat(utils.GetParam(conf, "max_entries")); ok && value > 0 {
+		maxEntries = int(value)
+	}
+
+	session := db.NewSession()
+	defer session.Close()
+
+	collection := session.DB(db.Params.DbName).C(NlpCacheCollection)
+
+	// The TTL index removes the values once expired: mgo requires a non-zero delay to create it
+	for _, index := range []mgo.Index{
+		{Key: []string{"expires_at"}, ExpireAfter: time.Second},
+		{Key: []string{"namespace"}},
+		{Key: []string{"used_at"}},
+	} {
+		if err := collection.EnsureIndex(index); err != nil {
+			log.WithField("index", index.Key).Infof("Could not create the index: %s", err)
+			return nil, err
+		}
+	}
+
+	return &cacheStore{
+		db:         db,
+		maxEntries: maxEntries,
+	}, nil
+}
+
+// Get returns the value of the key. Returns false if there is none, or if it expired.
+func (store *cacheStore) Get(namespace, key string) ([]byte, bool, error) {
+	session := store.db.NewSession()
+	defer session.Close()
+
+	document := &cacheDocument{}
+
+	// The TTL index removes the expired values within a minute: they are filtered out until then.
+	// The value is marked as used, so that it is not evicted before the least recently used ones.
+	_, err := session.DB(store.db.Params.DbName).C(NlpCacheCollection).Find(bson.M{
+		"_id":        namespace + "/" + key,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Apply(mgo.Change{
+		Update: bson.M{"$set": bson.M{"used_at": time.Now()}},
+	}, document)
+
+	if err == mgo.ErrNotFound {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		log.WithField("key", key).Infof("Could not find the cached value: %s", err)
+		return nil, false, err
+	}
+
+	return document.Value, true, nil
+}
+
+// Set sets the value of the key for the TTL, evicting the least recently used values if the store is full
+func (store *cacheStore) Set(namespace, key string, value []byte, ttl time.Duration) error {
+	session := store.db.NewSession()
+	defer session.Close()
+
+	collection := session.DB(store.db.Params.DbName).C(NlpCacheCollection)
+	now := time.Now()
+
+	_, err := collection.UpsertId(namespace+"/"+key, &cacheDocument{
+		Id:        namespace + "/" + key,
+		Namespace: namespace,
+		Value:     value,
+		ExpiresAt: now.Add(ttl),
+		UsedAt:    now,
+	})
+
+	if err != nil {
+		log.WithField("key", key).Infof("Could not save the cached value: %s", err)
+		return err
+	}
+
+	// The value is cached even though the eviction failed: it will be retried on the next value
+	if err = store.evict(collection); err != nil {
+		log.Infof("Could not evict the least recently used values: %s", err)
+	}
+
+	return nil
+}
+
+// evict removes the least recently used values while the store holds more than maxEntries values
+func (store *cacheStore) evict(collection *mgo.Collection) error {
+	count, err := collection.Count()
+
+	if err != nil || count <= store.maxEntries {
+		return err
+	}
+
+	var documents []*cacheDocument
+
+	err = collection.Find(nil).Select(bson.M{"_id": 1}).Sort("used_at").Limit(count - store.maxEntries).All(&documents)
+
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(documents))
+
+	for i, document := range documents {
+		ids[i] = document.Id
+	}
+
+	_, err = collection.RemoveAll(bson.M{"_id": bson.M{"$in": ids}})
+
+	return err
+}
+
+// Clear removes all of the namespace's values
+func (store *cacheStore) Clear(namespace string) error {
+	session := store.db.NewSession()
+	defer session.Close()
+
+	_, err := session.DB(store.db.Params.DbName).C(NlpCacheCollection).RemoveAll(bson.M{"namespace": namespace})
+
+	if err != nil {
+		log.WithField("namespace", namespace).Infof("Could not clear the cache: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func init() {
+	nlp.RegisterCacheStoreBuilder("mongo", newCacheStore)
+}
@@ -7,6 +7,7 @@ import (
 	"gopkg.in/mgo.v2/bson"
 	"time"
 
+	"github.com/aziule/conversation-management/core/bot"
 	"github.com/aziule/conversation-management/core/conversation"
 	"github.com/aziule/conversation-management/core/utils"
 	log "github.com/sirupsen/logrus"
@@ -15,6 +16,7 @@ import (
 const (
 	ConversationCollection = "conversation"
 	UserCollection         = "user"
+	LinkCodeCollection     = "link_code"
 )
 
 // conversationRepository is the unexported struct that implements the Repository interface
@@ -66,7 +68,8 @@ func (repository *conversationRepository) SaveConversation(c *conversation.Conve
 	return nil
 }
 
-// FindLatestConversation tries to find the latest conversation that happened with a user.
+// FindLatestConversation tries to find the latest conversation that happened with a user,
+// or with any of the users merged into it.
 // In case this is a new user, then no conversation is returned. Otherwise the latest one,
 // which can be the current one, is returned.
 // Returns a conversation.ErrNotFound error when the user is not found.
@@ -77,13 +80,17 @@ func (repository *conversationRepository) FindLatestConversation(user *conversat
 	// Store the result of the query in our own mongo struct
 	var c *conversation.Conversation
 
-	log.WithField("fbid", user.FbId).Debug("Finding latest conversation for user")
+	log.WithField("user", user.Id).Debug("Finding latest conversation for user")
 
+	// The conversations saved before the user id was stored are found from their messages
 	err := session.DB(repository.db.Params.DbName).C(ConversationCollection).Find(bson.M{
-		"messages": bson.M{
-			"$elemMatch": bson.M{
-				"message.sender_id": user.Id,
-			},
+		"$or": []bson.M{
+			{"user_id": bson.M{"$in": user.Ids()}},
+			{"messages": bson.M{
+				"$elemMatch": bson.M{
+					"message.sender_id": bson.M{"$in": user.Ids()},
+				},
+			}},
 		},
 	}).Sort("-created_at").One(&c)
 
@@ -102,28 +109,80 @@ func (repository *conversationRepository) FindLatestConversation(user *conversat
 	return c, nil
 }
 
-// FindUserByFbId tries to find a user based on its fbId
+// FindUserById tries to find a user based on its id.
+// Returns a conversation.ErrNotFound error when the user is not found
+func (repository *conversationRepository) FindUserById(id bson.ObjectId) (*conversation.User, error) {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	user := &conversation.User{}
+
+	err := session.DB(repository.db.Params.DbName).C(UserCollection).FindId(id).One(user)
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, conversation.ErrNotFound
+		}
+
+		log.WithField("id", id).Infof("Could not find the user: %s", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// FindUserByIdentity tries to find a user based on one of its identities.
+// Users created before the identities were introduced are found using their platform id,
+// or their fbId for Facebook users, and are migrated on the fly.
 // Returns a conversation.ErrNotFound error when the user is not found
 // @todo: we should use a specification pattern
-func (repository *conversationRepository) FindUserByFbId(fbId string) (*conversation.User, error) {
+func (repository *conversationRepository) FindUserByIdentity(platform, externalId string) (*conversation.User, error) {
 	session := repository.db.NewSession()
 	defer session.Close()
 
 	user := &conversation.User{}
 
-	err := session.DB(repository.db.Params.DbName).C(UserCollection).Find(bson.M{
-		"fbid": fbId,
-	}).One(user)
+	conditions := []bson.M{
+		{
+			"identities": bson.M{
+				"$elemMatch": bson.M{
+					"platform":    platform,
+					"external_id": externalId,
+				},
+			},
+		},
+		{
+			"platform":    platform,
+			"platform_id": externalId,
+		},
+	}
+
+	if platform == string(bot.PlatformFacebook) {
+		conditions = append(conditions, bson.M{"fbid": externalId})
+	}
+
+	err := session.DB(repository.db.Params.DbName).C(UserCollection).Find(bson.M{"$or": conditions}).One(user)
 
 	if err != nil {
 		if err == mgo.ErrNotFound {
 			return nil, conversation.ErrNotFound
 		}
 
-		log.WithField("fbId", fbId).Infof("Could not find the user: %s", err)
+		log.WithFields(log.Fields{
+			"platform":   platform,
+			"externalId": externalId,
+		}).Infof("Could not find the user: %s", err)
 		return nil, err
 	}
 
+	if user.MigrateLegacyFields() {
+		log.WithField("user", user.Id).Info("Migrating the user to identities")
+
+		if err := repository.UpdateUser(user); err != nil {
+			return nil, err
+		}
+	}
+
 	return user, nil
 }
 
@@ -145,6 +204,58 @@ func (repository *conversationRepository) InsertUser(user *conversation.User) er
 	return nil
 }
 
+// UpdateUser updates an existing user in the DB
+func (repository *conversationRepository) UpdateUser(user *conversation.User) error {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	err := session.DB(repository.db.Params.DbName).C(UserCollection).UpdateId(user.Id, user)
+
+	if err != nil {
+		log.WithField("user", user.Id).Infof("Could not update the user: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// InsertLinkCode creates a new link code in the DB
+func (repository *conversationRepository) InsertLinkCode(linkCode *conversation.LinkCode) error {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	err := session.DB(repository.db.Params.DbName).C(LinkCodeCollection).Insert(linkCode)
+
+	if err != nil {
+		log.WithField("user", linkCode.UserId).Infof("Could not insert the link code: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeLinkCode finds and deletes the link code at once, so that a code cannot be used twice.
+// Returns a conversation.ErrNotFound error when the code is not found
+func (repository *conversationRepository) ConsumeLinkCode(code string) (*conversation.LinkCode, error) {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	linkCode := &conversation.LinkCode{}
+
+	_, err := session.DB(repository.db.Params.DbName).C(LinkCodeCollection).FindId(code).Apply(mgo.Change{Remove: true}, linkCode)
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, conversation.ErrNotFound
+		}
+
+		log.Infof("Could not consume the link code: %s", err)
+		return nil, err
+	}
+
+	return linkCode, nil
+}
+
 func init() {
 	conversation.RegisterRepositoryBuilder("mongo", newConversationRepository)
 }
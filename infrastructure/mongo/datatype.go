@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"gopkg.in/mgo.v2"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const DataTypeMapCollection = "data_type_map"
+
+// dataTypeMapDocument is the document storing a bot's data type map, identified by the bot's slug
+type dataTypeMapDocument struct {
+	Bot       string          `bson:"_id"`
+	DataTypes nlp.DataTypeMap `bson:"data_types"`
+	UpdatedAt time.Time       `bson:"updated_at"`
+}
+
+// dataTypeRepository is the unexported struct that implements the nlp.DataTypeRepository interface
+type dataTypeRepository struct {
+	db *Db
+}
+
+// newDataTypeRepository creates a new data type repository using MongoDb as the data source
+func newDataTypeRepository(conf utils.BuilderConf) (interface{}, error) {
+	db, ok := utils.GetParam(conf, "db").(*Db)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("db")
+	}
+
+	return &dataTypeRepository{
+		db: db,
+	}, nil
+}
+
+// FindByBot returns the data type map of the bot. Returns nil if the bot has none.
+func (repository *dataTypeRepository) FindByBot(bot string) (nlp.DataTypeMap, error) {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	document := &dataTypeMapDocument{}
+	err := session.DB(repository.db.Params.DbName).C(DataTypeMapCollection).FindId(bot).One(document)
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.WithField("bot", bot).Infof("Could not find the data type map: %s", err)
+		return nil, err
+	}
+
+	return document.DataTypes, nil
+}
+
+// SaveForBot inserts / updates the data type map of the bot
+func (repository *dataTypeRepository) SaveForBot(bot string, dataTypeMap nlp.DataTypeMap) error {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	_, err := session.DB(repository.db.Params.DbName).C(DataTypeMapCollection).UpsertId(bot, &dataTypeMapDocument{
+		Bot:       bot,
+		DataTypes: dataTypeMap,
+		UpdatedAt: time.Now(),
+	})
+
+	if err != nil {
+		log.WithField("bot", bot).Infof("Could not save the data type map: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+func init() {
+	nlp.RegisterDataTypeRepositoryBuilder("mongo", newDataTypeRepository)
+}
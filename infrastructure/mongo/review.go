@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const ReviewItemCollection = "review_item"
+
+// reviewRepository is the unexported struct that implements the nlp.ReviewRepository interface
+type reviewRepository struct {
+	db *Db
+}
+
+// newReviewRepository creates a new review repository using MongoDb as the data source
+func newReviewRepository(conf utils.BuilderConf) (interface{}, error) {
+	db, ok := utils.GetParam(conf, "db").(*Db)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("db")
+	}
+
+	return &reviewRepository{
+		db: db,
+	}, nil
+}
+
+// Insert inserts a new review item
+func (repository *reviewRepository) Insert(item *nlp.ReviewItem) error {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	err := session.DB(repository.db.Params.DbName).C(ReviewItemCollection).Insert(item)
+
+	if err != nil {
+		log.WithField("item", item.Id).Infof("Could not insert the review item: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// Update updates an existing review item
+func (repository *reviewRepository) Update(item *nlp.ReviewItem) error {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	err := session.DB(repository.db.Params.DbName).C(ReviewItemCollection).UpdateId(item.Id, item)
+
+	if err != nil {
+		log.WithField("item", item.Id).Infof("Could not update the review item: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// FindById tries to find a review item based on its id.
+// Returns an nlp.ErrReviewItemNotFound error when the item is not found
+func (repository *reviewRepository) FindById(id bson.ObjectId) (*nlp.ReviewItem, error) {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	item := &nlp.ReviewItem{}
+
+	err := session.DB(repository.db.Params.DbName).C(ReviewItemCollection).FindId(id).One(item)
+
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nlp.ErrReviewItemNotFound
+		}
+
+		log.WithField("id", id).Infof("Could not find the review item: %s", err)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// FindByBot returns the bot's review items having the status, from the oldest to the newest.
+// All of the bot's items are returned when the status is empty.
+func (repository *reviewRepository) FindByBot(bot string, status nlp.ReviewStatus) ([]*nlp.ReviewItem, error) {
+	session := repository.db.NewSession()
+	defer session.Close()
+
+	query := bson.M{"bot": bot}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	items := []*nlp.ReviewItem{}
+
+	err := session.DB(repository.db.Params.DbName).C(ReviewItemCollection).Find(query).Sort("created_at").All(&items)
+
+	if err != nil {
+		log.WithField("bot", bot).Infof("Could not find the review items: %s", err)
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func init() {
+	nlp.RegisterReviewRepositoryBuilder("mongo", newReviewRepository)
+}
@@ -0,0 +1,237 @@
+// Package rasa implements NLP objects using a self-hosted Rasa NLU server as the datasource.
+package rasa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCouldNotMarshalJson = errors.New("Could not marshal JSON object")
+	ErrInvalidStatusCode   = errors.New("Invalid status code returned")
+)
+
+// rasaApi is the struct used to make calls to the Rasa server
+type rasaApi struct {
+	client  *http.Client
+	baseUrl *url.URL
+	token   string
+	parser  *rasaParser
+}
+
+// newRasaApi creates a new rasaApi using the given conf. The base url is the url of the
+// Rasa server, such as "http://localhost:5005", and the token is the server's optional auth token.
+func newRasaApi(conf utils.BuilderConf) (interface{}, error) {
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	rawBaseUrl, ok := utils.GetParam(conf, "base_url").(string)
+
+	if !ok || rawBaseUrl == "" {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	baseUrl, err := url.Parse(strings.TrimRight(rawBaseUrl, "/"))
+
+	if err != nil {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	// The token and the bot's data types are optional
+	token, _ := utils.GetParam(conf, "token").(string)
+	dataTypes, _ := utils.GetParam(conf, "data_types").(*nlp.DataTypes)
+
+	return &rasaApi{
+		client:  client,
+		baseUrl: baseUrl,
+		token:   token,
+		parser:  newRasaParser(dataTypes),
+	}, nil
+}
+
+// GetIntents gets the list of intents from the domain of the server's model
+func (api *rasaApi) GetIntents() ([]*nlp.Intent, error) {
+	domain, err := api.getDomain()
+
+	if err != nil {
+		return nil, err
+	}
+
+	intents := []*nlp.Intent{}
+
+	for _, name := range domainNames(domain.Intents) {
+		intents = append(intents, nlp.NewIntent(name))
+	}
+
+	return intents, nil
+}
+
+// GetEntities gets the list of entities from the domain of the server's model.
+// The entities' types are found using the parser's data types, when known.
+func (api *rasaApi) GetEntities() ([]*nlp.Entity, error) {
+	domain, err := api.getDomain()
+
+	if err != nil {
+		return nil, err
+	}
+
+	entities := []*nlp.Entity{}
+
+	for _, name := range domainNames(domain.Entities) {
+		dataType, _ := api.parser.dataType(name)
+		entities = append(entities, nlp.NewEntity(name, dataType))
+	}
+
+	return entities, nil
+}
+
+// Parse sends the text to the server's /model/parse endpoint, and parses the intents
+// and entities it returned. The session id, if any, is given as the message id.
+// This method is required in order to implement the nlp.Api interface.
+func (api *rasaApi) Parse(text string, context *nlp.Context) (*nlp.ParsedData, error) {
+	payload := map[string]interface{}{
+		"text": text,
+	}
+
+	if context != nil && context.SessionId != "" {
+		payload["message_id"] = context.SessionId
+	}
+
+	var envelope json.RawMessage
+
+	err := api.callApi("POST", api.getUrl("/model/parse"), payload, &envelope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api.parser.ParseNlpData(envelope)
+}
+
+// domainEnvelope is the domain of the server's model. The intents and entities are either
+// given by their name, or as objects keyed by their name along with their properties.
+type domainEnvelope struct {
+	Intents  []interface{} `json:"intents"`
+	Entities []interface{} `json:"entities"`
+}
+
+// getDomain gets the domain of the server's model
+func (api *rasaApi) getDomain() (*domainEnvelope, error) {
+	domain := &domainEnvelope{}
+
+	err := api.callApi("GET", api.getUrl("/domain"), nil, domain)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return domain, nil
+}
+
+// domainNames returns the names of the domain's intents or entities
+func domainNames(items []interface{}) []string {
+	var names []string
+
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			names = append(names, v)
+		case map[string]interface{}:
+			for name := range v {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// callApi calls the API given a method, an URL, an optional payload and an envelope. If it is a success,
+// then the data is parsed and stored inside the envelope (using JSON).
+// Returns an error if anything happens or if the status code != 200.
+func (api *rasaApi) callApi(method string, u *url.URL, payload interface{}, envelope interface{}) error {
+	var body []byte
+
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+
+		if err != nil {
+			log.WithField("payload", payload).Infof("Could not marshal the payload: %s", err)
+			return ErrCouldNotMarshalJson
+		}
+	}
+
+	request, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+
+	if err != nil {
+		log.WithField("url", u.Path).Infof("Could not create a new request: %s", err)
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := api.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return err
+	}
+
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		log.Infof("Failed to read the response body: %s", err)
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		log.WithFields(log.Fields{
+			"code": response.StatusCode,
+			"body": string(responseBody),
+		}).Info("API returned a non-200 code")
+		return ErrInvalidStatusCode
+	}
+
+	err = json.Unmarshal(responseBody, envelope)
+
+	if err != nil {
+		log.Infof("Failed to unmarshal the response body: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// getUrl returns the url of the given path, authenticated with the token when there is one
+func (api *rasaApi) getUrl(path string) *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + path)
+
+	if api.token != "" {
+		q := u.Query()
+		q.Set("token", api.token)
+		u.RawQuery = q.Encode()
+	}
+
+	return u
+}
+
+func init() {
+	nlp.RegisterApiBuilder("rasa", newRasaApi)
+	nlp.RegisterTextParserBuilder("rasa", newRasaApi)
+}
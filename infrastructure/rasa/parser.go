@@ -0,0 +1,347 @@
+package rasa
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Duckling dimensions, used as the entities' names by the Duckling extractor
+const (
+	ducklingNumber   = "number"
+	ducklingTime     = "time"
+	ducklingMoney    = "amount-of-money"
+	ducklingDuration = "duration"
+	ducklingEmail    = "email"
+	ducklingPhone    = "phone-number"
+	ducklingUrl      = "url"
+)
+
+var (
+	ErrCouldNotParseJson = errors.New("Could not parse JSON")
+	ErrUnhandledValue    = errors.New("Unhandled entity value")
+
+	// defaultDataTypeMap is the data type map of the Duckling entities. The bots' own entities
+	// are given by the bots' data types (see nlp.DataTypes).
+	defaultDataTypeMap = nlp.DataTypeMap{
+		ducklingNumber:   nlp.IntEntity,
+		ducklingTime:     nlp.DateTimeEntity,
+		ducklingMoney:    nlp.MoneyEntity,
+		ducklingDuration: nlp.DurationEntity,
+		ducklingEmail:    nlp.EmailEntity,
+		ducklingPhone:    nlp.PhoneEntity,
+		ducklingUrl:      nlp.UrlEntity,
+	}
+)
+
+// parseEnvelope is the response of the /model/parse endpoint.
+// More information here: https://rasa.com/docs/rasa/pages/http-api#operation/parseModelMessage
+type parseEnvelope struct {
+	Intent        *rankedIntent   `json:"intent"`
+	IntentRanking []*rankedIntent `json:"intent_ranking"`
+	Entities      []*entity       `json:"entities"`
+}
+
+// rankedIntent is an intent, along with its confidence
+type rankedIntent struct {
+	Name       string  `json:"name"`
+	Confidence float32 `json:"confidence"`
+}
+
+// entity is an entity extracted from the text, by any of the model's extractors
+type entity struct {
+	Entity string      `json:"entity"`
+	Value  interface{} `json:"value"`
+	// Confidence is given by the Duckling extractor, and ConfidenceEntity by the others
+	Confidence       *float32 `json:"confidence"`
+	ConfidenceEntity *float32 `json:"confidence_entity"`
+	Extractor        string   `json:"extractor"`
+	Role             string   `json:"role"`
+	Group            string   `json:"group"`
+	AdditionalInfo   *struct {
+		Grain string `json:"grain"`
+		// Unit is the currency of the amounts of money
+		Unit string `json:"unit"`
+		// Normalized is the duration in seconds
+		Normalized *struct {
+			Value float64 `json:"value"`
+		} `json:"normalized"`
+		From *struct {
+			Grain string `json:"grain"`
+		} `json:"from"`
+		To *struct {
+			Grain string `json:"grain"`
+		} `json:"to"`
+	} `json:"additional_info"`
+}
+
+// rasaParser is the NLP parser for Rasa.
+// It implements the nlp.Parser interface.
+type rasaParser struct {
+	// dataTypes are the bot's data types, if any
+	dataTypes *nlp.DataTypes
+}
+
+// newParser is the builder of rasaParser
+// The bot's data types are given using the optional "data_types" param.
+func newParser(conf utils.BuilderConf) (interface{}, error) {
+	dataTypes, _ := utils.GetParam(conf, "data_types").(*nlp.DataTypes)
+
+	return newRasaParser(dataTypes), nil
+}
+
+// newRasaParser is the constructor method for rasaParser
+func newRasaParser(dataTypes *nlp.DataTypes) *rasaParser {
+	return &rasaParser{
+		dataTypes: dataTypes,
+	}
+}
+
+// dataType returns the type of the entity, using the bot's data types and the default data type map
+func (parser *rasaParser) dataType(name string) (nlp.EntityType, bool) {
+	return parser.dataTypes.Lookup(name, defaultDataTypeMap)
+}
+
+// ParseNlpData parses a /model/parse response and returns parsed data.
+// The whole intent ranking is kept, and entities without any confidence, such as the ones
+// found by regexes or lookup tables, are considered certain.
+func (parser *rasaParser) ParseNlpData(rawData []byte) (*nlp.ParsedData, error) {
+	envelope := &parseEnvelope{}
+	err := json.Unmarshal(rawData, envelope)
+
+	if err != nil {
+		log.WithField("rawData", string(rawData)).Infof("Could not parse JSON: %s", err)
+		return nil, ErrCouldNotParseJson
+	}
+
+	var intent *nlp.ParsedIntent
+	var intents []*nlp.ParsedIntent
+	var entities []*nlp.ParsedEntity
+
+	if envelope.Intent != nil && envelope.Intent.Name != "" {
+		intent = nlp.NewParsedIntent(envelope.Intent.Name, envelope.Intent.Confidence)
+	}
+
+	for _, ranked := range envelope.IntentRanking {
+		// The ranking starts with the intent itself
+		if intent != nil && ranked.Name == intent.Intent.Name {
+			intents = append(intents, intent)
+			continue
+		}
+
+		intents = append(intents, nlp.NewParsedIntent(ranked.Name, ranked.Confidence))
+	}
+
+	for _, e := range envelope.Entities {
+		parsedEntity, err := parser.toEntity(e)
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"entity":    e.Entity,
+				"extractor": e.Extractor,
+				"value":     e.Value,
+			}).Warnf("Could not convert value to entity: %s", err)
+			continue
+		}
+
+		entities = append(entities, parsedEntity)
+	}
+
+	data := nlp.NewParsedData(intent, entities)
+	data.Intents = intents
+
+	return data, nil
+}
+
+// toEntity converts an entity to a built-in NLP representation of an entity.
+// Entities with a role are looked up using their role when their name is not handled.
+func (parser *rasaParser) toEntity(e *entity) (*nlp.ParsedEntity, error) {
+	name := e.Entity
+	dataType, ok := parser.dataType(name)
+
+	if !ok && e.Role != "" {
+		name = e.Role
+		dataType, ok = parser.dataType(name)
+	}
+
+	if !ok {
+		parser.dataTypes.ReportUnknown(name)
+		return nil, ErrUnhandledValue
+	}
+
+	var confidence float32 = 1
+
+	if e.ConfidenceEntity != nil {
+		confidence = *e.ConfidenceEntity
+	} else if e.Confidence != nil {
+		confidence = *e.Confidence
+	}
+
+	var parsedEntity *nlp.ParsedEntity
+
+	switch dataType {
+	case nlp.IntEntity:
+		value, ok := toInt(e.Value)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		parsedEntity = nlp.NewParsedIntEntity(name, confidence, value, e.Role)
+	case nlp.NumberEntity:
+		value, ok := toFloat(e.Value)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		parsedEntity = nlp.NewParsedNumberEntity(name, confidence, value, e.Role)
+	case nlp.StringEntity, nlp.EnumEntity, nlp.EmailEntity, nlp.PhoneEntity, nlp.UrlEntity:
+		value, ok := e.Value.(string)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		parsedEntity = nlp.NewParsedTextEntity(name, confidence, value, dataType, e.Role)
+	case nlp.MoneyEntity:
+		value, ok := toFloat(e.Value)
+
+		if !ok {
+			return nil, ErrUnhandledValue
+		}
+
+		currency := ""
+
+		if e.AdditionalInfo != nil {
+			currency = e.AdditionalInfo.Unit
+		}
+
+		parsedEntity = nlp.NewParsedMoneyEntity(name, confidence, value, currency, e.Role)
+	case nlp.DurationEntity:
+		if e.AdditionalInfo == nil || e.AdditionalInfo.Normalized == nil {
+			return nil, ErrUnhandledValue
+		}
+
+		seconds := e.AdditionalInfo.Normalized.Value
+		parsedEntity = nlp.NewParsedDurationEntity(name, confidence, time.Duration(seconds*float64(time.Second)), e.Role)
+	case nlp.DateTimeEntity:
+		var err error
+		parsedEntity, err = toDateTimeEntity(name, e, confidence)
+
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnhandledValue
+	}
+
+	parsedEntity.Group = e.Group
+
+	return parsedEntity, nil
+}
+
+// toInt converts a value to an int. Values can be numbers, as given by Duckling,
+// or texts, as given by the other extractors.
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+
+		return int(v), true
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+// toFloat converts a value to a float. Values can be numbers, as given by Duckling,
+// or texts, as given by the other extractors.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// toDateTimeEntity converts a time, as given by Duckling, to a datetime entity.
+// Intervals are given as "from" and "to" values, where "to" is optional.
+func toDateTimeEntity(name string, e *entity, confidence float32) (*nlp.ParsedEntity, error) {
+	switch v := e.Value.(type) {
+	case string:
+		t, err := utils.ParseTime(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		granularity := nlp.GranularityHour
+
+		if e.AdditionalInfo != nil && e.AdditionalInfo.Grain != "" {
+			granularity = nlp.DateTimeGranularity(e.AdditionalInfo.Grain)
+		}
+
+		return nlp.NewParsedSingleDateTimeEntity(name, confidence, t, granularity, e.Role), nil
+	case map[string]interface{}:
+		from, _ := v["from"].(string)
+		to, _ := v["to"].(string)
+
+		if from == "" {
+			return nil, ErrUnhandledValue
+		}
+
+		fromTime, err := utils.ParseTime(from)
+
+		if err != nil {
+			return nil, err
+		}
+
+		toTime := fromTime
+
+		if to != "" {
+			toTime, err = utils.ParseTime(to)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		fromGranularity, toGranularity := nlp.GranularityHour, nlp.GranularityHour
+
+		if info := e.AdditionalInfo; info != nil {
+			if info.From != nil && info.From.Grain != "" {
+				fromGranularity = nlp.DateTimeGranularity(info.From.Grain)
+			}
+
+			if info.To != nil && info.To.Grain != "" {
+				toGranularity = nlp.DateTimeGranularity(info.To.Grain)
+			}
+		}
+
+		return nlp.NewParsedDateTimeIntervalEntity(name, confidence, fromTime, toTime, fromGranularity, toGranularity, e.Role), nil
+	}
+
+	return nil, ErrUnhandledValue
+}
+
+func init() {
+	nlp.RegisterParserBuilder("rasa", newParser)
+}
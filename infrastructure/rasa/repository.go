@@ -0,0 +1,40 @@
+package rasa
+
+import (
+	"github.com/aziule/conversation-management/core/nlp"
+	"github.com/aziule/conversation-management/core/utils"
+)
+
+// rasaRepository is the struct used to access data from Rasa
+type rasaRepository struct {
+	api nlp.Api
+}
+
+// newRasaRepository instantiates a new rasaRepository using the given conf
+func newRasaRepository(conf utils.BuilderConf) (interface{}, error) {
+	api, ok := utils.GetParam(conf, "api").(nlp.Api)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("api")
+	}
+
+	return &rasaRepository{
+		api: api,
+	}, nil
+}
+
+// GetIntents is the method returning all of the available intents.
+// It is required in order to implement the nlp.Repository interface.
+func (repository *rasaRepository) GetIntents() ([]*nlp.Intent, error) {
+	return repository.api.GetIntents()
+}
+
+// GetEntities is the method returning all of the available entities.
+// It is required in order to implement the nlp.Repository interface.
+func (repository *rasaRepository) GetEntities() ([]*nlp.Entity, error) {
+	return repository.api.GetEntities()
+}
+
+func init() {
+	nlp.RegisterRepositoryBuilder("rasa", newRasaRepository)
+}
@@ -0,0 +1,132 @@
+// Package slack provides a Slack API to be used by bots running on Slack.
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBaseUrl is the base url of the Slack Web API
+const defaultBaseUrl = "https://slack.com/api"
+
+var (
+	ErrCouldNotMarshalJson = errors.New("Could not marshal JSON object")
+	ErrApiCallFailed       = errors.New("The Web API call failed")
+)
+
+// slackApi is the real-world implementation of the API
+type slackApi struct {
+	botToken string
+	client   *http.Client
+	baseUrl  *url.URL
+}
+
+// newSlackApi is the constructor that creates a new Slack API, using the bot's token.
+// The base url can be changed, for example to use a local stand-in.
+func newSlackApi(conf utils.BuilderConf) (interface{}, error) {
+	botToken, ok := utils.GetParam(conf, "token").(string)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("token")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	rawBaseUrl, ok := utils.GetParam(conf, "base_url").(string)
+
+	if !ok || rawBaseUrl == "" {
+		rawBaseUrl = defaultBaseUrl
+	}
+
+	baseUrl, err := url.Parse(rawBaseUrl)
+
+	if err != nil {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	return &slackApi{
+		botToken: botToken,
+		client:   client,
+		baseUrl:  baseUrl,
+	}, nil
+}
+
+// getMethodUrl returns the url to ping to call a Web API method
+func (api *slackApi) getMethodUrl(method string) *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + "/" + method)
+
+	return u
+}
+
+// responseEnvelope is the JSON envelope returned by every Web API method
+type responseEnvelope struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// callApi calls a Web API method with the given payload, sent as JSON.
+// Returns an error if anything happens or if the API did not return "ok".
+func (api *slackApi) callApi(method string, payload interface{}) error {
+	jsonObject, err := json.Marshal(payload)
+
+	if err != nil {
+		log.WithField("payload", payload).Infof("Could not marshal the payload: %s", err)
+		return ErrCouldNotMarshalJson
+	}
+
+	request, err := http.NewRequest("POST", api.getMethodUrl(method).String(), bytes.NewBuffer(jsonObject))
+
+	if err != nil {
+		log.WithField("method", method).Infof("Could not create a new request: %s", err)
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("Authorization", "Bearer "+api.botToken)
+
+	response, err := api.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return err
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		log.Infof("Failed to read the response body: %s", err)
+		return err
+	}
+
+	envelope := &responseEnvelope{}
+	err = json.Unmarshal(body, envelope)
+
+	if err != nil || !envelope.Ok {
+		log.WithFields(log.Fields{
+			"method": method,
+			"code":   response.StatusCode,
+			"error":  envelope.Error,
+		}).Info("The Web API call failed")
+		return ErrApiCallFailed
+	}
+
+	return nil
+}
+
+func init() {
+	api.RegisterSlackApiBuilder("slack", newSlackApi)
+}
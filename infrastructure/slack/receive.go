@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCouldNotReadRequestBody = errors.New("Could not read the request's body")
+	ErrInvalidJson             = errors.New("Invalid JSON")
+	ErrUnhandledEvent          = errors.New("Unhandled event type")
+	ErrNoAction                = errors.New("No action to parse")
+)
+
+// eventEnvelope is the JSON envelope of the requests sent by the Events API
+type eventEnvelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	TeamId    string `json:"team_id"`
+	EventId   string `json:"event_id"`
+	Event     *struct {
+		Type        string `json:"type"`
+		User        string `json:"user"`
+		BotId       string `json:"bot_id"`
+		Subtype     string `json:"subtype"`
+		Text        string `json:"text"`
+		Channel     string `json:"channel"`
+		ChannelType string `json:"channel_type"`
+		Ts          string `json:"ts"`
+	} `json:"event"`
+}
+
+// interactionEnvelope is the JSON envelope of the interactions payloads
+type interactionEnvelope struct {
+	Type string `json:"type"`
+	User struct {
+		Id string `json:"id"`
+	} `json:"user"`
+	Team struct {
+		Id string `json:"id"`
+	} `json:"team"`
+	Channel struct {
+		Id string `json:"id"`
+	} `json:"channel"`
+	Actions []struct {
+		ActionId string `json:"action_id"`
+		Value    string `json:"value"`
+		Text     struct {
+			Text string `json:"text"`
+		} `json:"text"`
+		ActionTs string `json:"action_ts"`
+	} `json:"actions"`
+}
+
+// ParseEvent creates a SlackEvent from a request sent by the Events API.
+// Returns ErrUnhandledEvent for the events that are not handled.
+func (slackApi *slackApi) ParseEvent(r *http.Request) (*api.SlackEvent, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		return nil, ErrCouldNotReadRequestBody
+	}
+
+	envelope := &eventEnvelope{}
+	err = json.Unmarshal(body, envelope)
+
+	if err != nil {
+		log.WithField("body", string(body)).Infof("Could not parse JSON from the request: %s", err)
+		return nil, ErrInvalidJson
+	}
+
+	if envelope.Type == api.SlackEventUrlVerification {
+		return &api.SlackEvent{
+			Type:      api.SlackEventUrlVerification,
+			Challenge: envelope.Challenge,
+		}, nil
+	}
+
+	if envelope.Event == nil {
+		return nil, ErrUnhandledEvent
+	}
+
+	event := envelope.Event
+
+	if event.Type != api.SlackEventMessage && event.Type != api.SlackEventAppMention {
+		log.WithField("type", event.Type).Debug("Unhandled event type")
+		return nil, ErrUnhandledEvent
+	}
+
+	// Message changes, deletions, etc. are sent as messages with a subtype
+	if event.Subtype != "" && event.Subtype != "bot_message" {
+		log.WithField("subtype", event.Subtype).Debug("Unhandled message subtype")
+		return nil, ErrUnhandledEvent
+	}
+
+	return &api.SlackEvent{
+		Type:        event.Type,
+		EventId:     envelope.EventId,
+		TeamId:      envelope.TeamId,
+		UserId:      event.User,
+		ChannelId:   event.Channel,
+		ChannelType: event.ChannelType,
+		BotId:       event.BotId,
+		Text:        event.Text,
+		SentAt:      parseTs(event.Ts),
+	}, nil
+}
+
+// ParseInteraction creates a SlackEvent from a request sent when the user interacts with
+// a Block Kit component. The interaction is sent as a form, with a JSON "payload" field.
+// Only block actions are handled.
+func (slackApi *slackApi) ParseInteraction(r *http.Request) (*api.SlackEvent, error) {
+	err := r.ParseForm()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		return nil, ErrCouldNotReadRequestBody
+	}
+
+	envelope := &interactionEnvelope{}
+	err = json.Unmarshal([]byte(r.PostForm.Get("payload")), envelope)
+
+	if err != nil {
+		log.Infof("Could not parse JSON from the request: %s", err)
+		return nil, ErrInvalidJson
+	}
+
+	if envelope.Type != api.SlackEventBlockActions {
+		log.WithField("type", envelope.Type).Debug("Unhandled interaction type")
+		return nil, ErrUnhandledEvent
+	}
+
+	if len(envelope.Actions) == 0 {
+		return nil, ErrNoAction
+	}
+
+	action := envelope.Actions[0]
+
+	return &api.SlackEvent{
+		Type:        api.SlackEventBlockActions,
+		TeamId:      envelope.Team.Id,
+		UserId:      envelope.User.Id,
+		ChannelId:   envelope.Channel.Id,
+		Text:        action.Text.Text,
+		SentAt:      parseTs(action.ActionTs),
+		ActionValue: action.Value,
+	}, nil
+}
+
+// parseTs converts a Slack timestamp, such as "1355517523.000005", to a time.Time.
+// Returns the current time if the timestamp is malformed.
+func parseTs(ts string) time.Time {
+	seconds, err := strconv.ParseInt(strings.SplitN(ts, ".", 2)[0], 10, 64)
+
+	if err != nil {
+		return time.Now()
+	}
+
+	return time.Unix(seconds, 0)
+}
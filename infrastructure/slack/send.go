@@ -0,0 +1,35 @@
+package slack
+
+import (
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// PostMessage is the SlackApi's interface method responsible for sending a message to a channel.
+// The text is used as a fallback for notifications when blocks are provided.
+func (slackApi *slackApi) PostMessage(channelId, text string, blocks []*api.SlackBlock) error {
+	envelope := &postMessageEnvelope{
+		Channel: channelId,
+		Text:    text,
+		Blocks:  blocks,
+	}
+
+	err := slackApi.callApi("chat.postMessage", envelope)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"channel": channelId,
+			"text":    text,
+		}).Infof("Could not post the message: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// postMessageEnvelope is the JSON envelope that needs to be sent to post a message
+type postMessageEnvelope struct {
+	Channel string            `json:"channel"`
+	Text    string            `json:"text"`
+	Blocks  []*api.SlackBlock `json:"blocks,omitempty"`
+}
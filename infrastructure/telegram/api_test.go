@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+)
+
+// testToken is the bot token used by the test cases
+const testToken = "123456:test-token"
+
+// botApiCall is a call received by the fake Bot API server
+type botApiCall struct {
+	Path    string
+	Payload map[string]interface{}
+}
+
+// fakeBotApi is a local stand-in for the Bot API, recording the calls it receives
+// and answering them with the given response
+type fakeBotApi struct {
+	server   *httptest.Server
+	response string
+	mutex    sync.Mutex
+	calls    []*botApiCall
+}
+
+// newFakeBotApi starts a fake Bot API server, answering every call with the given response
+func newFakeBotApi(t *testing.T, response string) *fakeBotApi {
+	fake := &fakeBotApi{response: response}
+
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := &botApiCall{Path: r.URL.Path}
+
+		if err := json.NewDecoder(r.Body).Decode(&call.Payload); err != nil {
+			t.Errorf("Could not decode the payload of %s: %s", r.URL.Path, err)
+		}
+
+		fake.mutex.Lock()
+		fake.calls = append(fake.calls, call)
+		fake.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fake.response))
+	}))
+
+	return fake
+}
+
+// recorded returns the calls received so far
+func (fake *fakeBotApi) recorded() []*botApiCall {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	return fake.calls
+}
+
+// newTestApi creates a Telegram API calling the fake Bot API server
+func newTestApi(t *testing.T, fake *fakeBotApi) *telegramApi {
+	built, err := newTelegramApi(map[string]interface{}{
+		"token":    testToken,
+		"base_url": fake.server.URL,
+		"client":   fake.server.Client(),
+	})
+
+	if err != nil {
+		t.Fatalf("Could not create the Telegram API: %s", err)
+	}
+
+	return built.(*telegramApi)
+}
+
+// TestSetWebhook checks that the webhook is registered along with its secret token,
+// and that only the handled updates are requested
+func TestSetWebhook(t *testing.T) {
+	fake := newFakeBotApi(t, `{"ok":true,"result":true,"description":"Webhook was set"}`)
+	defer fake.server.Close()
+
+	err := newTestApi(t, fake).SetWebhook("https://example.com/api/bots/booking/webhooks/", "s3cr3t")
+
+	if err != nil {
+		t.Fatalf("Could not set the webhook: %s", err)
+	}
+
+	calls := fake.recorded()
+
+	if len(calls) != 1 {
+		t.Fatalf("Expected a single call, got %d", len(calls))
+	}
+
+	if calls[0].Path != "/bot"+testToken+"/setWebhook" {
+		t.Errorf("Unexpected path: %s", calls[0].Path)
+	}
+
+	if calls[0].Payload["url"] != "https://example.com/api/bots/booking/webhooks/" {
+		t.Errorf("Unexpected url: %v", calls[0].Payload["url"])
+	}
+
+	if calls[0].Payload["secret_token"] != "s3cr3t" {
+		t.Errorf("Unexpected secret token: %v", calls[0].Payload["secret_token"])
+	}
+
+	allowedUpdates, _ := json.Marshal(calls[0].Payload["allowed_updates"])
+
+	if string(allowedUpdates) != `["message","callback_query"]` {
+		t.Errorf("Unexpected allowed updates: %s", allowedUpdates)
+	}
+}
+
+// TestSetWebhookFailure checks that the errors returned by the Bot API are reported
+func TestSetWebhookFailure(t *testing.T) {
+	fake := newFakeBotApi(t, `{"ok":false,"error_code":400,"description":"Bad Request: bad webhook: HTTPS url must be provided for webhook"}`)
+	defer fake.server.Close()
+
+	err := newTestApi(t, fake).SetWebhook("http://example.com/", "s3cr3t")
+
+	if err != ErrApiCallFailed {
+		t.Errorf("Expected ErrApiCallFailed, got %v", err)
+	}
+}
+
+// TestParseUpdate parses the updates recorded within testdata/
+func TestParseUpdate(t *testing.T) {
+	cases := []struct {
+		file     string
+		expected *api.TelegramUpdate
+		err      error
+	}{
+		{
+			file: "message.json",
+			expected: &api.TelegramUpdate{
+				UpdateId: 10001,
+				ChatId:   -2222,
+				SenderId: 1111,
+				SentAt:   time.Unix(1700000000, 0),
+				Text:     "A table for 4 people",
+			},
+		},
+		{
+			file: "callback_query.json",
+			expected: &api.TelegramUpdate{
+				UpdateId:        10002,
+				ChatId:          -2222,
+				SenderId:        3333,
+				Text:            "NB_PERSONS_4",
+				CallbackQueryId: "4382bfdwdsb323b2d9",
+				CallbackData:    "NB_PERSONS_4",
+			},
+		},
+		{
+			file: "edited_message.json",
+			err:  ErrUnhandledUpdate,
+		},
+	}
+
+	fake := newFakeBotApi(t, `{"ok":true}`)
+	defer fake.server.Close()
+
+	telegramApi := newTestApi(t, fake)
+
+	for _, c := range cases {
+		t.Run(strings.TrimSuffix(c.file, ".json"), func(t *testing.T) {
+			body, err := ioutil.ReadFile(filepath.Join("testdata", c.file))
+
+			if err != nil {
+				t.Fatalf("Could not read %s: %s", c.file, err)
+			}
+
+			update, err := telegramApi.ParseUpdate(httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+
+			if err != c.err {
+				t.Fatalf("Expected error %v, got %v", c.err, err)
+			}
+
+			if c.expected == nil {
+				return
+			}
+
+			// Callback queries have no date: they are received right away
+			if c.expected.SentAt.IsZero() {
+				c.expected.SentAt = update.SentAt
+			}
+
+			if *update != *c.expected {
+				t.Errorf("Unexpected update:\n got: %+v\nwant: %+v", update, c.expected)
+			}
+		})
+	}
+}
+
+// TestSendMessage checks that the quick replies are sent as an inline keyboard, to the given chat
+func TestSendMessage(t *testing.T) {
+	fake := newFakeBotApi(t, `{"ok":true,"result":{"message_id":44}}`)
+	defer fake.server.Close()
+
+	err := newTestApi(t, fake).SendMessage(-2222, "How many people?", [][]*api.TelegramInlineButton{
+		{{Text: "4", CallbackData: "NB_PERSONS_4"}},
+	})
+
+	if err != nil {
+		t.Fatalf("Could not send the message: %s", err)
+	}
+
+	calls := fake.recorded()
+
+	if len(calls) != 1 || calls[0].Path != "/bot"+testToken+"/sendMessage" {
+		t.Fatalf("Unexpected calls: %+v", calls)
+	}
+
+	payload, _ := json.Marshal(calls[0].Payload)
+	expected := `{"chat_id":-2222,"reply_markup":{"inline_keyboard":[[{"callback_data":"NB_PERSONS_4","text":"4"}]]},"text":"How many people?"}`
+
+	if string(payload) != expected {
+		t.Errorf("Unexpected payload:\n got: %s\nwant: %s", payload, expected)
+	}
+}
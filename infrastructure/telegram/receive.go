@@ -0,0 +1,118 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCouldNotReadRequestBody = errors.New("Could not read the request's body")
+	ErrInvalidJson             = errors.New("Invalid JSON")
+	ErrUnhandledUpdate         = errors.New("Unhandled update type")
+)
+
+// updateEnvelope is the JSON envelope of an update sent to the webhook
+type updateEnvelope struct {
+	UpdateId      int64                  `json:"update_id"`
+	Message       *messageEnvelope       `json:"message"`
+	CallbackQuery *callbackQueryEnvelope `json:"callback_query"`
+}
+
+// messageEnvelope is the JSON envelope of a message
+type messageEnvelope struct {
+	MessageId int64 `json:"message_id"`
+	Date      int64 `json:"date"`
+	From      *struct {
+		Id int64 `json:"id"`
+	} `json:"from"`
+	Chat struct {
+		Id int64 `json:"id"`
+	} `json:"chat"`
+	Text     string `json:"text"`
+	Location *struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"location"`
+}
+
+// callbackQueryEnvelope is the JSON envelope of a callback query, sent
+// when the user presses a button of an inline keyboard
+type callbackQueryEnvelope struct {
+	Id   string `json:"id"`
+	From struct {
+		Id int64 `json:"id"`
+	} `json:"from"`
+	Message *messageEnvelope `json:"message"`
+	Data    string           `json:"data"`
+}
+
+// ParseUpdate creates a TelegramUpdate from the request sent to the webhook.
+// Returns ErrUnhandledUpdate for updates that are neither messages nor callback queries.
+func (telegramApi *telegramApi) ParseUpdate(r *http.Request) (*api.TelegramUpdate, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		return nil, ErrCouldNotReadRequestBody
+	}
+
+	envelope := &updateEnvelope{}
+	err = json.Unmarshal(body, envelope)
+
+	if err != nil {
+		log.WithField("body", string(body)).Infof("Could not parse JSON from the request: %s", err)
+		return nil, ErrInvalidJson
+	}
+
+	switch {
+	case envelope.Message != nil:
+		return newUpdateFromMessage(envelope.UpdateId, envelope.Message), nil
+	case envelope.CallbackQuery != nil && envelope.CallbackQuery.Message != nil:
+		callbackQuery := envelope.CallbackQuery
+
+		return &api.TelegramUpdate{
+			UpdateId:        envelope.UpdateId,
+			ChatId:          callbackQuery.Message.Chat.Id,
+			SenderId:        callbackQuery.From.Id,
+			SentAt:          time.Now(),
+			Text:            callbackQuery.Data,
+			CallbackQueryId: callbackQuery.Id,
+			CallbackData:    callbackQuery.Data,
+		}, nil
+	}
+
+	log.WithField("update", strconv.FormatInt(envelope.UpdateId, 10)).Info("Unhandled update type")
+
+	return nil, ErrUnhandledUpdate
+}
+
+// newUpdateFromMessage creates a TelegramUpdate from a message
+func newUpdateFromMessage(updateId int64, message *messageEnvelope) *api.TelegramUpdate {
+	update := &api.TelegramUpdate{
+		UpdateId: updateId,
+		ChatId:   message.Chat.Id,
+		SentAt:   time.Unix(message.Date, 0),
+		Text:     message.Text,
+	}
+
+	if message.From != nil {
+		update.SenderId = message.From.Id
+	}
+
+	if message.Location != nil {
+		update.Location = &api.TelegramLocation{
+			Latitude:  message.Location.Latitude,
+			Longitude: message.Location.Longitude,
+		}
+	}
+
+	return update
+}
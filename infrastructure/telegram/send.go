@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// SendMessage is the TelegramApi's interface method responsible for sending a message to a chat.
+// The inline keyboard is optional.
+func (telegramApi *telegramApi) SendMessage(chatId int64, text string, keyboard [][]*api.TelegramInlineButton) error {
+	envelope := &sendMessageEnvelope{
+		ChatId: chatId,
+		Text:   text,
+	}
+
+	if len(keyboard) > 0 {
+		envelope.ReplyMarkup = &inlineKeyboardEnvelope{keyboard}
+	}
+
+	err := telegramApi.callApi("sendMessage", envelope)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"chatId": chatId,
+			"text":   text,
+		}).Infof("Could not send the message: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// AnswerCallbackQuery is the TelegramApi's interface method responsible for acknowledging
+// a callback query, so that the client stops showing a progress bar on the button
+func (telegramApi *telegramApi) AnswerCallbackQuery(callbackQueryId string) error {
+	return telegramApi.callApi("answerCallbackQuery", &answerCallbackQueryEnvelope{callbackQueryId})
+}
+
+// SetWebhook is the TelegramApi's interface method responsible for registering the webhook.
+// Telegram sends the secret token within the X-Telegram-Bot-Api-Secret-Token header of every update.
+func (telegramApi *telegramApi) SetWebhook(url, secretToken string) error {
+	envelope := &setWebhookEnvelope{
+		Url:            url,
+		SecretToken:    secretToken,
+		AllowedUpdates: []string{"message", "callback_query"},
+	}
+
+	err := telegramApi.callApi("setWebhook", envelope)
+
+	if err != nil {
+		log.WithField("url", url).Infof("Could not set the webhook: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// sendMessageEnvelope is the JSON envelope that needs to be sent to send a message
+type sendMessageEnvelope struct {
+	ChatId      int64                   `json:"chat_id"`
+	Text        string                  `json:"text"`
+	ReplyMarkup *inlineKeyboardEnvelope `json:"reply_markup,omitempty"`
+}
+
+// inlineKeyboardEnvelope is the JSON envelope of an inline keyboard
+type inlineKeyboardEnvelope struct {
+	InlineKeyboard [][]*api.TelegramInlineButton `json:"inline_keyboard"`
+}
+
+// answerCallbackQueryEnvelope is the JSON envelope that needs to be sent to answer a callback query
+type answerCallbackQueryEnvelope struct {
+	CallbackQueryId string `json:"callback_query_id"`
+}
+
+// setWebhookEnvelope is the JSON envelope that needs to be sent to set the webhook
+type setWebhookEnvelope struct {
+	Url            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
@@ -0,0 +1,139 @@
+// Package twilio provides an SMS API to be used by bots running on SMS,
+// using the Twilio API or any compatible API.
+package twilio
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBaseUrl is the base url of the Twilio REST API
+const defaultBaseUrl = "https://api.twilio.com"
+
+var (
+	ErrApiCallFailed = errors.New("The REST API call failed")
+)
+
+// twilioApi is the real-world implementation of the API
+type twilioApi struct {
+	accountSid string
+	authToken  string
+	from       string
+	client     *http.Client
+	baseUrl    *url.URL
+}
+
+// newTwilioApi is the constructor that creates a new Twilio API, using the account's
+// credentials and the bot's phone number. The base url can be changed, for example
+// to use a local stand-in.
+func newTwilioApi(conf utils.BuilderConf) (interface{}, error) {
+	accountSid, ok := utils.GetParam(conf, "account_sid").(string)
+
+	if !ok || accountSid == "" {
+		return nil, utils.ErrInvalidOrMissingParam("account_sid")
+	}
+
+	authToken, ok := utils.GetParam(conf, "auth_token").(string)
+
+	if !ok || authToken == "" {
+		return nil, utils.ErrInvalidOrMissingParam("auth_token")
+	}
+
+	from, ok := utils.GetParam(conf, "from").(string)
+
+	if !ok || from == "" {
+		return nil, utils.ErrInvalidOrMissingParam("from")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	rawBaseUrl, ok := utils.GetParam(conf, "base_url").(string)
+
+	if !ok || rawBaseUrl == "" {
+		rawBaseUrl = defaultBaseUrl
+	}
+
+	baseUrl, err := url.Parse(rawBaseUrl)
+
+	if err != nil {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	return &twilioApi{
+		accountSid: accountSid,
+		authToken:  authToken,
+		from:       from,
+		client:     client,
+		baseUrl:    baseUrl,
+	}, nil
+}
+
+// getMessagesUrl returns the url to ping to send messages
+func (api *twilioApi) getMessagesUrl() *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + "/2010-04-01/Accounts/" + api.accountSid + "/Messages.json")
+
+	return u
+}
+
+// errorEnvelope is the JSON envelope returned by the REST API when an error occurs
+type errorEnvelope struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+}
+
+// callApi POSTs the form to the REST API, authenticated using the account's credentials.
+// Returns an error if anything happens or if the API did not return a 2xx status code.
+func (api *twilioApi) callApi(u *url.URL, form url.Values) error {
+	request, err := http.NewRequest("POST", u.String(), strings.NewReader(form.Encode()))
+
+	if err != nil {
+		log.WithField("url", u).Infof("Could not create a new request: %s", err)
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(api.accountSid, api.authToken)
+
+	response, err := api.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(response.Body)
+	envelope := &errorEnvelope{}
+	json.Unmarshal(body, envelope)
+
+	log.WithFields(log.Fields{
+		"code":      response.StatusCode,
+		"errorCode": envelope.Code,
+		"message":   envelope.Message,
+		"moreInfo":  envelope.MoreInfo,
+	}).Info("The REST API call failed")
+
+	return ErrApiCallFailed
+}
+
+func init() {
+	api.RegisterSmsApiBuilder("twilio", newTwilioApi)
+}
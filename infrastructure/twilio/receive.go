@@ -0,0 +1,77 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// signatureHeader is the header containing the request's signature
+const signatureHeader = "X-Twilio-Signature"
+
+var (
+	ErrCouldNotReadRequestBody = errors.New("Could not read the request's body")
+	ErrMissingSender           = errors.New("The message has no sender")
+)
+
+// ParseMessage validates the request's signature and creates a SmsMessage from
+// the form-encoded webhook.
+// More information here: https://www.twilio.com/docs/usage/webhooks/webhooks-security
+func (twilioApi *twilioApi) ParseMessage(r *http.Request, webhookUrl string) (*api.SmsMessage, error) {
+	err := r.ParseForm()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		return nil, ErrCouldNotReadRequestBody
+	}
+
+	expected := computeSignature(twilioApi.authToken, webhookUrl, r.PostForm)
+
+	if !hmac.Equal([]byte(r.Header.Get(signatureHeader)), []byte(expected)) {
+		log.WithField("url", webhookUrl).Info("Invalid request signature")
+		return nil, api.ErrInvalidSignature
+	}
+
+	if r.PostForm.Get("From") == "" {
+		return nil, ErrMissingSender
+	}
+
+	return &api.SmsMessage{
+		MessageId: r.PostForm.Get("MessageSid"),
+		From:      r.PostForm.Get("From"),
+		To:        r.PostForm.Get("To"),
+		Text:      r.PostForm.Get("Body"),
+		SentAt:    time.Now(),
+	}, nil
+}
+
+// computeSignature returns the base64-encoded HMAC-SHA1 of the url followed by the
+// POST parameters, sorted by name, each name being directly followed by its value.
+func computeSignature(authToken, webhookUrl string, params url.Values) string {
+	var names []string
+
+	for name := range params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(webhookUrl))
+
+	for _, name := range names {
+		for _, value := range params[name] {
+			mac.Write([]byte(name + value))
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
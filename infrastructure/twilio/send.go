@@ -0,0 +1,28 @@
+package twilio
+
+import (
+	"net/url"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SendMessage is the SmsApi's interface method responsible for sending a text message
+// to a phone number, from the bot's phone number.
+func (twilioApi *twilioApi) SendMessage(to, text string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", twilioApi.from)
+	form.Set("Body", text)
+
+	err := twilioApi.callApi(twilioApi.getMessagesUrl(), form)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"to":   to,
+			"text": text,
+		}).Infof("Could not send the message: %s", err)
+		return err
+	}
+
+	return nil
+}
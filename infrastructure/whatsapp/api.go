@@ -0,0 +1,160 @@
+// Package whatsapp provides a WhatsApp Cloud API to be used by bots running on WhatsApp.
+package whatsapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/aziule/conversation-management/core/api"
+	"github.com/aziule/conversation-management/core/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBaseUrl is the base url of the Graph API
+const defaultBaseUrl = "https://graph.facebook.com"
+
+var (
+	ErrCouldNotMarshalJson = errors.New("Could not marshal JSON object")
+)
+
+// whatsAppApi is the real-world implementation of the API
+type whatsAppApi struct {
+	accessToken   string
+	phoneNumberId string
+	client        *http.Client
+	baseUrl       *url.URL
+}
+
+// newWhatsAppApi is the constructor that creates a new WhatsApp API, using the access token,
+// the id of the bot's phone number and the Graph API version.
+// The base url can be changed, for example to use a local stand-in.
+func newWhatsAppApi(conf utils.BuilderConf) (interface{}, error) {
+	accessToken, ok := utils.GetParam(conf, "access_token").(string)
+
+	if !ok || accessToken == "" {
+		return nil, utils.ErrInvalidOrMissingParam("access_token")
+	}
+
+	phoneNumberId, ok := utils.GetParam(conf, "phone_number_id").(string)
+
+	if !ok || phoneNumberId == "" {
+		return nil, utils.ErrInvalidOrMissingParam("phone_number_id")
+	}
+
+	version, ok := utils.GetParam(conf, "version").(string)
+
+	if !ok || version == "" {
+		return nil, utils.ErrInvalidOrMissingParam("version")
+	}
+
+	client, ok := utils.GetParam(conf, "client").(*http.Client)
+
+	if !ok {
+		return nil, utils.ErrInvalidOrMissingParam("client")
+	}
+
+	rawBaseUrl, ok := utils.GetParam(conf, "base_url").(string)
+
+	if !ok || rawBaseUrl == "" {
+		rawBaseUrl = defaultBaseUrl
+	}
+
+	baseUrl, err := url.Parse(rawBaseUrl + "/v" + version)
+
+	if err != nil {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	return &whatsAppApi{
+		accessToken:   accessToken,
+		phoneNumberId: phoneNumberId,
+		client:        client,
+		baseUrl:       baseUrl,
+	}, nil
+}
+
+// getMessagesUrl returns the url to ping to send messages from the bot's phone number
+func (api *whatsAppApi) getMessagesUrl() *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + "/" + api.phoneNumberId + "/messages")
+
+	return u
+}
+
+// callApi POSTs the payload to the Graph API, as JSON.
+// Graph API errors are returned as *api.GraphError.
+func (whatsAppApi *whatsAppApi) callApi(u *url.URL, payload interface{}) error {
+	jsonObject, err := json.Marshal(payload)
+
+	if err != nil {
+		log.WithField("payload", payload).Infof("Could not marshal the payload: %s", err)
+		return ErrCouldNotMarshalJson
+	}
+
+	request, err := http.NewRequest("POST", u.String(), bytes.NewReader(jsonObject))
+
+	if err != nil {
+		log.WithField("url", u.Path).Infof("Could not create a new request: %s", err)
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+whatsAppApi.accessToken)
+
+	response, err := whatsAppApi.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(response.Body)
+	graphError := decodeGraphError(response.StatusCode, body)
+
+	log.WithFields(log.Fields{
+		"code":      graphError.Code,
+		"subcode":   graphError.Subcode,
+		"fbTraceId": graphError.FbTraceId,
+	}).Infof("Graph API returned a non-200 code: %s", graphError.Message)
+
+	return graphError
+}
+
+// graphErrorEnvelope is the JSON envelope returned by the Graph API in case of error
+type graphErrorEnvelope struct {
+	Error struct {
+		Message     string `json:"message"`
+		Type        string `json:"type"`
+		Code        int    `json:"code"`
+		Subcode     int    `json:"error_subcode"`
+		FbTraceId   string `json:"fbtrace_id"`
+		IsTransient bool   `json:"is_transient"`
+	} `json:"error"`
+}
+
+// decodeGraphError decodes the error returned by the Graph API.
+// If the body cannot be decoded, a generic error is returned.
+func decodeGraphError(statusCode int, body []byte) *api.GraphError {
+	envelope := &graphErrorEnvelope{}
+
+	if err := json.Unmarshal(body, envelope); err != nil {
+		return api.NewGraphError(statusCode, 0, 0, "", string(body), "", false)
+	}
+
+	e := envelope.Error
+
+	return api.NewGraphError(statusCode, e.Code, e.Subcode, e.Type, e.Message, e.FbTraceId, e.IsTransient)
+}
+
+func init() {
+	api.RegisterWhatsAppApiBuilder("whatsapp", newWhatsAppApi)
+}
@@ -0,0 +1,191 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// whatsAppObject is the object of the notifications sent by the WhatsApp Cloud API
+const whatsAppObject = "whatsapp_business_account"
+
+var (
+	ErrCouldNotReadRequestBody = errors.New("Could not read the request's body")
+	ErrInvalidJson             = errors.New("Invalid JSON")
+	ErrUnhandledObject         = errors.New("Unhandled notification object")
+)
+
+// notificationEnvelope is the JSON envelope of a webhook notification.
+// It has the same shape as the Messenger notifications, but the changes hold the payload.
+type notificationEnvelope struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		Id      string `json:"id"`
+		Changes []struct {
+			Field string `json:"field"`
+			Value struct {
+				Metadata struct {
+					PhoneNumberId string `json:"phone_number_id"`
+				} `json:"metadata"`
+				Messages []*messageEnvelope `json:"messages"`
+				Statuses []*statusEnvelope  `json:"statuses"`
+			} `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+// messageEnvelope is the JSON envelope of a message sent by a user
+type messageEnvelope struct {
+	Id        string `json:"id"`
+	From      string `json:"from"`
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Text      *struct {
+		Body string `json:"body"`
+	} `json:"text"`
+	Interactive *struct {
+		Type        string     `json:"type"`
+		ButtonReply *replyData `json:"button_reply"`
+		ListReply   *replyData `json:"list_reply"`
+	} `json:"interactive"`
+	// Button is set when the user pressed a quick reply button of a template
+	Button *struct {
+		Payload string `json:"payload"`
+		Text    string `json:"text"`
+	} `json:"button"`
+	Location *struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"location"`
+}
+
+// replyData is the JSON envelope of a button or list row chosen by the user
+type replyData struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// statusEnvelope is the JSON envelope of the status of a message we sent
+type statusEnvelope struct {
+	Id          string `json:"id"`
+	RecipientId string `json:"recipient_id"`
+	Status      string `json:"status"`
+	Timestamp   string `json:"timestamp"`
+	Errors      []struct {
+		Code  int    `json:"code"`
+		Title string `json:"title"`
+	} `json:"errors"`
+}
+
+// ParseNotification creates a WhatsAppNotification from the webhook's request,
+// gathering the messages and statuses of every change.
+// Unhandled message types are kept, with their type and no text.
+func (whatsAppApi *whatsAppApi) ParseNotification(r *http.Request) (*api.WhatsAppNotification, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+
+	if err != nil {
+		log.Infof("Could not read the request's body: %s", err)
+		return nil, ErrCouldNotReadRequestBody
+	}
+
+	envelope := &notificationEnvelope{}
+	err = json.Unmarshal(body, envelope)
+
+	if err != nil {
+		log.WithField("body", string(body)).Infof("Could not parse JSON from the request: %s", err)
+		return nil, ErrInvalidJson
+	}
+
+	if envelope.Object != whatsAppObject {
+		log.WithField("object", envelope.Object).Info("Unhandled notification object")
+		return nil, ErrUnhandledObject
+	}
+
+	notification := &api.WhatsAppNotification{}
+
+	for _, entry := range envelope.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+
+			for _, message := range change.Value.Messages {
+				notification.Messages = append(notification.Messages, parseMessage(message, change.Value.Metadata.PhoneNumberId))
+			}
+
+			for _, status := range change.Value.Statuses {
+				notification.Statuses = append(notification.Statuses, parseStatus(status))
+			}
+		}
+	}
+
+	return notification, nil
+}
+
+// parseMessage creates a WhatsAppMessage from its JSON envelope
+func parseMessage(envelope *messageEnvelope, phoneNumberId string) *api.WhatsAppMessage {
+	message := &api.WhatsAppMessage{
+		Id:            envelope.Id,
+		From:          envelope.From,
+		PhoneNumberId: phoneNumberId,
+		Type:          envelope.Type,
+		SentAt:        parseTimestamp(envelope.Timestamp),
+	}
+
+	switch {
+	case envelope.Text != nil:
+		message.Text = envelope.Text.Body
+	case envelope.Interactive != nil && envelope.Interactive.ButtonReply != nil:
+		message.Text = envelope.Interactive.ButtonReply.Title
+		message.ReplyId = envelope.Interactive.ButtonReply.Id
+	case envelope.Interactive != nil && envelope.Interactive.ListReply != nil:
+		message.Text = envelope.Interactive.ListReply.Title
+		message.ReplyId = envelope.Interactive.ListReply.Id
+	case envelope.Button != nil:
+		message.Text = envelope.Button.Text
+		message.ReplyId = envelope.Button.Payload
+	case envelope.Location != nil:
+		message.Location = &api.WhatsAppLocation{
+			Latitude:  envelope.Location.Latitude,
+			Longitude: envelope.Location.Longitude,
+		}
+	}
+
+	return message
+}
+
+// parseStatus creates a WhatsAppStatus from its JSON envelope
+func parseStatus(envelope *statusEnvelope) *api.WhatsAppStatus {
+	status := &api.WhatsAppStatus{
+		MessageId:   envelope.Id,
+		RecipientId: envelope.RecipientId,
+		Status:      envelope.Status,
+		Timestamp:   parseTimestamp(envelope.Timestamp),
+	}
+
+	if len(envelope.Errors) > 0 {
+		status.ErrorCode = envelope.Errors[0].Code
+		status.ErrorTitle = envelope.Errors[0].Title
+	}
+
+	return status
+}
+
+// parseTimestamp converts a Unix timestamp, sent as a string, to a time.Time.
+// Returns the current time if the timestamp is malformed.
+func parseTimestamp(timestamp string) time.Time {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+
+	if err != nil {
+		return time.Now()
+	}
+
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,200 @@
+package whatsapp
+
+import (
+	"github.com/aziule/conversation-management/core/api"
+	log "github.com/sirupsen/logrus"
+)
+
+// SendText is the WhatsAppApi's interface method responsible for sending a text to a user
+func (whatsAppApi *whatsAppApi) SendText(to, text string) error {
+	envelope := newMessageEnvelope(to, "text")
+	envelope.Text = &textEnvelope{Body: text}
+
+	return whatsAppApi.send(envelope)
+}
+
+// SendButtons is the WhatsAppApi's interface method responsible for sending a text along
+// with reply buttons. Only the first WhatsAppMaxButtons buttons are sent.
+func (whatsAppApi *whatsAppApi) SendButtons(to, text string, buttons []*api.WhatsAppButton) error {
+	if len(buttons) > api.WhatsAppMaxButtons {
+		buttons = buttons[:api.WhatsAppMaxButtons]
+	}
+
+	action := &actionEnvelope{}
+
+	for _, button := range buttons {
+		action.Buttons = append(action.Buttons, &buttonEnvelope{
+			Type: "reply",
+			Reply: replyData{
+				Id:    button.Id,
+				Title: truncate(button.Title, api.WhatsAppMaxButtonTitle),
+			},
+		})
+	}
+
+	envelope := newMessageEnvelope(to, "interactive")
+	envelope.Interactive = &interactiveEnvelope{
+		Type:   "button",
+		Action: action,
+	}
+	envelope.Interactive.Body.Text = text
+
+	return whatsAppApi.send(envelope)
+}
+
+// SendList is the WhatsAppApi's interface method responsible for sending a text along
+// with a list of options, displayed when pressing the button. Only the first WhatsAppMaxListRows
+// rows are sent.
+func (whatsAppApi *whatsAppApi) SendList(to, text, buttonText string, rows []*api.WhatsAppListRow) error {
+	if len(rows) > api.WhatsAppMaxListRows {
+		rows = rows[:api.WhatsAppMaxListRows]
+	}
+
+	section := &sectionEnvelope{}
+
+	for _, row := range rows {
+		section.Rows = append(section.Rows, &rowEnvelope{
+			Id:          row.Id,
+			Title:       truncate(row.Title, api.WhatsAppMaxListRowTitle),
+			Description: row.Description,
+		})
+	}
+
+	envelope := newMessageEnvelope(to, "interactive")
+	envelope.Interactive = &interactiveEnvelope{
+		Type: "list",
+		Action: &actionEnvelope{
+			Button:   truncate(buttonText, api.WhatsAppMaxListButton),
+			Sections: []*sectionEnvelope{section},
+		},
+	}
+	envelope.Interactive.Body.Text = text
+
+	return whatsAppApi.send(envelope)
+}
+
+// SendTemplate is the WhatsAppApi's interface method responsible for sending a template.
+// The parameters fill the template's body variables, in order.
+func (whatsAppApi *whatsAppApi) SendTemplate(to string, template *api.WhatsAppTemplate) error {
+	t := &templateEnvelope{
+		Name: template.Name,
+	}
+	t.Language.Code = template.LanguageCode
+
+	if len(template.Parameters) > 0 {
+		body := &componentEnvelope{Type: "body"}
+
+		for _, parameter := range template.Parameters {
+			body.Parameters = append(body.Parameters, &parameterEnvelope{
+				Type: "text",
+				Text: parameter,
+			})
+		}
+
+		t.Components = append(t.Components, body)
+	}
+
+	envelope := newMessageEnvelope(to, "template")
+	envelope.Template = t
+
+	return whatsAppApi.send(envelope)
+}
+
+// send sends the message envelope to the messages endpoint
+func (whatsAppApi *whatsAppApi) send(envelope *messageToUserEnvelope) error {
+	err := whatsAppApi.callApi(whatsAppApi.getMessagesUrl(), envelope)
+
+	if err != nil {
+		log.WithFields(log.Fields{
+			"to":   envelope.To,
+			"type": envelope.Type,
+		}).Infof("Could not send the message: %s", err)
+		return err
+	}
+
+	return nil
+}
+
+// truncate cuts the text so that it is at most max characters long
+func truncate(text string, max int) string {
+	runes := []rune(text)
+
+	if len(runes) <= max {
+		return text
+	}
+
+	return string(runes[:max])
+}
+
+// newMessageEnvelope is the constructor method for messageToUserEnvelope
+func newMessageEnvelope(to, messageType string) *messageToUserEnvelope {
+	return &messageToUserEnvelope{
+		MessagingProduct: "whatsapp",
+		RecipientType:    "individual",
+		To:               to,
+		Type:             messageType,
+	}
+}
+
+// messageToUserEnvelope is the JSON envelope that needs to be sent to send a message
+type messageToUserEnvelope struct {
+	MessagingProduct string               `json:"messaging_product"`
+	RecipientType    string               `json:"recipient_type"`
+	To               string               `json:"to"`
+	Type             string               `json:"type"`
+	Text             *textEnvelope        `json:"text,omitempty"`
+	Interactive      *interactiveEnvelope `json:"interactive,omitempty"`
+	Template         *templateEnvelope    `json:"template,omitempty"`
+}
+
+type textEnvelope struct {
+	Body string `json:"body"`
+}
+
+type interactiveEnvelope struct {
+	Type string `json:"type"`
+	Body struct {
+		Text string `json:"text"`
+	} `json:"body"`
+	Action *actionEnvelope `json:"action"`
+}
+
+type actionEnvelope struct {
+	Buttons  []*buttonEnvelope  `json:"buttons,omitempty"`
+	Button   string             `json:"button,omitempty"`
+	Sections []*sectionEnvelope `json:"sections,omitempty"`
+}
+
+type buttonEnvelope struct {
+	Type  string    `json:"type"`
+	Reply replyData `json:"reply"`
+}
+
+type sectionEnvelope struct {
+	Title string         `json:"title,omitempty"`
+	Rows  []*rowEnvelope `json:"rows"`
+}
+
+type rowEnvelope struct {
+	Id          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+type templateEnvelope struct {
+	Name     string `json:"name"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Components []*componentEnvelope `json:"components,omitempty"`
+}
+
+type componentEnvelope struct {
+	Type       string               `json:"type"`
+	Parameters []*parameterEnvelope `json:"parameters"`
+}
+
+type parameterEnvelope struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
@@ -1,18 +1,28 @@
 package wit
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/aziule/conversation-management/core/nlp"
 	"github.com/aziule/conversation-management/core/utils"
 )
 
+const (
+	defaultBaseUrl = "https://api.wit.ai"
+	// defaultVersion is the version of the Wit API, given as a date
+	defaultVersion = "20200513"
+)
+
 // witApi is the struct used to make calls to Wit
 type witApi struct {
 	client      *http.Client
 	baseUrl     *url.URL
 	bearerToken string
+	version     string
+	parser      *witParser
 }
 
 // newWitApi creates a new witApi using the given conf
@@ -29,13 +39,36 @@ func newWitApi(conf utils.BuilderConf) (interface{}, error) {
 		return nil, utils.ErrInvalidOrMissingParam("bearer_token")
 	}
 
-	rawBaseUrl := "https://api.wit.ai"
-	baseUrl, _ := url.Parse(rawBaseUrl)
+	// The base url is optional and can be used to target a local stand-in
+	rawBaseUrl, ok := utils.GetParam(conf, "base_url").(string)
+
+	if !ok || rawBaseUrl == "" {
+		rawBaseUrl = defaultBaseUrl
+	}
+
+	baseUrl, err := url.Parse(rawBaseUrl)
+
+	if err != nil {
+		return nil, utils.ErrInvalidOrMissingParam("base_url")
+	}
+
+	version, ok := utils.GetParam(conf, "version").(string)
+
+	if !ok || version == "" {
+		version = defaultVersion
+	}
+
+	// The bot's data types are optional
+	dataTypes, _ := utils.GetParam(conf, "data_types").(*nlp.DataTypes)
 
 	return &witApi{
 		client:      client,
 		baseUrl:     baseUrl,
 		bearerToken: token,
+		version:     version,
+		parser: &witParser{
+			dataTypes: dataTypes,
+		},
 	}, nil
 }
 
@@ -68,7 +101,8 @@ func (api *witApi) GetIntents() ([]*nlp.Intent, error) {
 	return intents, nil
 }
 
-// GetEntities gets the list of entities from Wit
+// GetEntities gets the list of entities from Wit.
+// The entities' types are found using the parser's data types, when known.
 // @todo: make the API return api-specific objects instead of domain objects,
 // and put the logic inside the repository.
 func (api *witApi) GetEntities() ([]*nlp.Entity, error) {
@@ -88,12 +122,28 @@ func (api *witApi) GetEntities() ([]*nlp.Entity, error) {
 			continue
 		}
 
-		entities = append(entities, &nlp.Entity{Name: entityName})
+		dataType, _ := api.parser.dataType(entityName)
+		entities = append(entities, nlp.NewEntity(entityName, dataType))
 	}
 
 	return entities, nil
 }
 
+// Parse sends the text to Wit's /message endpoint, and parses the intents and entities it returned.
+// This method is required in order to implement the nlp.Api interface.
+func (api *witApi) Parse(text string, context *nlp.Context) (*nlp.ParsedData, error) {
+	var envelope json.RawMessage
+
+	err := api.callApi("GET", api.getMessageUrl(text, context), &envelope)
+
+	if err != nil {
+		// @todo: log this and return a proper error
+		return nil, err
+	}
+
+	return api.parser.ParseNlpData(envelope)
+}
+
 // callApi calls the API given a method, an URL and an envelope. If it is a success, then
 // the data is parsed and stored inside the envelope (using JSON).
 // Returns an error if anything happens or if the status code != 200.
@@ -124,6 +174,38 @@ func (api *witApi) getIntentsUrl() *url.URL {
 	return u
 }
 
+// getMessageUrl returns the url to ping to parse the text, given its context
+func (api *witApi) getMessageUrl(text string, context *nlp.Context) *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + "/message")
+
+	q := u.Query()
+	q.Set("v", api.version)
+	q.Set("q", text)
+
+	if context != nil {
+		witContext := struct {
+			ReferenceTime string `json:"reference_time,omitempty"`
+			Timezone      string `json:"timezone,omitempty"`
+			Locale        string `json:"locale,omitempty"`
+		}{
+			Timezone: context.Timezone,
+			Locale:   context.Locale,
+		}
+
+		if !context.ReferenceTime.IsZero() {
+			witContext.ReferenceTime = context.ReferenceTime.Format(time.RFC3339)
+		}
+
+		j, _ := json.Marshal(witContext)
+		q.Set("context", string(j))
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u
+}
+
 func init() {
 	nlp.RegisterApiBuilder("wit", newWitApi)
+	nlp.RegisterTextParserBuilder("wit", newWitApi)
 }
@@ -4,6 +4,8 @@ package wit
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/antonholmquist/jason"
@@ -19,22 +21,35 @@ var (
 	ErrCouldNotParseJsonObject = errors.New("Could not parse object from JSON")
 	ErrMissingKey              = func(key string) error { return errors.New(fmt.Sprintf("Missing key: %s", key)) }
 	ErrCouldNotCastValue       = func(key, expectedType string) error {
-		return errors.New(fmt.Sprintf("Could not cast %s to %s", expectedType))
+		return errors.New(fmt.Sprintf("Could not cast %s to %s", key, expectedType))
 	}
 	ErrUnhandledDataType = func(dataType string) error { return errors.New(fmt.Sprintf("Unhandled data type %s", dataType)) }
 
-	// DefaultDataTypeMap is the default data type map to be used with Wit.
-	// For now, this is highly coupled with Wit's data types and should
-	// be updated every time a change is made to Wit.
+	// defaultDataTypeMap is the data type map of Wit's built-in entities. The bots' own entities
+	// are given by the bots' data types (see nlp.DataTypes).
 	// It is initialised in the init() function
 	defaultDataTypeMap nlp.DataTypeMap
 )
 
 func init() {
 	defaultDataTypeMap = make(nlp.DataTypeMap)
-	defaultDataTypeMap["nb_persons"] = nlp.IntEntity
 	defaultDataTypeMap["intent"] = nlp.IntentEntity
-	defaultDataTypeMap["datetime"] = nlp.DateTimeEntity
+
+	// Built-in entities, named with the "wit$" prefix by the Wit /message endpoint,
+	// and without it by the Messenger built-in NLP
+	for name, dataType := range map[string]nlp.EntityType{
+		"number":          nlp.NumberEntity,
+		"amount_of_money": nlp.MoneyEntity,
+		"duration":        nlp.DurationEntity,
+		"email":           nlp.EmailEntity,
+		"phone_number":    nlp.PhoneEntity,
+		"url":             nlp.UrlEntity,
+		"location":        nlp.LocationEntity,
+		"datetime":        nlp.DateTimeEntity,
+	} {
+		defaultDataTypeMap[name] = dataType
+		defaultDataTypeMap["wit$"+name] = dataType
+	}
 
 	nlp.RegisterParserBuilder("wit", newParser)
 }
@@ -42,19 +57,33 @@ func init() {
 // witParser is the NLP parser for Wit.
 // It implements the nlp.Parser interface.
 type witParser struct {
-	dataTypeMap nlp.DataTypeMap
+	// dataTypes are the bot's data types, if any
+	dataTypes *nlp.DataTypes
 }
 
-// newParser is the constructor method for witParser
+// newParser is the constructor method for witParser.
+// The bot's data types are given using the optional "data_types" param.
 func newParser(conf utils.BuilderConf) (interface{}, error) {
+	dataTypes, _ := utils.GetParam(conf, "data_types").(*nlp.DataTypes)
+
 	return &witParser{
-		dataTypeMap: defaultDataTypeMap,
+		dataTypes: dataTypes,
 	}, nil
 }
 
-// ParseNlpData parses raw data and returns parsed data
+// dataType returns the type of the entity, using the bot's data types and Wit's built-in entities
+func (parser *witParser) dataType(name string) (nlp.EntityType, bool) {
+	return parser.dataTypes.Lookup(name, defaultDataTypeMap)
+}
+
+// ParseNlpData parses raw data and returns parsed data.
+//
+// Both the Messenger built-in NLP format, where the intents are given within the "intent" entity,
+// and the Wit /message format, where they are given within the "intents" array, are handled.
+// Entities are keyed by their name, or by "name:role" when they have a role. When many intents
+// are given, the one with the highest confidence is the intent, and all of them are kept as candidates.
 func (parser *witParser) ParseNlpData(rawData []byte) (*nlp.ParsedData, error) {
-	var intent *nlp.ParsedIntent
+	var intents []*nlp.ParsedIntent
 	var entities []*nlp.ParsedEntity
 
 	data, err := jason.NewObjectFromBytes(rawData)
@@ -64,138 +93,231 @@ func (parser *witParser) ParseNlpData(rawData []byte) (*nlp.ParsedData, error) {
 		return nil, ErrCouldNotParseJson
 	}
 
-	for key, value := range data.Map() {
-		dataType, ok := parser.dataTypeMap[key]
+	// The Wit /message format wraps the entities, and gives the intents aside
+	if objects, err := data.GetObjectArray("intents"); err == nil {
+		intents = append(intents, toIntents(objects, "name")...)
+	}
+
+	if wrapped, err := data.GetObject("entities"); err == nil {
+		data = wrapped
+	}
+
+	fields := data.Map()
+	keys := make([]string, 0, len(fields))
+
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	// The entities are given in a stable order, as the JSON object's keys are not ordered
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, role := splitRole(key)
+		dataType, ok := parser.dataType(name)
+
+		// Entities with a handled role are looked up using their role, so that built-in entities
+		// are named after their role, such as "wit$number:nb_persons"
+		if roleDataType, roleOk := parser.dataType(role); role != "" && roleOk {
+			name, dataType, ok = role, roleDataType, true
+		}
 
 		if !ok {
 			log.WithField("key", key).Warnf("Data type is not handled: %s", key)
+
+			// The role is reported, as it is the name the bot uses
+			if role != "" {
+				parser.dataTypes.ReportUnknown(role)
+			} else {
+				parser.dataTypes.ReportUnknown(name)
+			}
+
 			continue
 		}
 
-		switch dataType {
-		case nlp.IntentEntity:
-			i, err := toIntent(value)
+		values, err := fields[key].ObjectArray()
 
-			if err != nil {
-				log.WithField("dataType", dataType).Warnf("Could not convert value to DataTypeIntent: %s", err)
-				continue
-			}
+		if err != nil {
+			log.WithField("key", key).Warnf("Could not parse the values: %s", err)
+			continue
+		}
 
-			intent = i
-			break
+		switch dataType {
+		case nlp.IntentEntity:
+			intents = append(intents, toIntents(values, "value")...)
 		default:
-			entity, err := toEntity(value, key, dataType)
+			for _, v := range values {
+				entity, err := toEntity(v, name, role, dataType)
 
-			if err != nil {
-				log.WithFields(log.Fields{
-					"dataType": dataType,
-					"key":      key,
-				}).Warnf("Could not convert value to entity: %s", err)
-				continue
-			}
+				if err != nil {
+					log.WithFields(log.Fields{
+						"dataType": dataType,
+						"key":      key,
+					}).Warnf("Could not convert value to entity: %s", err)
+					continue
+				}
 
-			entities = append(entities, entity)
-			break
+				entities = append(entities, entity)
+			}
 		}
 	}
 
-	return nlp.NewParsedData(intent, entities), nil
+	var intent *nlp.ParsedIntent
+
+	if len(intents) > 0 {
+		nlp.RankIntents(intents)
+		intent = intents[0]
+	}
+
+	parsedData := nlp.NewParsedData(intent, entities)
+	parsedData.Intents = intents
+
+	return parsedData, nil
 }
 
-// toIntent converts a jason intent to a built-in NLP representation of an intent
-// Returns an error if the JSON is malformed
-func toIntent(value *jason.Value) (*nlp.ParsedIntent, error) {
-	object, err := value.ObjectArray()
+// splitRole splits an entity's key, formatted as "name:role", into its name and role.
+// The role is empty when the key has none.
+func splitRole(key string) (string, string) {
+	parts := strings.SplitN(key, ":", 2)
 
-	if err != nil {
-		return nil, ErrCouldNotParseJsonObject
+	if len(parts) == 1 {
+		return parts[0], ""
 	}
 
-	// Handle single intents only
-	intentName, err := object[0].GetString("value")
+	return parts[0], parts[1]
+}
+
+// toIntents converts jason intents to built-in NLP representations of intents.
+// The intents' names are read from the given key. Malformed intents are skipped.
+func toIntents(objects []*jason.Object, nameKey string) []*nlp.ParsedIntent {
+	var intents []*nlp.ParsedIntent
 
-	if err != nil {
-		return nil, ErrMissingKey("value")
+	for _, object := range objects {
+		name, err := object.GetString(nameKey)
+
+		if err != nil {
+			log.WithField("key", nameKey).Warn("Could not parse the intent: missing key")
+			continue
+		}
+
+		// The confidence is optional
+		confidence, _ := object.GetFloat64("confidence")
+
+		intents = append(intents, nlp.NewParsedIntent(name, float32(confidence)))
 	}
 
-	return nlp.NewParsedIntent(intentName), nil
+	return intents
 }
 
-// toEntity converts a jason entity to a built-in NLP representation of an entity
+// toEntity converts a single jason entity value to a built-in NLP representation of an entity.
+// The role given in the value, if any, takes precedence over the one given in the key.
 // Returns an error if the JSON is malformed or if we do not handle the data type correctly
-func toEntity(value *jason.Value, name string, dataType nlp.EntityType) (*nlp.ParsedEntity, error) {
-	object, err := value.ObjectArray()
+func toEntity(e *jason.Object, name, role string, dataType nlp.EntityType) (*nlp.ParsedEntity, error) {
+	confidence, err := e.GetFloat64("confidence")
 
 	if err != nil {
-		return nil, ErrCouldNotParseJsonObject
+		return nil, ErrCouldNotCastValue("confidence", "float64")
+	}
+
+	if valueRole, err := e.GetString("role"); err == nil && valueRole != name {
+		role = valueRole
 	}
 
-	for _, e := range object {
-		confidence, err := e.GetFloat64("confidence")
+	switch dataType {
+	case nlp.IntEntity:
+		value, err := e.GetInt64("value")
 
 		if err != nil {
-			return nil, ErrCouldNotCastValue("confidence", "float64")
+			return nil, ErrCouldNotCastValue("value", "int64")
 		}
 
-		switch dataType {
-		case nlp.IntEntity:
-			value, err := e.GetInt64("value")
+		return nlp.NewParsedIntEntity(name, float32(confidence), int(value), role), nil
+	case nlp.NumberEntity:
+		value, err := e.GetFloat64("value")
 
-			if err != nil {
-				return nil, ErrCouldNotCastValue("value", "int64")
-			}
+		if err != nil {
+			return nil, ErrCouldNotCastValue("value", "float64")
+		}
 
-			// @todo: handle roles
-			return nlp.NewParsedIntEntity(name, float32(confidence), int(value), ""), nil
-		case nlp.DateTimeEntity:
-			_, err := e.GetString("value")
+		return nlp.NewParsedNumberEntity(name, float32(confidence), value, role), nil
+	case nlp.StringEntity, nlp.EnumEntity, nlp.EmailEntity, nlp.PhoneEntity, nlp.UrlEntity:
+		value, err := e.GetString("value")
 
-			if err != nil {
-				// If there's an error, then look for interval datetimes, parsed as "from" & "to"
-				from, err := e.GetObject("from")
+		if err != nil {
+			return nil, ErrCouldNotCastValue("value", "string")
+		}
 
-				if err != nil {
-					return nil, ErrMissingKey("from")
-				}
+		return nlp.NewParsedTextEntity(name, float32(confidence), value, dataType, role), nil
+	case nlp.MoneyEntity:
+		value, err := e.GetFloat64("value")
 
-				fromTime, fromGranularity, err := extractDateTimeInformation(from)
+		if err != nil {
+			return nil, ErrCouldNotCastValue("value", "float64")
+		}
 
-				if err != nil {
-					return nil, err
-				}
+		// The unit is either a currency code, such as "EUR", or a symbol, such as "$"
+		unit, _ := e.GetString("unit")
 
-				to, err := e.GetObject("to")
+		return nlp.NewParsedMoneyEntity(name, float32(confidence), value, unit, role), nil
+	case nlp.DurationEntity:
+		seconds, err := e.GetFloat64("normalized", "value")
 
-				if err != nil {
-					return nil, ErrMissingKey("to")
-				}
+		if err != nil {
+			return nil, ErrMissingKey("normalized")
+		}
 
-				toTime, toGranularity, err := extractDateTimeInformation(to)
+		return nlp.NewParsedDurationEntity(name, float32(confidence), time.Duration(seconds*float64(time.Second)), role), nil
+	case nlp.LocationEntity:
+		location := &nlp.Location{}
+		location.Name, _ = e.GetString("value")
 
-				if err != nil {
-					return nil, err
-				}
+		// The location is resolved when Wit knows the place
+		if values, err := e.GetObjectArray("resolved", "values"); err == nil && len(values) > 0 {
+			if location.Name == "" {
+				location.Name, _ = values[0].GetString("name")
+			}
 
-				// @todo: handle roles
-				return nlp.NewParsedDateTimeIntervalEntity(
-					name,
-					float32(confidence),
-					fromTime,
-					toTime,
-					fromGranularity,
-					toGranularity,
-					"",
-				), nil
+			location.Latitude, _ = values[0].GetFloat64("coords", "lat")
+			location.Longitude, _ = values[0].GetFloat64("coords", "long")
+		}
+
+		if location.Name == "" {
+			return nil, ErrMissingKey("value")
+		}
+
+		return nlp.NewParsedLocationEntity(name, float32(confidence), location, role), nil
+	case nlp.DateTimeEntity:
+		_, err := e.GetString("value")
+
+		if err != nil {
+			// If there's an error, then look for interval datetimes, parsed as "from" & "to".
+			// Open intervals, such as "after 8pm", have a single bound.
+			from, err := extractDateTimeBound(e, "from")
+
+			if err != nil {
+				return nil, err
 			}
 
-			t, granularity, err := extractDateTimeInformation(e)
+			to, err := extractDateTimeBound(e, "to")
 
 			if err != nil {
 				return nil, err
 			}
 
-			return nlp.NewParsedSingleDateTimeEntity(name, float32(confidence), t, granularity, ""), nil
+			if from == nil && to == nil {
+				return nil, ErrMissingKey("from")
+			}
+
+			return nlp.NewParsedOpenDateTimeIntervalEntity(name, float32(confidence), from, to, role), nil
+		}
+
+		t, granularity, err := extractDateTimeInformation(e)
+
+		if err != nil {
+			return nil, err
 		}
+
+		return nlp.NewParsedSingleDateTimeEntity(name, float32(confidence), t, granularity, role), nil
 	}
 
 	return nil, ErrUnhandledDataType(string(dataType))
@@ -225,3 +347,24 @@ func extractDateTimeInformation(object *jason.Object) (time.Time, nlp.DateTimeGr
 	// @todo: use a converter that will check that the granularity exists
 	return t, nlp.DateTimeGranularity(grain), nil
 }
+
+// extractDateTimeBound extracts a bound of an interval, given by the key.
+// Returns nil if the interval has no such bound.
+func extractDateTimeBound(object *jason.Object, key string) (*nlp.SingleDateTime, error) {
+	bound, err := object.GetObject(key)
+
+	if err != nil {
+		return nil, nil
+	}
+
+	t, granularity, err := extractDateTimeInformation(bound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &nlp.SingleDateTime{
+		Date:        t,
+		Granularity: granularity,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package wit
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aziule/conversation-management/core/nlp"
+)
+
+// update rewrites the golden files using the parser's current output: go test ./infrastructure/wit -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenEntity is the golden representation of a parsed entity, along with its type,
+// which is not serialized within parsed entities
+type goldenEntity struct {
+	Name       string         `json:"name"`
+	Type       nlp.EntityType `json:"type"`
+	Role       string         `json:"role,omitempty"`
+	Confidence float32        `json:"confidence"`
+	Data       interface{}    `json:"data"`
+}
+
+// golden is the golden representation of the parser's output, along with the entities
+// reported as unknown
+type golden struct {
+	Intent   *nlp.ParsedIntent   `json:"intent"`
+	Intents  []*nlp.ParsedIntent `json:"intents"`
+	Entities []*goldenEntity     `json:"entities"`
+	Unknown  map[string]int      `json:"unknown"`
+}
+
+// messengerPayload is the part of the recorded Messenger webhook payloads giving the messages' NLP data
+type messengerPayload struct {
+	Entry []struct {
+		Messaging []struct {
+			Message struct {
+				Nlp json.RawMessage `json:"nlp"`
+			} `json:"message"`
+		} `json:"messaging"`
+	} `json:"entry"`
+}
+
+// testDataTypes returns the data types of the booking bot used by the test cases
+func testDataTypes() *nlp.DataTypes {
+	return nlp.NewDataTypes(nlp.DataTypeMap{
+		"nb_persons":   nlp.IntEntity,
+		"booking_date": nlp.DateTimeEntity,
+	})
+}
+
+// TestParseNlpData parses the NLP data of the Messenger payloads recorded within data/, and the
+// Messenger and Wit /message payloads within testdata/, and compares the results to the golden files
+func TestParseNlpData(t *testing.T) {
+	cases := make(map[string][]byte)
+
+	recorded, err := filepath.Glob(filepath.Join("..", "..", "data", "*.json"))
+
+	if err != nil || len(recorded) == 0 {
+		t.Fatalf("Could not find the recorded payloads: %v", err)
+	}
+
+	for _, path := range recorded {
+		payload := &messengerPayload{}
+
+		if err := json.Unmarshal(readFile(t, path), payload); err != nil {
+			t.Fatalf("Could not decode %s: %s", path, err)
+		}
+
+		if len(payload.Entry) == 0 || len(payload.Entry[0].Messaging) == 0 {
+			t.Fatalf("No message within %s", path)
+		}
+
+		cases[strings.TrimSuffix(filepath.Base(path), ".json")] = payload.Entry[0].Messaging[0].Message.Nlp
+	}
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range inputs {
+		cases[strings.TrimSuffix(filepath.Base(path), ".json")] = readFile(t, path)
+	}
+
+	for name, rawData := range cases {
+		t.Run(name, func(t *testing.T) {
+			dataTypes := testDataTypes()
+			parser, _ := newParser(map[string]interface{}{"data_types": dataTypes})
+
+			data, err := parser.(*witParser).ParseNlpData(rawData)
+
+			if err != nil {
+				t.Fatalf("Could not parse the NLP data: %s", err)
+			}
+
+			actual := toGolden(data, dataTypes)
+			path := filepath.Join("testdata", name+".golden")
+
+			if *update {
+				if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			if expected := readFile(t, path); !bytes.Equal(expected, actual) {
+				t.Errorf("Unexpected parsed data for %s.\nExpected:\n%s\nActual:\n%s", name, expected, actual)
+			}
+		})
+	}
+}
+
+// TestParseNlpDataMalformed checks that malformed JSON is rejected
+func TestParseNlpDataMalformed(t *testing.T) {
+	parser, _ := newParser(map[string]interface{}{})
+
+	if _, err := parser.(*witParser).ParseNlpData([]byte(`{"entities":`)); err != ErrCouldNotParseJson {
+		t.Errorf("Expected ErrCouldNotParseJson, got %v", err)
+	}
+}
+
+// toGolden returns the golden representation of the parsed data
+func toGolden(data *nlp.ParsedData, dataTypes *nlp.DataTypes) []byte {
+	g := &golden{
+		Intent:   data.Intent,
+		Intents:  data.Intents,
+		Entities: []*goldenEntity{},
+		Unknown:  dataTypes.Unknown(),
+	}
+
+	for _, entity := range data.Entities {
+		g.Entities = append(g.Entities, &goldenEntity{
+			Name:       entity.Entity.Name,
+			Type:       entity.Entity.Type,
+			Role:       entity.Role,
+			Confidence: entity.Confidence,
+			Data:       entity.Data,
+		})
+	}
+
+	j, _ := json.MarshalIndent(g, "", "  ")
+
+	return append(j, '\n')
+}
+
+// readFile returns the content of the file, failing the test if it cannot be read
+func readFile(t *testing.T, path string) []byte {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Could not read %s: %s", path, err)
+	}
+
+	return content
+}
@@ -0,0 +1,179 @@
+package wit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/aziule/conversation-management/core/nlp"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxUtterancesPerRequest is the maximum number of utterances sent to Wit at once
+const maxUtterancesPerRequest = 200
+
+var (
+	ErrCouldNotMarshalJson = errors.New("Could not marshal JSON object")
+	ErrInvalidStatusCode   = errors.New("Invalid status code returned")
+)
+
+// witUtterance is an utterance, as sent to the /utterances endpoint.
+// More information here: https://wit.ai/docs/http/20200513/#post__utterances_link
+type witUtterance struct {
+	Text     string               `json:"text"`
+	Intent   string               `json:"intent,omitempty"`
+	Entities []*witUtteranceLabel `json:"entities"`
+	Traits   []interface{}        `json:"traits"`
+}
+
+// witUtteranceLabel is an entity labeled within an utterance, named "name:role"
+type witUtteranceLabel struct {
+	Entity   string               `json:"entity"`
+	Start    int                  `json:"start"`
+	End      int                  `json:"end"`
+	Body     string               `json:"body"`
+	Entities []*witUtteranceLabel `json:"entities"`
+}
+
+// CreateIntent creates an intent within Wit.
+// This method is required in order to implement the nlp.Trainer interface.
+func (api *witApi) CreateIntent(name string) error {
+	return api.sendJson("POST", api.getTrainingUrl("/intents"), map[string]string{
+		"name": name,
+	})
+}
+
+// CreateEntity creates an entity within Wit, whose values are either free texts or keywords.
+// This method is required in order to implement the nlp.Trainer interface.
+func (api *witApi) CreateEntity(name string, roles []string) error {
+	if len(roles) == 0 {
+		roles = []string{name}
+	}
+
+	return api.sendJson("POST", api.getTrainingUrl("/entities"), map[string]interface{}{
+		"name":    name,
+		"roles":   roles,
+		"lookups": []string{"free-text", "keywords"},
+	})
+}
+
+// AddEntityValue adds a keyword, along with its synonyms, to a Wit entity.
+// This method is required in order to implement the nlp.Trainer interface.
+func (api *witApi) AddEntityValue(entity string, value *nlp.EntityValue) error {
+	// The keyword is expected to be one of its synonyms
+	synonyms := []string{value.Value}
+
+	for _, synonym := range value.Synonyms {
+		if synonym != value.Value {
+			synonyms = append(synonyms, synonym)
+		}
+	}
+
+	return api.sendJson("POST", api.getTrainingUrl("/entities/"+url.PathEscape(entity)+"/keywords"), map[string]interface{}{
+		"keyword":  value.Value,
+		"synonyms": synonyms,
+	})
+}
+
+// AddUtterances sends the utterances to Wit, in batches. The entities without a role
+// use their name as their role, as Wit does.
+// This method is required in order to implement the nlp.Trainer interface.
+func (api *witApi) AddUtterances(utterances []*nlp.Utterance) error {
+	var batch []*witUtterance
+
+	for i, utterance := range utterances {
+		witUtterance := &witUtterance{
+			Text:     utterance.Text,
+			Intent:   utterance.Intent,
+			Entities: []*witUtteranceLabel{},
+			Traits:   []interface{}{},
+		}
+
+		for _, entity := range utterance.Entities {
+			role := entity.Role
+
+			if role == "" {
+				role = entity.Entity
+			}
+
+			witUtterance.Entities = append(witUtterance.Entities, &witUtteranceLabel{
+				Entity:   entity.Entity + ":" + role,
+				Start:    entity.Start,
+				End:      entity.End,
+				Body:     entity.Body,
+				Entities: []*witUtteranceLabel{},
+			})
+		}
+
+		batch = append(batch, witUtterance)
+
+		if len(batch) < maxUtterancesPerRequest && i < len(utterances)-1 {
+			continue
+		}
+
+		if err := api.sendJson("POST", api.getTrainingUrl("/utterances"), batch); err != nil {
+			log.WithField("utterances", len(batch)).Infof("Could not send the utterances: %s", err)
+			return err
+		}
+
+		batch = nil
+	}
+
+	return nil
+}
+
+// sendJson sends the payload to Wit, using the given method and URL.
+// Returns an error if anything happens or if the status code != 200.
+func (api *witApi) sendJson(method string, u *url.URL, payload interface{}) error {
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		log.WithField("payload", payload).Infof("Could not marshal the payload: %s", err)
+		return ErrCouldNotMarshalJson
+	}
+
+	request, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+
+	if err != nil {
+		log.WithField("url", u.Path).Infof("Could not create a new request: %s", err)
+		return err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+api.bearerToken)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := api.client.Do(request)
+
+	if err != nil {
+		log.Infof("Failed to send the request: %s", err)
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+
+		log.WithFields(log.Fields{
+			"code": response.StatusCode,
+			"body": string(responseBody),
+		}).Info("API returned a non-200 code")
+		return ErrInvalidStatusCode
+	}
+
+	return nil
+}
+
+// getTrainingUrl returns the url of the given training endpoint, using the API's version
+func (api *witApi) getTrainingUrl(path string) *url.URL {
+	u, _ := url.Parse(api.baseUrl.String() + path)
+
+	q := u.Query()
+	q.Set("v", api.version)
+	u.RawQuery = q.Encode()
+
+	return u
+}
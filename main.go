@@ -9,6 +9,7 @@ func main() {
 	cliHandler := cli.NewHandler()
 	cliHandler.RegisterCommand(cli.NewRunCommand())
 	cliHandler.RegisterCommand(cli.NewReceiveCommand())
+	cliHandler.RegisterCommand(cli.NewChatCommand())
 	err := cliHandler.Handle()
 
 	if err != nil {